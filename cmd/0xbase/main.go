@@ -1,14 +1,30 @@
 package main
 
 import (
-	"log"
+	"context"
+	er "errors"
+	"flag"
+	"log/slog"
+	"os"
 	"sync"
 
 	"github.com/0xbase-Corp/portfolio_svc/docs"
 	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/internal/routes"
+	"github.com/0xbase-Corp/portfolio_svc/internal/rpc"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/providers"
+	"github.com/0xbase-Corp/portfolio_svc/shared/cache"
 	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/events"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+	"github.com/0xbase-Corp/portfolio_svc/shared/mail"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
 	"github.com/0xbase-Corp/portfolio_svc/shared/migrations"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -33,24 +49,109 @@ var (
 //	@host		localhost:5050
 //	@BasePath	/api/v1
 
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+
+//	@securityDefinitions.apikey	ApiKeyAuth
+//	@in							header
+//	@name						x-api-key
+
 func main() {
-	//Loading Environment variables from app.env
-	configs.InitEnvConfigs()
+	// Loading the configuration from app.env, the environment and the command line flags
+	args, err := configs.InitEnvConfigs(os.Args[1:])
+	if er.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fatal("Invalid configuration", err)
+	}
 
-	db := configs.GetDB()
+	if err := logging.Setup(configs.EnvConfigVars.LogLevel, configs.EnvConfigVars.LogFormat); err != nil {
+		fatal("Invalid logging configuration", err)
+	}
+
+	db, err := configs.GetDB()
+	if err != nil {
+		fatal("Failed to connect to the database", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		fatal("Failed to get the database pool", err)
+	}
+	metrics.RegisterDB(sqlDB)
+
+	// the pool is registered first so it is closed last, once requests and background work are done
+	lifecycle.OnShutdown("database pool", func(context.Context) error {
+		return sqlDB.Close()
+	})
+
+	// spans are flushed before the pool is closed, after the requests and background work that record them
+	shutdownTracing, err := tracing.Setup(configs.EnvConfigVars)
+	if err != nil {
+		fatal("Failed to set up tracing", err)
+	}
+	lifecycle.OnShutdown("tracing", shutdownTracing)
+
+	if err := db.Use(tracing.GormPlugin{}); err != nil {
+		fatal("Failed to set up query tracing", err)
+	}
+
+	// `0xbase migrate <command>` manages the schema and exits instead of serving
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := migrations.RunCommand(db, os.Stdout, args[1:]); err != nil {
+			fatal("Migration failed", err)
+		}
+		return
+	}
 
 	// This ensures that the migration process is executed only once, regardless of how many times main() is called.
-	// Execute database migration once
+	// Execute database migration once, unless operators run them with `0xbasectl migrate`
 	once.Do(func() {
-		log.Println("Starting database migration...")
+		if !configs.EnvConfigVars.AutoMigrate {
+			slog.Info("Skipping database migration, AUTO_MIGRATE is off")
+			return
+		}
+
+		slog.Info("Starting database migration")
 		err := migrations.Migrate(db) // Call the Migrate function from migrations package
 		if err != nil {
-			log.Fatalf("Database migration failed: %v", err)
+			fatal("Database migration failed", err)
 		}
-		log.Println("Database migration completed successfully.")
+		slog.Info("Database migration completed successfully")
+	})
+
+	// client-side rate limits, daily quotas and circuit breakers for the upstream providers
+	providers.ConfigureLimits(configs.EnvConfigVars)
+	providers.ConfigureBreakers(configs.EnvConfigVars)
+	ratelimit.SetUsageStore(&models.ProviderUsageStore{DB: db})
+	lifecycle.OnShutdown("provider usage", func(context.Context) error {
+		return ratelimit.Flush()
 	})
 
-	r := gin.Default()
+	// provider responses and portfolios, see services.CacheTTL
+	cache.SetStore(cache.NewMemoryStore(configs.EnvConfigVars.CacheMaxEntries))
+
+	// price ticks and balance changes pushed to websocket clients, for the wallets and prices they watch
+	events.SetBus(events.NewBus(configs.EnvConfigVars.EventsReplaySize))
+	lifecycle.Go(func() { services.RunWatcher(lifecycle.Context(), db, configs.EnvConfigVars.WatchInterval) })
+	lifecycle.Go(func() { services.RunWebhooks(lifecycle.Context(), db, configs.EnvConfigVars.WatchInterval) })
+
+	// alert rules delivered through webhooks, and by email when an SMTP server is configured
+	if env := configs.EnvConfigVars; env.SmtpHost != "" {
+		sender := mail.NewSMTP(env.SmtpHost, env.SmtpPort, env.SmtpUsername, env.SmtpPassword, env.SmtpFrom, env.SmtpTimeout)
+		services.RegisterAlertChannel(services.AlertChannelEmail, services.EmailAlertChannel{Sender: sender})
+	}
+	lifecycle.Go(func() { services.RunAlerts(lifecycle.Context(), db, configs.EnvConfigVars.WatchInterval) })
+
+	// gin's default logger and recovery write unstructured lines, requests are logged with their request ID instead
+	r := gin.New()
+	r.Use(middlewares.RequestID())
+	r.Use(middlewares.Logger())
+	r.Use(middlewares.Recovery())
+	r.Use(middlewares.Metrics())
+	r.Use(middlewares.Tracing())
 	r.Use(middlewares.CORSMiddleware())
 	docs.SwaggerInfo.BasePath = "/api/v1"
 
@@ -61,13 +162,44 @@ func main() {
 	//gin warning: "you trusted all proxies this is not safe. we recommend you to set a value"
 	r.ForwardedByClientIP = true
 	if err := r.SetTrustedProxies(nil); err != nil {
-		log.Fatal("Failed to setup trusted Proxies")
+		fatal("Failed to setup trusted Proxies", err)
 	}
 
-	routes.PortfolioRoutes(r, db)
+	rateLimits, err := middlewares.ParseRateLimitConfig(configs.EnvConfigVars.RateLimitDefault, configs.EnvConfigVars.RateLimitRoutes)
+	if err != nil {
+		fatal("Invalid rate limit configuration", err)
+	}
+
+	// clients are limited by IP before their credentials are looked up, then by user or api key
+	rateLimitStore := middlewares.NewMemoryRateLimitStore()
+	routes.PortfolioRoutes(r, db,
+		middlewares.IPRateLimiter(rateLimitStore, rateLimits),
+		middlewares.Authenticate(db, false),
+		middlewares.RateLimiter(rateLimitStore, rateLimits),
+		middlewares.MaxAddresses(configs.EnvConfigVars.MaxAddressesPerRequest),
+	)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
-	if err := r.Run(configs.EnvConfigVars.Port); err != nil {
-		log.Println("Server failed to start ", err)
+	// internal consumers call the same services over gRPC, on their own port
+	if addr := configs.EnvConfigVars.GrpcPort; addr != "" {
+		srv := rpc.NewServer(db, rpc.Config{
+			MaxAddresses:   configs.EnvConfigVars.MaxAddressesPerRequest,
+			RateLimits:     rateLimits,
+			RateLimitStore: rateLimitStore,
+			Reflection:     configs.EnvConfigVars.GrpcReflection,
+		})
+		if err := serveGRPC(srv, addr); err != nil {
+			fatal("Failed to start the gRPC server", err)
+		}
+	}
+
+	if err := serve(r); err != nil {
+		fatal("Server failed", err)
 	}
 }
+
+// fatal logs err and exits, the deferred calls do not run.
+func fatal(msg string, err error) {
+	slog.Error(msg, slog.Any("error", err))
+	os.Exit(1)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	er "errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+)
+
+// serve runs handler until SIGINT or SIGTERM, then shuts down gracefully: it stops accepting connections,
+// lets in-flight requests finish, waits for background work and closes the registered resources, all within
+// SHUTDOWN_TIMEOUT.
+func serve(handler http.Handler) error {
+	env := configs.EnvConfigVars
+
+	srv := &http.Server{
+		Addr:              env.Port,
+		Handler:           handler,
+		ReadTimeout:       env.HTTPReadTimeout,
+		ReadHeaderTimeout: env.HTTPReadHeaderTimeout,
+		WriteTimeout:      env.HTTPWriteTimeout,
+		IdleTimeout:       env.HTTPIdleTimeout,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Listening on " + env.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		// the server could not start, nothing to drain but the resources
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), env.ShutdownTimeout)
+		defer cancel()
+		return er.Join(err, lifecycle.Shutdown(shutdownCtx))
+
+	case <-ctx.Done():
+	}
+
+	// a second signal kills the process right away
+	stop()
+	slog.Info("Shutting down, draining in-flight requests")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), env.ShutdownTimeout)
+	defer cancel()
+
+	// resources are released even when requests are still running at the timeout
+	if err := er.Join(srv.Shutdown(shutdownCtx), lifecycle.Shutdown(shutdownCtx)); err != nil {
+		return err
+	}
+
+	slog.Info("Server stopped")
+
+	return nil
+}
+
+// serveGRPC serves srv on addr in the background. Shutdown stops it gracefully along with the other background
+// work, calls still running when SHUTDOWN_TIMEOUT ends are cut off before the resources they use are closed.
+func serveGRPC(srv *grpc.Server, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	lifecycle.Go(func() {
+		slog.Info("gRPC listening on " + addr)
+		if err := srv.Serve(lis); err != nil {
+			slog.Error("gRPC server failed", slog.Any("error", err))
+		}
+	})
+	lifecycle.Go(func() {
+		<-lifecycle.Context().Done()
+		srv.GracefulStop()
+	})
+	lifecycle.OnShutdown("gRPC server", func(context.Context) error {
+		srv.Stop()
+		return nil
+	})
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/migrations"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// errUsage is returned for missing or extra arguments, main then prints the usage of the command.
+var errUsage = errors.New("invalid arguments")
+
+// chains maps the chain names accepted on the command line, including the bucket names of the API, to
+// wallet blockchain types.
+var chains = map[string]string{
+	utils.Bitcoin: utils.Bitcoin,
+	"btc":         utils.Bitcoin,
+	utils.Solana:  utils.Solana,
+	"sol":         utils.Solana,
+	utils.Debank:  utils.Debank,
+	"evm":         utils.Debank,
+}
+
+func runMigrate(_ context.Context, db *gorm.DB, args []string) error {
+	return migrations.RunCommand(db, os.Stdout, args)
+}
+
+func runRefreshWallet(ctx context.Context, db *gorm.DB, args []string) error {
+	if len(args) < 2 {
+		return errUsage
+	}
+
+	chain, err := parseChain(args[0])
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, address := range args[1:] {
+		wallet, err := services.RefreshWallet(ctx, db, chain, address)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", address, err)
+			failed++
+			continue
+		}
+		fmt.Printf("refreshed %s wallet %d %s\n", chain, wallet.WalletID, wallet.WalletAddress)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d wallets failed", failed, len(args)-1)
+	}
+
+	return nil
+}
+
+func runRefreshChain(ctx context.Context, db *gorm.DB, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	chain, err := parseChain(args[0])
+	if err != nil {
+		return err
+	}
+
+	refreshed, failed, err := services.RefreshChain(ctx, db, chain)
+	if err != nil {
+		return err
+	}
+
+	addresses := make([]string, 0, len(failed))
+	for address := range failed {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	for _, address := range addresses {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", address, failed[address])
+	}
+	fmt.Printf("refreshed %d %s wallets\n", refreshed, chain)
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d wallets failed", len(failed), refreshed+len(failed))
+	}
+
+	return nil
+}
+
+func runReprice(ctx context.Context, db *gorm.DB, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	feeds, err := services.RefreshPrices(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPRICE\tCURRENCY\tUPDATED AT")
+	for _, feed := range feeds {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", feed.Name, feed.Price, feed.Currency, feed.UpdatedAt.Format(time.RFC3339))
+	}
+
+	return w.Flush()
+}
+
+func runListUsers(_ context.Context, db *gorm.DB, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	users, err := models.GetAllUsers(db)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tPUBLIC KEY\tSIGNED UP\tTOKENS REVOKED AT")
+	for _, user := range users {
+		revokedAt := "-"
+		if user.TokensValidAfter != nil {
+			revokedAt = user.TokensValidAfter.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", user.UserId, user.Username, user.Email, user.PublicKey, user.SignupDate.Format(time.RFC3339), revokedAt)
+	}
+
+	return w.Flush()
+}
+
+func runRevokeTokens(_ context.Context, db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("revoke-tokens", flag.ContinueOnError)
+	apiKeys := flags.Bool("api-keys", false, "also revoke the users' api keys")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return errUsage
+	}
+
+	userIDs := make([]int, 0, flags.NArg())
+	for _, arg := range flags.Args() {
+		userID, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid user id %q", arg)
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	for _, userID := range userIDs {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if _, err := models.RevokeUserTokens(tx, userID); err != nil {
+				return err
+			}
+
+			if !*apiKeys {
+				return nil
+			}
+
+			keys, err := models.GetAPIKeysByUserID(tx, userID)
+			if err != nil {
+				return err
+			}
+
+			for _, key := range keys {
+				if _, err := models.RevokeAPIKey(tx, userID, key.APIKeyID); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("user %d: %w", userID, err)
+		}
+
+		fmt.Printf("revoked the tokens of user %d\n", userID)
+	}
+
+	return nil
+}
+
+func runBackfillPrices(ctx context.Context, db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("backfill-prices", flag.ContinueOnError)
+	currency := flags.String("currency", "usd", "currency of the prices")
+	days := flags.Int("days", 365, "number of days back from today")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 || *days < 1 {
+		return errUsage
+	}
+
+	for _, cryptoID := range flags.Args() {
+		stored, err := services.BackfillPriceHistory(ctx, db, cryptoID, *currency, *days)
+		if err != nil {
+			return fmt.Errorf("%s: %w", cryptoID, err)
+		}
+
+		fmt.Printf("stored %d %s prices of %s\n", stored, *currency, cryptoID)
+	}
+
+	return nil
+}
+
+func runConfig(_ context.Context, _ *gorm.DB, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	for _, setting := range configs.EnvConfigVars.Settings() {
+		fmt.Printf("%s=%s\n", setting.Key, setting.Value)
+	}
+
+	// the configuration is printed even when invalid, so the problems can be seen next to the values
+	return configs.EnvConfigVars.Validate()
+}
+
+func parseChain(name string) (string, error) {
+	chain, ok := chains[name]
+	if !ok {
+		return "", fmt.Errorf("unknown chain %q, expected bitcoin, solana or debank", name)
+	}
+
+	return chain, nil
+}
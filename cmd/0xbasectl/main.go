@@ -0,0 +1,183 @@
+// Command 0xbasectl runs operational jobs against the portfolio database and providers without going through
+// the HTTP API, for use from a shell, cron or a kubernetes job. It reads the same configuration as the server.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
+)
+
+type (
+	// command is a 0xbasectl subcommand.
+	command struct {
+		usage   string
+		summary string
+		offline bool // runs without the database, even when the configuration is invalid
+		run     func(ctx context.Context, db *gorm.DB, args []string) error
+	}
+)
+
+var commands = map[string]command{
+	"migrate": {
+		usage:   "migrate <up|down [N]|status|goto V>",
+		summary: "apply, revert or list schema migrations",
+		run:     runMigrate,
+	},
+	"refresh-wallet": {
+		usage:   "refresh-wallet <chain> <address>...",
+		summary: "fetch wallets from their provider and store them",
+		run:     runRefreshWallet,
+	},
+	"refresh-chain": {
+		usage:   "refresh-chain <chain>",
+		summary: "refresh every stored wallet of a chain",
+		run:     runRefreshChain,
+	},
+	"reprice": {
+		usage:   "reprice",
+		summary: "fetch the current price of every stored price feed",
+		run:     runReprice,
+	},
+	"list-users": {
+		usage:   "list-users",
+		summary: "list users",
+		run:     runListUsers,
+	},
+	"revoke-tokens": {
+		usage:   "revoke-tokens [-api-keys] <user-id>...",
+		summary: "invalidate the tokens, and optionally the api keys, issued to users",
+		run:     runRevokeTokens,
+	},
+	"backfill-prices": {
+		usage:   "backfill-prices [-currency usd] [-days 365] <crypto-id>...",
+		summary: "store the daily price history of cryptos",
+		run:     runBackfillPrices,
+	},
+	"config": {
+		usage:   "config",
+		summary: "print the configuration with secrets redacted",
+		offline: true,
+		run:     runConfig,
+	},
+}
+
+func main() {
+	log.SetFlags(0)
+
+	// configuration flags come before the command: 0xbasectl -config prod.yaml reprice
+	config, args, err := configs.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		printUsage()
+		return
+	}
+	if config == nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	if len(args) == 0 || args[0] == "help" {
+		printUsage()
+		return
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		printUsage()
+		os.Exit(2)
+	}
+
+	configs.EnvConfigVars = config
+
+	ctx := context.Background()
+
+	// the job runs as a single span, flushed before exiting even when the job fails
+	finish := func(error) {}
+
+	var db *gorm.DB
+	if !cmd.offline {
+		if err != nil {
+			log.Fatalf("Invalid configuration: %v", err)
+		}
+
+		// provider and database logs go to stderr like the server's, the output of the job to stdout
+		if err := logging.Setup(configs.EnvConfigVars.LogLevel, configs.EnvConfigVars.LogFormat); err != nil {
+			log.Fatalf("Invalid logging configuration: %v", err)
+		}
+
+		shutdownTracing, err := tracing.Setup(configs.EnvConfigVars)
+		if err != nil {
+			log.Fatalf("Failed to set up tracing: %v", err)
+		}
+
+		var span trace.Span
+		ctx, span = tracing.Start(ctx, "0xbasectl "+args[0])
+		finish = func(err error) {
+			tracing.End(span, err)
+			if err := shutdownTracing(context.Background()); err != nil {
+				log.Printf("Failed to flush traces: %v", err)
+			}
+		}
+
+		db, err = configs.GetDB()
+		if err != nil {
+			log.Fatalf("Failed to connect to the database: %v", err)
+		}
+		if err := db.Use(tracing.GormPlugin{}); err != nil {
+			log.Fatalf("Failed to set up query tracing: %v", err)
+		}
+
+		// jobs share the provider limits, quotas and breakers of the server
+		providers.ConfigureLimits(configs.EnvConfigVars)
+		providers.ConfigureBreakers(configs.EnvConfigVars)
+		ratelimit.SetUsageStore(&models.ProviderUsageStore{DB: db})
+	}
+
+	err = cmd.run(ctx, db, args[1:])
+
+	// usage is persisted in the background, the calls of the job must be counted before exiting
+	if flushErr := ratelimit.Flush(); flushErr != nil {
+		log.Printf("Failed to persist provider usage: %v", flushErr)
+	}
+	finish(err)
+
+	if errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "usage: 0xbasectl %s\n", cmd.usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("%s: %v", args[0], err)
+	}
+}
+
+func printUsage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprint(os.Stderr, "usage: 0xbasectl [configuration flags] <command> [arguments]\n\ncommands:\n")
+
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s\t%s\n", commands[name].usage, commands[name].summary)
+	}
+	w.Flush()
+
+	fmt.Fprint(os.Stderr, "\nchains are bitcoin (btc), solana (sol) or debank (evm)\n")
+}
@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers/names"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+	"github.com/0xbase-Corp/portfolio_svc/shared/validation"
+)
+
+type (
+	// DetectedAddress echoes an entry of a flat address list with the chain family it was routed to.
+	DetectedAddress struct {
+		Input   string `json:"input"`
+		Address string `json:"address"`
+		Chain   string `json:"chain"`          // btc, sol or evm, like the PortfolioAddresses buckets
+		Name    string `json:"name,omitempty"` // set when the input was an ENS or SNS name
+	}
+
+	// WatchedWallet is a wallet a webhook or an alert rule follows.
+	WatchedWallet struct {
+		Chain   string `json:"chain"` // btc, sol or evm
+		Address string `json:"address"`
+	}
+)
+
+// chainFamilies maps wallet blockchain types to the bucket names clients use.
+var chainFamilies = map[string]string{
+	utils.Bitcoin: "btc",
+	utils.Solana:  "sol",
+	utils.Debank:  "evm",
+}
+
+// queryAddresses reads the comma separated addresses query parameter, resolves the names among them and
+// validates it for chain. It returns the addresses and the names they were requested by, keyed by address.
+// On invalid or missing addresses the error response is written and ok is false.
+func queryAddresses(c *gin.Context, chain string) (addresses []string, requested map[string]string, ok bool) {
+	inputs, requested, nameDetails, err := resolveNames(c.Request.Context(), "addresses", chain, strings.Split(c.Query("addresses"), ","))
+	if err != nil {
+		errors.HandleHttpError(c, err)
+		return nil, nil, false
+	}
+
+	addresses, details := validateAddresses("addresses", chain, inputs)
+
+	if details = append(nameDetails, details...); len(details) > 0 {
+		errors.HandleHttpError(c, errors.NewValidationError("invalid "+chain+" addresses", details))
+		return nil, nil, false
+	}
+
+	if len(addresses) == 0 {
+		errors.HandleHttpError(c, errors.NewBadRequestError("empty "+chain+" addresses"))
+		return nil, nil, false
+	}
+	metrics.ObserveFanout(chain, len(addresses))
+
+	return addresses, requested, true
+}
+
+// validateAddresses normalizes addresses for chain and describes every invalid entry as field[index].
+func validateAddresses(field, chain string, addresses []string) ([]string, []errors.ErrorDetail) {
+	normalized, invalid := validation.Addresses(chain, addresses)
+
+	details := make([]errors.ErrorDetail, 0, len(invalid))
+	for _, addressErr := range invalid {
+		details = append(details, errors.ErrorDetail{
+			Field:  fmt.Sprintf("%s[%d]", field, addressErr.Index),
+			Value:  addressErr.Address,
+			Reason: addressErr.Reason,
+		})
+	}
+
+	return normalized, details
+}
+
+// resolveNames replaces the names of chain among inputs by the addresses they resolve to. Names that do not
+// resolve are blanked and reported as details; err is only set when the resolver could not be reached.
+func resolveNames(ctx context.Context, field, chain string, inputs []string) ([]string, map[string]string, []errors.ErrorDetail, error) {
+	resolved := make([]string, len(inputs))
+	requested := make(map[string]string)
+	details := make([]errors.ErrorDetail, 0)
+
+	for i, input := range inputs {
+		resolved[i] = input
+
+		input = strings.TrimSpace(input)
+		if !names.IsName(input) {
+			continue
+		}
+
+		if nameChain, err := names.Chain(input); err != nil || nameChain != chain {
+			// left for validation to reject as an address of chain
+			continue
+		}
+
+		address, err := names.Resolve(ctx, input)
+		if er.Is(err, names.ErrNotFound) {
+			details = append(details, errors.ErrorDetail{Field: fmt.Sprintf("%s[%d]", field, i), Value: input, Reason: err.Error()})
+			resolved[i] = ""
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		resolved[i] = address
+		requested[validation.Normalize(chain, address)] = strings.ToLower(input)
+	}
+
+	return resolved, requested, details, nil
+}
+
+// detectAddresses routes every entry of a flat address list to the chain its format, or the suffix of a
+// name, matches. Entries that match no chain or more than one, and names that do not resolve, are returned
+// as details instead; err is only set when a name resolver could not be reached.
+func detectAddresses(ctx context.Context, inputs []string) ([]DetectedAddress, []errors.ErrorDetail, error) {
+	detected := make([]DetectedAddress, 0, len(inputs))
+	details := make([]errors.ErrorDetail, 0)
+
+	for i, input := range inputs {
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		reject := func(err error) {
+			details = append(details, errors.ErrorDetail{Field: fmt.Sprintf("addresses[%d]", i), Value: input, Reason: err.Error()})
+		}
+
+		if names.IsName(input) {
+			chain, err := names.Chain(input)
+			if err != nil {
+				reject(err)
+				continue
+			}
+
+			address, err := names.Resolve(ctx, input)
+			if er.Is(err, names.ErrNotFound) {
+				reject(err)
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+
+			detected = append(detected, DetectedAddress{
+				Input:   input,
+				Address: validation.Normalize(chain, address),
+				Chain:   chainFamilies[chain],
+				Name:    strings.ToLower(input),
+			})
+			continue
+		}
+
+		chain, err := validation.Detect(input)
+		if err != nil {
+			reject(err)
+			continue
+		}
+
+		detected = append(detected, DetectedAddress{
+			Input:   input,
+			Address: validation.Normalize(chain, input),
+			Chain:   chainFamilies[chain],
+		})
+	}
+
+	return detected, details, nil
+}
+
+// watchWallets detects the chain of each of inputs, resolving names, and returns the wallets as the unique
+// chain:address entries stored by webhooks and alert rules, at most MAX_ADDRESSES_PER_REQUEST of them. owner
+// names what follows them in errors. On invalid or missing addresses the error response is written and ok is
+// false.
+func watchWallets(c *gin.Context, inputs []string, owner string) (wallets []string, ok bool) {
+	detected, rejected, err := detectAddresses(c.Request.Context(), inputs)
+	if err != nil {
+		errors.HandleHttpError(c, err)
+		return nil, false
+	}
+
+	if len(rejected) > 0 {
+		errors.HandleHttpError(c, errors.NewValidationError("invalid addresses", rejected))
+		return nil, false
+	}
+
+	if len(detected) == 0 {
+		errors.HandleHttpError(c, errors.NewBadRequestError("empty addresses"))
+		return nil, false
+	}
+
+	if max := configs.EnvConfigVars.MaxAddressesPerRequest; len(detected) > max {
+		errors.HandleHttpError(c, errors.NewBadRequestError("too many addresses: at most "+strconv.Itoa(max)+" per "+owner))
+		return nil, false
+	}
+
+	wallets = make([]string, 0, len(detected))
+	for _, address := range detected {
+		wallets = append(wallets, familyChain(address.Chain)+":"+address.Address)
+	}
+
+	return utils.UniqueAddress(wallets), true
+}
+
+// newWatchedWallets returns chain:address entries as they are shown to clients.
+func newWatchedWallets(entries []string) []WatchedWallet {
+	wallets := make([]WatchedWallet, 0, len(entries))
+	for _, entry := range entries {
+		chain, address, _ := strings.Cut(entry, ":")
+		wallets = append(wallets, WatchedWallet{Chain: chainFamilies[chain], Address: address})
+	}
+
+	return wallets
+}
+
+// nameLookups bounds the reverse lookups running at once for a response.
+const nameLookups = 8
+
+// nameWallets passes on every wallet of ch after naming it: wallets requested by name keep that name, the
+// others get the reverse name of their address when there is one. Names are stored on the wallet. The lookups
+// run concurrently, and those still running after NAME_LOOKUP_TIMEOUT leave the stored names as they are.
+func nameWallets(ctx context.Context, db *gorm.DB, chain string, ch <-chan *models.GlobalWallet, requested map[string]string) <-chan *models.GlobalWallet {
+	ctx, cancel := context.WithTimeout(ctx, configs.EnvConfigVars.NameLookupTimeout)
+	defer cancel()
+
+	wallets := make([]*models.GlobalWallet, 0, cap(ch))
+	for wallet := range ch {
+		wallets = append(wallets, wallet)
+	}
+
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, nameLookups)
+	for _, wallet := range wallets {
+		if wallet == nil {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(wallet *models.GlobalWallet) {
+			defer func() { <-sem; wg.Done() }()
+			nameWallet(ctx, db, chain, wallet, requested[wallet.WalletAddress])
+		}(wallet)
+	}
+	wg.Wait()
+
+	named := make(chan *models.GlobalWallet, len(wallets))
+	for _, wallet := range wallets {
+		named <- wallet
+	}
+	close(named)
+
+	return named
+}
+
+func nameWallet(ctx context.Context, db *gorm.DB, chain string, wallet *models.GlobalWallet, name string) {
+	if name == "" {
+		// keep the stored name when there is no reverse record or the resolver is unavailable
+		reverse, err := names.Reverse(ctx, chain, wallet.WalletAddress)
+		if err != nil {
+			return
+		}
+		name = reverse
+	}
+
+	if name == wallet.Name {
+		return
+	}
+
+	// naming is best effort and must not fail the request
+	_ = models.UpdateWalletName(db.WithContext(ctx), wallet, name)
+}
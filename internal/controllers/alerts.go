@@ -0,0 +1,440 @@
+package controllers
+
+import (
+	"net/http"
+	"net/mail"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+const (
+	// alertHistoryLimit is the number of alerts listed, the latest ones.
+	alertHistoryLimit = 100
+
+	// alertMinWindow and alertMaxWindow bound the period portfolio_change rules compare over, snapshots are
+	// taken every few minutes and kept for twice the window.
+	alertMinWindow = 15 * time.Minute
+	alertMaxWindow = 31 * 24 * time.Hour
+)
+
+// coingeckoID matches the ids of coins on coingecko, such as bitcoin or usd-coin.
+var coingeckoID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type (
+	AlertRuleRequest struct {
+		Name      string           `json:"name" binding:"required"`
+		Kind      string           `json:"kind" binding:"required"`      // price, portfolio_change or allocation
+		Asset     string           `json:"asset"`                        // a coingecko id for price, btc, sol or evm for allocation
+		Direction string           `json:"direction" binding:"required"` // above or below, a rise or a drop for portfolio_change
+		Threshold *decimal.Decimal `json:"threshold" binding:"required"` // usd for price, percent otherwise
+		Window    string           `json:"window"`                       // such as 24h, required by portfolio_change
+		Addresses []string         `json:"addresses"`                    // addresses or names, required by portfolio_change and allocation
+		Channels  []string         `json:"channels" binding:"required"`  // webhook and, when configured, email
+		WebhookID *int             `json:"webhook_id"`                   // required by the webhook channel
+		Email     string           `json:"email"`                        // the email of the account, which the email channel mails
+		Cooldown  string           `json:"cooldown"`                     // such as 30m, defaults to ALERT_COOLDOWN
+		Enabled   *bool            `json:"enabled"`                      // defaults to true
+	}
+
+	AlertRuleResponse struct {
+		RuleID          int             `json:"rule_id"`
+		Name            string          `json:"name"`
+		Kind            string          `json:"kind"`
+		Asset           string          `json:"asset,omitempty"`
+		Direction       string          `json:"direction"`
+		Threshold       decimal.Decimal `json:"threshold"`
+		Window          string          `json:"window,omitempty"`
+		Wallets         []WatchedWallet `json:"wallets"`
+		Channels        []string        `json:"channels"`
+		WebhookID       *int            `json:"webhook_id"`
+		Email           string          `json:"email,omitempty"`
+		Cooldown        string          `json:"cooldown"`
+		Enabled         bool            `json:"enabled"`
+		Triggered       bool            `json:"triggered"` // the condition held when the rule was last evaluated
+		LastTriggeredAt *time.Time      `json:"last_triggered_at"`
+		UpdatedAt       time.Time       `json:"updated_at"`
+		CreatedAt       time.Time       `json:"created_at"`
+	}
+)
+
+//	@BasePath	/api/v1
+
+// CreateAlertRuleController godoc
+//
+// @Summary      Create an alert rule
+// @Description  Alerts when the usd price of asset is above or below threshold (price), when the value of the wallets of
+// @Description  addresses rose or dropped threshold percent within window (portfolio_change), or when the chain asset is
+// @Description  above or below threshold percent of their value (allocation). Prices are evaluated as they are fetched,
+// @Description  portfolios as their wallets change and every WATCH_INTERVAL. A rule triggers when its condition starts
+// @Description  holding, and not again before it stopped holding or within its cooldown. The email channel mails the account email.
+// @Tags         alerts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body AlertRuleRequest true "AlertRuleRequest object"
+// @Success      201 {object} AlertRuleResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /alerts [post]
+func CreateAlertRuleController(c *gin.Context, db *gorm.DB) {
+	rule := &models.AlertRule{UserID: c.GetInt(utils.ContextUserID)}
+
+	if !bindAlertRule(c, db, rule) {
+		return
+	}
+
+	if err := models.CreateAlertRule(db, rule); err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while creating alert rule", err))
+		return
+	}
+	services.InvalidateAlertRules()
+
+	c.JSON(http.StatusCreated, newAlertRuleResponse(rule))
+}
+
+//	@BasePath	/api/v1
+
+// ListAlertRulesController godoc
+//
+// @Summary      List alert rules
+// @Description  Lists the alert rules of the signed in user, newest first.
+// @Tags         alerts
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} []AlertRuleResponse
+// @Failure      401 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /alerts [get]
+func ListAlertRulesController(c *gin.Context, db *gorm.DB) {
+	rules, err := models.GetAlertRules(db, c.GetInt(utils.ContextUserID))
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while listing alert rules", err))
+		return
+	}
+
+	response := make([]*AlertRuleResponse, 0, len(rules))
+	for _, rule := range rules {
+		response = append(response, newAlertRuleResponse(rule))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+//	@BasePath	/api/v1
+
+// GetAlertRuleController godoc
+//
+// @Summary      Get an alert rule
+// @Tags         alerts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        rule_id path int true "Rule ID" Format(int)
+// @Success      200 {object} AlertRuleResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Router       /alerts/{rule_id} [get]
+func GetAlertRuleController(c *gin.Context, db *gorm.DB) {
+	rule, ok := ownedAlertRule(c, db)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, newAlertRuleResponse(rule))
+}
+
+//	@BasePath	/api/v1
+
+// UpdateAlertRuleController godoc
+//
+// @Summary      Update an alert rule
+// @Description  Replaces every field of an alert rule. The portfolio snapshots of the rule are dropped, so a
+// @Description  portfolio_change rule only compares with values taken after the update.
+// @Tags         alerts
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        rule_id path int true "Rule ID" Format(int)
+// @Param        request body AlertRuleRequest true "AlertRuleRequest object"
+// @Success      200 {object} AlertRuleResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /alerts/{rule_id} [put]
+func UpdateAlertRuleController(c *gin.Context, db *gorm.DB) {
+	rule, ok := ownedAlertRule(c, db)
+	if !ok {
+		return
+	}
+
+	if !bindAlertRule(c, db, rule) {
+		return
+	}
+
+	if err := models.UpdateAlertRule(db, rule); err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while updating alert rule", err))
+		return
+	}
+	services.InvalidateAlertRules()
+
+	c.JSON(http.StatusOK, newAlertRuleResponse(rule))
+}
+
+//	@BasePath	/api/v1
+
+// DeleteAlertRuleController godoc
+//
+// @Summary      Delete an alert rule
+// @Description  Deletes an alert rule with its history.
+// @Tags         alerts
+// @Security     BearerAuth
+// @Param        rule_id path int true "Rule ID" Format(int)
+// @Success      204
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Router       /alerts/{rule_id} [delete]
+func DeleteAlertRuleController(c *gin.Context, db *gorm.DB) {
+	ruleID, err := strconv.Atoi(c.Param("rule-id"))
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError("invalid rule id"))
+		return
+	}
+
+	if err := models.DeleteAlertRule(db, c.GetInt(utils.ContextUserID), ruleID); err != nil {
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "alert rule not found"))
+		return
+	}
+	services.InvalidateAlertRules()
+
+	c.Status(http.StatusNoContent)
+}
+
+//	@BasePath	/api/v1
+
+// ListAlertHistoryController godoc
+//
+// @Summary      List the history of an alert rule
+// @Description  Lists the latest alerts of a rule, newest first, with the channels they were delivered on and why
+// @Description  the others failed.
+// @Tags         alerts
+// @Produce      json
+// @Security     BearerAuth
+// @Param        rule_id path int true "Rule ID" Format(int)
+// @Success      200 {object} []models.AlertHistory
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Router       /alerts/{rule_id}/history [get]
+func ListAlertHistoryController(c *gin.Context, db *gorm.DB) {
+	rule, ok := ownedAlertRule(c, db)
+	if !ok {
+		return
+	}
+
+	history, err := models.GetAlertHistory(db, rule.RuleID, alertHistoryLimit)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while listing alert history", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
+// ownedAlertRule loads the rule of the rule-id path parameter if the signed in user owns it. Otherwise the
+// error response is written and ok is false.
+func ownedAlertRule(c *gin.Context, db *gorm.DB) (*models.AlertRule, bool) {
+	ruleID, err := strconv.Atoi(c.Param("rule-id"))
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError("invalid rule id"))
+		return nil, false
+	}
+
+	rule, err := models.GetAlertRule(db, c.GetInt(utils.ContextUserID), ruleID)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "alert rule not found"))
+		return nil, false
+	}
+
+	return rule, true
+}
+
+// bindAlertRule validates the AlertRuleRequest body and sets the fields of rule from it. On an invalid request
+// the error response is written and ok is false.
+func bindAlertRule(c *gin.Context, db *gorm.DB, rule *models.AlertRule) (ok bool) {
+	request := &AlertRuleRequest{}
+
+	if err := c.ShouldBindJSON(request); err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError(err.Error()))
+		return false
+	}
+
+	fail := func(detail string) bool {
+		errors.HandleHttpError(c, errors.NewBadRequestError(detail))
+		return false
+	}
+
+	if request.Direction != services.AlertAbove && request.Direction != services.AlertBelow {
+		return fail("direction must be " + services.AlertAbove + " or " + services.AlertBelow)
+	}
+
+	if !request.Threshold.IsPositive() {
+		return fail("threshold must be positive")
+	}
+
+	var window time.Duration
+	asset := request.Asset
+
+	switch request.Kind {
+	case models.AlertPrice:
+		if !coingeckoID.MatchString(asset) {
+			return fail("asset must be a coingecko id such as bitcoin")
+		}
+
+	case models.AlertPortfolioChange:
+		parsed, err := time.ParseDuration(request.Window)
+		if err != nil || parsed < alertMinWindow || parsed > alertMaxWindow {
+			return fail("window must be a duration between " + alertMinWindow.String() + " and " + alertMaxWindow.String())
+		}
+		window = parsed
+		asset = ""
+
+	case models.AlertAllocation:
+		if request.Threshold.GreaterThan(decimal.NewFromInt(100)) {
+			return fail("threshold must be a percent of at most 100")
+		}
+		if _, ok := chainFamilies[familyChain(asset)]; !ok {
+			return fail("asset must be btc, sol or evm")
+		}
+		asset = familyChain(asset)
+
+	default:
+		return fail("kind must be " + strings.Join([]string{models.AlertPrice, models.AlertPortfolioChange, models.AlertAllocation}, ", "))
+	}
+
+	wallets := []string{}
+	if request.Kind != models.AlertPrice {
+		if wallets, ok = watchWallets(c, request.Addresses, "alert rule"); !ok {
+			return false
+		}
+	}
+
+	channels := utils.Unique(request.Channels)
+	if len(channels) == 0 {
+		return fail("channels must not be empty")
+	}
+
+	for _, channel := range channels {
+		if !containsString(services.AlertChannels(), channel) {
+			return fail("unknown channel " + channel + ", expected one of " + strings.Join(services.AlertChannels(), ", "))
+		}
+	}
+
+	if containsString(channels, services.AlertChannelWebhook) {
+		if request.WebhookID == nil {
+			return fail("webhook_id is required by the webhook channel")
+		}
+
+		webhook, err := models.GetWebhook(db, rule.UserID, nil, *request.WebhookID)
+		if err != nil {
+			errors.HandleHttpError(c, errors.NewDatabaseError(err, "webhook not found"))
+			return false
+		}
+
+		if webhook.IsDisabled() {
+			return fail("webhook was deleted")
+		}
+	} else {
+		request.WebhookID = nil
+	}
+
+	email := ""
+	if containsString(channels, services.AlertChannelEmail) {
+		// alerts are only mailed to the verified email of the account
+		user, err := models.GetUserById(db, rule.UserID)
+		if err != nil {
+			errors.HandleHttpError(c, errors.NewDatabaseError(err, "user not found"))
+			return false
+		}
+
+		if user.Email == "" {
+			return fail("the email channel requires an account with an email")
+		}
+
+		if request.Email != "" {
+			address, err := mail.ParseAddress(request.Email)
+			if err != nil || !strings.EqualFold(address.Address, user.Email) {
+				return fail("email must be the email of the account")
+			}
+		}
+		email = user.Email
+	}
+
+	cooldown := configs.EnvConfigVars.AlertCooldown
+	if request.Cooldown != "" {
+		parsed, err := time.ParseDuration(request.Cooldown)
+		if err != nil || parsed < 0 {
+			return fail("cooldown must be a duration such as 30m")
+		}
+		cooldown = parsed
+	}
+
+	rule.Name = request.Name
+	rule.Kind = request.Kind
+	rule.Asset = asset
+	rule.Direction = request.Direction
+	rule.Threshold = *request.Threshold
+	rule.WindowSeconds = int(window / time.Second)
+	rule.Wallets = strings.Join(wallets, ",")
+	rule.Channels = strings.Join(channels, ",")
+	rule.WebhookID = request.WebhookID
+	rule.Email = email
+	rule.CooldownSeconds = int(cooldown / time.Second)
+	rule.Enabled = request.Enabled == nil || *request.Enabled
+
+	return true
+}
+
+func newAlertRuleResponse(rule *models.AlertRule) *AlertRuleResponse {
+	response := &AlertRuleResponse{
+		RuleID:          rule.RuleID,
+		Name:            rule.Name,
+		Kind:            rule.Kind,
+		Asset:           rule.Asset,
+		Direction:       rule.Direction,
+		Threshold:       rule.Threshold,
+		Wallets:         newWatchedWallets(rule.WalletList()),
+		Channels:        rule.ChannelList(),
+		WebhookID:       rule.WebhookID,
+		Email:           rule.Email,
+		Cooldown:        rule.Cooldown().String(),
+		Enabled:         rule.Enabled,
+		Triggered:       rule.Triggered,
+		LastTriggeredAt: rule.LastTriggeredAt,
+		UpdatedAt:       rule.UpdatedAt,
+		CreatedAt:       rule.CreatedAt,
+	}
+
+	if rule.Kind == models.AlertAllocation {
+		response.Asset = chainFamilies[rule.Asset]
+	}
+	if rule.WindowSeconds > 0 {
+		response.Window = rule.Window().String()
+	}
+
+	return response
+}
@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type (
+	CreateAPIKeyRequest struct {
+		Name      string   `json:"name" binding:"required"`
+		Scopes    []string `json:"scopes"`     // defaults to every scope
+		RateLimit int      `json:"rate_limit"` // requests per minute, 0 uses the route limits
+	}
+
+	APIKeyResponse struct {
+		APIKeyID   int        `json:"api_key_id"`
+		Name       string     `json:"name"`
+		Prefix     string     `json:"prefix"`
+		Key        string     `json:"key,omitempty"` // only returned once, on creation
+		Scopes     []string   `json:"scopes"`
+		RateLimit  int        `json:"rate_limit"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+		RevokedAt  *time.Time `json:"revoked_at"`
+		CreatedAt  time.Time  `json:"created_at"`
+	}
+)
+
+//	@BasePath	/api/v1
+
+// CreateAPIKeyController godoc
+//
+// @Summary      Create an API key
+// @Description  Creates a scoped API key for server-to-server calls. The key is only returned in this response.
+// @Tags         api-keys
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateAPIKeyRequest true "CreateAPIKeyRequest object"
+// @Success      201 {object} APIKeyResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /api-keys [post]
+func CreateAPIKeyController(c *gin.Context, db *gorm.DB) {
+	request := &CreateAPIKeyRequest{}
+
+	if err := c.ShouldBindJSON(request); err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	scopes := utils.Unique(request.Scopes)
+	if len(scopes) == 0 {
+		scopes = utils.Scopes
+	}
+
+	for _, scope := range scopes {
+		if !isKnownScope(scope) {
+			errors.HandleHttpError(c, errors.NewBadRequestError("unknown scope "+scope+", expected one of "+strings.Join(utils.Scopes, ", ")))
+			return
+		}
+	}
+
+	if request.RateLimit < 0 {
+		errors.HandleHttpError(c, errors.NewBadRequestError("rate_limit must not be negative"))
+		return
+	}
+
+	key, prefix, hash, err := utils.GenerateAPIKey()
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while generating api key", err))
+		return
+	}
+
+	apiKey := &models.APIKey{
+		UserID:    c.GetInt(utils.ContextUserID),
+		Name:      request.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    strings.Join(scopes, ","),
+		RateLimit: request.RateLimit,
+	}
+
+	if err := models.CreateAPIKey(db, apiKey); err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while creating api key", err))
+		return
+	}
+
+	response := newAPIKeyResponse(apiKey)
+	response.Key = key
+
+	c.JSON(http.StatusCreated, response)
+}
+
+//	@BasePath	/api/v1
+
+// ListAPIKeysController godoc
+//
+// @Summary      List API keys
+// @Description  Lists the API keys of the signed in user, including revoked ones.
+// @Tags         api-keys
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} []APIKeyResponse
+// @Failure      401 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /api-keys [get]
+func ListAPIKeysController(c *gin.Context, db *gorm.DB) {
+	apiKeys, err := models.GetAPIKeysByUserID(db, c.GetInt(utils.ContextUserID))
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while listing api keys", err))
+		return
+	}
+
+	response := make([]*APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		response = append(response, newAPIKeyResponse(apiKey))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+//	@BasePath	/api/v1
+
+// RevokeAPIKeyController godoc
+//
+// @Summary      Revoke an API key
+// @Description  Revokes one of the signed in user's API keys. Revoked keys are rejected immediately.
+// @Tags         api-keys
+// @Produce      json
+// @Security     BearerAuth
+// @Param        api_key_id path int true "API key ID" Format(int)
+// @Success      200 {object} APIKeyResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Router       /api-keys/{api_key_id} [delete]
+func RevokeAPIKeyController(c *gin.Context, db *gorm.DB) {
+	apiKeyID, err := strconv.Atoi(c.Param("api-key-id"))
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError("invalid api key id"))
+		return
+	}
+
+	apiKey, err := models.RevokeAPIKey(db, c.GetInt(utils.ContextUserID), apiKeyID)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "api key not found"))
+		return
+	}
+
+	c.JSON(http.StatusOK, newAPIKeyResponse(apiKey))
+}
+
+func newAPIKeyResponse(apiKey *models.APIKey) *APIKeyResponse {
+	return &APIKeyResponse{
+		APIKeyID:   apiKey.APIKeyID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.ScopeList(),
+		RateLimit:  apiKey.RateLimit,
+		LastUsedAt: apiKey.LastUsedAt,
+		RevokedAt:  apiKey.RevokedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+func isKnownScope(scope string) bool {
+	for _, s := range utils.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
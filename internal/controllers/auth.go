@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	er "errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -43,9 +44,9 @@ type (
 // @Produce      json
 // @Param        request body GenerateHashRequest true  "GenerateHashRequest object"
 // @Success      200 {object} GenerateHashResonse
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
 // @Router       /generate-hash [post]
 func AuthGenerateHash(c *gin.Context, db *gorm.DB) {
 	request := &GenerateHashRequest{}
@@ -57,7 +58,11 @@ func AuthGenerateHash(c *gin.Context, db *gorm.DB) {
 
 	var response GenerateHashResonse
 	// check in user table to find the record with this key is exist pass other wise create
-	exist, _ := models.GetUserByPublicKey(db, request.PublicKey)
+	exist, err := models.GetUserByPublicKey(db, request.PublicKey)
+	if err != nil && !er.Is(err, gorm.ErrRecordNotFound) {
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, ""))
+		return
+	}
 
 	if exist != nil {
 		response = GenerateHashResonse{
@@ -73,8 +78,12 @@ func AuthGenerateHash(c *gin.Context, db *gorm.DB) {
 		}
 
 		err := models.CreateUser(db, user)
+		if er.Is(err, gorm.ErrDuplicatedKey) {
+			errors.HandleHttpError(c, errors.NewConflictError("a user with this public key already exists"))
+			return
+		}
 		if err != nil {
-			errors.HandleHttpError(c, errors.NewBadRequestError("Error while creating user: "+err.Error()))
+			errors.HandleHttpError(c, errors.NewInternalError("error while creating user", err))
 			return
 		}
 
@@ -98,9 +107,9 @@ func AuthGenerateHash(c *gin.Context, db *gorm.DB) {
 // @Produce      json
 // @Param        request body VerifyRequest true  "VerifyRequest object"
 // @Success      200 {object} TokenResponse
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
 // @Router       /verify-hash [post]
 func AuthVerifyHashKey(c *gin.Context, db *gorm.DB) {
 	request := &VerifyRequest{}
@@ -110,9 +119,9 @@ func AuthVerifyHashKey(c *gin.Context, db *gorm.DB) {
 		return
 	}
 
-	user, _ := models.GetUserByPublicKey(db, request.PublicKey)
-	if user == nil {
-		errors.HandleHttpError(c, errors.NewNotFoundError("User not found"))
+	user, err := models.GetUserByPublicKey(db, request.PublicKey)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "User not found"))
 		return
 	}
 
@@ -127,13 +136,13 @@ func AuthVerifyHashKey(c *gin.Context, db *gorm.DB) {
 	// generate jwt token
 	accessToken, err := utils.GenerateAccessToken(user.UserId, user.Email, user.PublicKey)
 	if err != nil {
-		errors.HandleHttpError(c, errors.NewBadRequestError(err.Error()))
+		errors.HandleHttpError(c, errors.NewInternalError("error while generating access token", err))
 		return
 	}
 
 	refreshToken, err := utils.GenerateRefreshToken(user.UserId, user.Email, user.PublicKey)
 	if err != nil {
-		errors.HandleHttpError(c, errors.NewBadRequestError(err.Error()))
+		errors.HandleHttpError(c, errors.NewInternalError("error while generating refresh token", err))
 		return
 	}
 
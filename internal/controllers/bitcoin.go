@@ -1,10 +1,10 @@
 package controllers
 
 import (
+	"context"
 	er "errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -12,9 +12,12 @@ import (
 
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/internal/responses"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
 	"github.com/0xbase-Corp/portfolio_svc/providers"
-	"github.com/0xbase-Corp/portfolio_svc/providers/bitcoin"
+	"github.com/0xbase-Corp/portfolio_svc/shared/breaker"
 	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
@@ -29,49 +32,47 @@ import (
 // @Produce      json
 // @Param        addresses  query      array  true  "Bitcoin Addresses" Format(string)
 // @Success      200 {object} []responses.PortfolioResponse
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      429 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Failure      502 {object} errors.Problem
+// @Failure      503 {object} errors.Problem
 // @Router       /portfolio/btc [get]
 func BitcoinController(c *gin.Context, db *gorm.DB, apiClient providers.APIClient) {
-	addresses := c.Query("addresses")
-	btcAddresses := strings.Split(addresses, ",")
-
-	btcAddresses = utils.UniqueAddress(btcAddresses)
-
-	if len(btcAddresses) == 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError("empty btc addresses"))
+	btcAddresses, _, ok := queryAddresses(c, utils.Bitcoin)
+	if !ok {
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-	mutex := &sync.Mutex{}
-	ch := make(chan *models.GlobalWallet, len(btcAddresses)) // len(btcAddresses) specifies the buffer size of the channel
-	errorCh := make(chan error, len(btcAddresses))
+	serveCached(c, portfolioKey(utils.Bitcoin, nil, btcAddresses), services.CacheTTL(utils.Bitcoin), func(ctx context.Context) (interface{}, error) {
+		wg := &sync.WaitGroup{}
+		mutex := &sync.Mutex{}
+		ch := make(chan *models.GlobalWallet, len(btcAddresses)) // len(btcAddresses) specifies the buffer size of the channel
+		errorCh := make(chan error, len(btcAddresses))
 
-	for _, btcAddress := range btcAddresses {
-		wg.Add(1)
+		for _, btcAddress := range btcAddresses {
+			wg.Add(1)
 
-		go fetchAndSaveBtc(db, apiClient, btcAddress, ch, wg, mutex, errorCh)
-	}
+			// tracked so that a shutdown lets the save finish
+			address := btcAddress
+			lifecycle.Go(func() { fetchAndSaveBtc(ctx, db.WithContext(ctx), apiClient, address, ch, wg, mutex, errorCh) })
+		}
 
-	// Use a goroutine to close the channel after all goroutines have finished
-	go func() {
-		wg.Wait()
-		close(ch)
-		close(errorCh)
-	}()
+		// Use a goroutine to close the channel after all goroutines have finished
+		go func() {
+			wg.Wait()
+			close(ch)
+			close(errorCh)
+		}()
 
-	errs := errors.HandleChannelErrors(errorCh, c)
-	if len(errs) > 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError(strings.Join(errs, "; ")))
-		return
-	}
-
-	// Collect all results from the channel and process to genernic response for btc
-	responses := processBtcResponses(ch)
+		if apiErr := errors.HandleChannelErrors(errorCh); apiErr != nil {
+			return nil, apiErr
+		}
 
-	c.JSON(http.StatusOK, responses)
+		// Collect all results from the channel and process to genernic response for btc
+		return processBtcResponses(ch), nil
+	})
 }
 
 //	@BasePath	/api/v1
@@ -86,9 +87,9 @@ func BitcoinController(c *gin.Context, db *gorm.DB, apiClient providers.APIClien
 // @Param        offset query int false "Pagination offset" Format(int)
 // @Param        limit query int false "Pagination limit" Format(int)
 // @Success      200 {object} models.GlobalWallet
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
 // @Router       /portfolio/btc-wallet/{wallet_id} [get]
 func GetBtcDataController(c *gin.Context, db *gorm.DB) {
 	wallet := models.GlobalWallet{}
@@ -124,84 +125,33 @@ func GetBtcDataController(c *gin.Context, db *gorm.DB) {
 		First(&wallet).Error
 
 	if err != nil {
-		errors.HandleHttpError(c, errors.NewNotFoundError("wallet not found"))
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "wallet not found"))
 		return
 	}
 
 	c.JSON(http.StatusOK, wallet)
 }
 
-// helper to save data
-// TODO: write a common interface in provider which saves the data in database
-func saveBtc(db *gorm.DB, btcAddress string, apiResponse bitcoin.BtcApiResponse) (*models.GlobalWallet, error) {
-	// Begin a new transaction
-	tx := db.Begin()
-
-	wallet, err := models.GetOrCreateWallet(tx, btcAddress, utils.Bitcoin)
-	if err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Assuming wallet is the GlobalWallet record found or created
-	walletID := wallet.WalletID
-
-	// Initialize btcComV1 and set the WalletID
-	btcComV1 := models.BitcoinBtcComV1{}
-	btcComV1.WalletID = uint(walletID)
-
-	// Proceed to call SaveBitcoinData with the updated BitcoinAddressInfo and btcComV1
-	if err := models.SaveBitcoinData(tx, &apiResponse.Data, &btcComV1); err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// save the bitcoin price feed
-	// for now hard code the USD -> TODO: change
-	if err := HandleCoingeckoPrice(tx, utils.Bitcoin, "usd"); err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		return &models.GlobalWallet{}, err
-	}
-
-	// get the data
-	walletResponse, err := models.GetGlobalWalletWithBitcoinInfo(db, btcAddress)
-	if err != nil {
-		return &models.GlobalWallet{}, err
-	}
-
-	return walletResponse, nil
-}
-
 // Fetch and save the data for one address
 // TODO: write a common interface in provider which saves the data in database
-func fetchAndSaveBtc(db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+func fetchAndSaveBtc(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
 	defer wg.Done()
 
-	body, err := apiClient.FetchData(address)
-	if err != nil {
-		errorCh <- err
-		return
-	}
-
-	// ignore the Address which returns error or empty response
-	resp := bitcoin.BtcApiResponse{}
-	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
-		errorCh <- err
-		return
+	loadStored := func() (*models.GlobalWallet, error) {
+		return models.GetGlobalWalletWithBitcoinInfo(db, address)
 	}
 
-	if resp.Status == "fail" {
-		errorCh <- er.New("API error: " + resp.Message)
+	// serve the stored wallet while btc.com is unavailable or its daily budget is nearly spent
+	if services.UseStoredData(utils.ProviderBtcCom) && sendStoredWallet(loadStored, ch, mutex) {
 		return
 	}
 
-	walletResponse, err := saveBtc(db, address, resp)
+	walletResponse, err := services.RefreshBitcoin(ctx, db, apiClient, address)
 	if err != nil {
+		if er.Is(err, breaker.ErrOpen) && sendStoredWallet(loadStored, ch, mutex) {
+			return
+		}
+
 		errorCh <- err
 		return
 	}
@@ -219,6 +169,7 @@ func processBtcResponses(ch <-chan *models.GlobalWallet) []*responses.PortfolioR
 		if walletResponse == nil || walletResponse.BitcoinBtcComV1 == nil {
 			continue
 		}
+		metrics.ObserveWalletStaleness(utils.Bitcoin, walletResponse.LastUpdatedAt)
 
 		btcResponse := &responses.PortfolioResponse{}
 		btcResponse.BitcoinPortfolioResponse(walletResponse)
@@ -0,0 +1,82 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/cache"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+)
+
+// serveCached answers the JSON of the payload build returns, cached under key for ttl: identical requests
+// within ttl are answered from the cache, and concurrent ones from a single build. The response carries an
+// ETag, and a GET whose If-None-Match matches it is answered 304 Not Modified.
+//
+// The build serves every concurrent request, so it outlives the one that started it: it gets a context that
+// keeps the trace and request ID of that request but not its cancellation, and must not use the gin context.
+func serveCached(c *gin.Context, key string, ttl time.Duration, build func(ctx context.Context) (interface{}, error)) {
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	body, _, err := cache.Load(ctx, key, ttl, func() ([]byte, error) {
+		payload, err := build(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(payload)
+	})
+	if err != nil {
+		errors.HandleHttpError(c, err)
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Header("ETag", etag)
+
+	if c.Request.Method == http.MethodGet && etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, or is "*".
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// portfolioKey returns the cache key of a portfolio of kind over the given address lists. Lists are keyed
+// as sets, names requested for addresses are part of the key since they are part of the response.
+func portfolioKey(kind string, requested map[string]string, lists ...[]string) string {
+	hash := sha256.New()
+
+	for _, list := range lists {
+		sorted := append([]string(nil), list...)
+		sort.Strings(sorted)
+
+		for _, address := range sorted {
+			hash.Write([]byte(address + "=" + requested[address] + ","))
+		}
+		hash.Write([]byte("|"))
+	}
+
+	// prices are only fetched in usd for now
+	return "portfolio:" + kind + ":usd:" + hex.EncodeToString(hash.Sum(nil))
+}
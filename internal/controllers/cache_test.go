@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+	"github.com/0xbase-Corp/portfolio_svc/shared/cache"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+)
+
+func TestServeCached(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cache.SetStore(cache.NewMemoryStore(10))
+	t.Cleanup(func() { cache.SetStore(cache.NewMemoryStore(10000)) })
+
+	builds := 0
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler())
+	router.Any("/portfolio", func(c *gin.Context) {
+		serveCached(c, "test:portfolio", time.Minute, func(ctx context.Context) (interface{}, error) {
+			builds++
+			return gin.H{"total": "42"}, nil
+		})
+	})
+	router.GET("/failing", func(c *gin.Context) {
+		serveCached(c, "test:failing", time.Minute, func(ctx context.Context) (interface{}, error) {
+			builds++
+			return nil, errors.NewUpstreamFailure("debank", http.StatusOK, "down")
+		})
+	})
+
+	request := func(method, path, ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		return w
+	}
+
+	first := request(http.MethodGet, "/portfolio", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != `{"total":"42"}` || etag == "" {
+		t.Fatalf("first response = %d %s, etag %q", first.Code, first.Body, etag)
+	}
+
+	tests := []struct {
+		name        string
+		method      string
+		ifNoneMatch string
+		want        int
+	}{
+		{"cached", http.MethodGet, "", http.StatusOK},
+		{"matching etag", http.MethodGet, etag, http.StatusNotModified},
+		{"weak etag in a list", http.MethodGet, `"other", W/` + etag, http.StatusNotModified},
+		{"any etag", http.MethodGet, "*", http.StatusNotModified},
+		{"stale etag", http.MethodGet, `"other"`, http.StatusOK},
+		{"only GETs are not modified", http.MethodPost, etag, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.method, "/portfolio", tt.ifNoneMatch)
+			if w.Code != tt.want {
+				t.Fatalf("status = %d, want %d", w.Code, tt.want)
+			}
+			if w.Header().Get("ETag") != etag {
+				t.Errorf("etag = %q, want %q", w.Header().Get("ETag"), etag)
+			}
+			if tt.want == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Errorf("304 with a body: %s", w.Body)
+			}
+		})
+	}
+	if builds != 1 {
+		t.Errorf("built %d times, want once", builds)
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := request(http.MethodGet, "/failing", ""); w.Code != http.StatusBadGateway || w.Header().Get("ETag") != "" {
+			t.Errorf("failing build = %d, etag %q, want a 502 without etag", w.Code, w.Header().Get("ETag"))
+		}
+	}
+	if builds != 3 {
+		t.Errorf("built %d times, want failures rebuilt", builds)
+	}
+}
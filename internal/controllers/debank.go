@@ -1,8 +1,7 @@
 package controllers
 
 import (
-	"net/http"
-	"strings"
+	"context"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -10,9 +9,12 @@ import (
 
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/internal/responses"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
 	"github.com/0xbase-Corp/portfolio_svc/providers"
-	"github.com/0xbase-Corp/portfolio_svc/providers/debank"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
 	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
@@ -25,56 +27,54 @@ import (
 // @Tags         debank
 // @Accept       json
 // @Produce      json
-// @Param        addresses  query      array  true  "Debank Address" Format(string)
+// @Param        addresses  query      array  true  "EVM Addresses or ENS names" Format(string)
 // @Success      200 {object} []responses.PortfolioResponse
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      429 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Failure      502 {object} errors.Problem
+// @Failure      503 {object} errors.Problem
 // @Router       /portfolio/debank [get]
 func DebankController(c *gin.Context, db *gorm.DB, apiClient providers.APIClient) {
-	addresses := c.Query("addresses")
-	debankAddresses := strings.Split(addresses, ",")
-
-	debankAddresses = utils.UniqueAddress(debankAddresses)
-
-	if len(debankAddresses) == 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError("empty btc addresses"))
+	debankAddresses, requested, ok := queryAddresses(c, utils.Debank)
+	if !ok {
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-	mutex := &sync.Mutex{}
-	ch := make(chan *models.GlobalWallet, len(debankAddresses)) // len(debankAddresses) specifies the buffer size of the channel
-	errorCh := make(chan error, len(debankAddresses))
+	serveCached(c, portfolioKey(utils.Debank, requested, debankAddresses), services.CacheTTL(utils.Debank), func(ctx context.Context) (interface{}, error) {
+		wg := &sync.WaitGroup{}
+		mutex := &sync.Mutex{}
+		ch := make(chan *models.GlobalWallet, len(debankAddresses)) // len(debankAddresses) specifies the buffer size of the channel
+		errorCh := make(chan error, len(debankAddresses))
 
-	for _, btcAddress := range debankAddresses {
-		wg.Add(1)
+		for _, btcAddress := range debankAddresses {
+			wg.Add(1)
 
-		go fetchAndSaveDebank(db, apiClient, btcAddress, ch, wg, mutex, errorCh)
-	}
+			// tracked so that a shutdown lets the save finish
+			address := btcAddress
+			lifecycle.Go(func() { fetchAndSaveDebank(ctx, db.WithContext(ctx), apiClient, address, ch, wg, mutex, errorCh) })
+		}
 
-	// Use a goroutine to close the channel after all goroutines have finished
-	go func() {
-		wg.Wait()
-		close(ch)
-		close(errorCh)
-	}()
+		// Use a goroutine to close the channel after all goroutines have finished
+		go func() {
+			wg.Wait()
+			close(ch)
+			close(errorCh)
+		}()
 
-	errs := errors.HandleChannelErrors(errorCh, c)
-	if len(errs) > 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError(strings.Join(errs, "; ")))
-		return
-	}
-
-	// Collect all results from the channel and process to genernic response for debank
-	responses := processDebankResponses(ch)
+		if apiErr := errors.HandleChannelErrors(errorCh); apiErr != nil {
+			return nil, apiErr
+		}
 
-	c.JSON(http.StatusOK, responses)
+		// Collect all results from the channel and process to genernic response for debank
+		return processDebankResponses(nameWallets(ctx, db, utils.Debank, ch, requested)), nil
+	})
 }
 
 // Fetch and save the data for one address
 // TODO: write a common interface in provider which saves the data in database
-func fetchAndSaveDebank(db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+func fetchAndSaveDebank(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
 	defer wg.Done()
 
 	wallet, _ := models.GetWallet(db, address)
@@ -85,23 +85,27 @@ func fetchAndSaveDebank(db *gorm.DB, apiClient providers.APIClient, address stri
 		// Calculate the duration between the timestamps
 		duration := now.Sub(wallet.LastUpdatedAt)
 
-		if duration.Hours() > 24 {
+		// keep serving stored data while debank is unavailable or its daily budget is nearly spent, flagged as
+		// stale when it is older than the max age
+		expired := duration > configs.EnvConfigVars.DebankWalletMaxAge
+		if expired && !services.UseStoredData(utils.ProviderDebank) {
 			updateWalletAndSend(db, wallet, address, ch, mutex, errorCh)
 		} else {
-			retrieveWalletAndSend(db, address, ch, mutex, errorCh)
+			retrieveWalletAndSend(db, address, expired, ch, mutex, errorCh)
 		}
 	} else {
-		fetchFromAPIAndSave(db, apiClient, address, ch, mutex, errorCh)
+		fetchFromAPIAndSave(ctx, db, apiClient, address, ch, mutex, errorCh)
 	}
 }
 
-// fetch data from database and send in response
-func retrieveWalletAndSend(db *gorm.DB, address string, ch chan<- *models.GlobalWallet, mutex *sync.Mutex, errorCh chan<- error) {
+// fetch data from database and send in response, stale when it is served instead of expired data
+func retrieveWalletAndSend(db *gorm.DB, address string, stale bool, ch chan<- *models.GlobalWallet, mutex *sync.Mutex, errorCh chan<- error) {
 	walletResponse, err := models.GetGlobalWalletWithEvmDebankInfo(db, address)
 	if err != nil {
 		errorCh <- err
 		return
 	}
+	walletResponse.Stale = stale
 
 	// Use a mutex to safely append to the channel.
 	mutex.Lock()
@@ -110,21 +114,8 @@ func retrieveWalletAndSend(db *gorm.DB, address string, ch chan<- *models.Global
 }
 
 // fetch data from api and save data to database
-func fetchFromAPIAndSave(db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, mutex *sync.Mutex, errorCh chan<- error) {
-	body, err := apiClient.FetchData(address)
-	if err != nil {
-		errorCh <- err
-		return
-	}
-
-	// ignore the Address which returns error or empty response
-	resp := debank.EvmDebankTotalBalanceApiResponse{}
-	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
-		errorCh <- err
-		return
-	}
-
-	walletResponse, err := saveDebank(db, address, resp)
+func fetchFromAPIAndSave(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, mutex *sync.Mutex, errorCh chan<- error) {
+	walletResponse, err := services.RefreshDebank(ctx, db, apiClient, address)
 	if err != nil {
 		errorCh <- err
 		return
@@ -155,64 +146,6 @@ func updateWalletAndSend(db *gorm.DB, wallet *models.GlobalWallet, address strin
 	mutex.Unlock()
 }
 
-// helper to save data
-// TODO: write a common interface in provider which saves the data in database
-func saveDebank(db *gorm.DB, address string, apiResponse debank.EvmDebankTotalBalanceApiResponse) (*models.GlobalWallet, error) {
-	// Begin a new transaction
-	tx := db.Begin()
-
-	wallet, err := models.GetOrCreateWallet(tx, address, utils.Debank)
-	if err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Initialize EvmAssetsDebankV1 and set the WalletID
-	evmAssetsDebankV1 := models.EvmAssetsDebankV1{
-		WalletID:      wallet.WalletID,
-		TotalUsdValue: apiResponse.TotalUsdValue,
-	}
-
-	// Save EvmAssetsDebankV1
-	if err = models.CreateOrUpdateEvmAssetsDebankV1(tx, &evmAssetsDebankV1); err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Save Chain
-	if err = models.SaveChainDetails(tx, wallet.WalletID, apiResponse.ChainList); err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Save token list
-	err = models.SaveTokenListByEvmAssetsDebankV1ID(tx, evmAssetsDebankV1.EvmAssetID, apiResponse.TokensList)
-	if err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Save nft list
-	err = models.SaveNFTSListByEvmAssetsDebankV1ID(tx, evmAssetsDebankV1.EvmAssetID, apiResponse.NFTList)
-	if err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		return &models.GlobalWallet{}, err
-	}
-
-	// get the data
-	walletResponse, err := models.GetGlobalWalletWithEvmDebankInfo(db, address)
-	if err != nil {
-		return &models.GlobalWallet{}, err
-	}
-
-	return walletResponse, nil
-}
-
 // processDebankResponses processes wallet responses and returns a slice of debank responses.
 func processDebankResponses(ch <-chan *models.GlobalWallet) []*responses.PortfolioResponse {
 	debankResponses := make([]*responses.ChainsResponse, 0)
@@ -220,6 +153,7 @@ func processDebankResponses(ch <-chan *models.GlobalWallet) []*responses.Portfol
 		if walletResponse == nil || walletResponse.EvmAssetsDebankV1 == nil {
 			continue
 		}
+		metrics.ObserveWalletStaleness(utils.Debank, walletResponse.LastUpdatedAt)
 
 		for _, token := range *walletResponse.EvmAssetsDebankV1.TokenList {
 			debankResponse := &responses.ChainsResponse{}
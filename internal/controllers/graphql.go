@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"encoding/json"
+	er "errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/graph"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// GraphQLRequest is a GraphQL query with its variables, the body of a POST to /graphql.
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// GraphQLController godoc
+//
+// GraphQLController executes a GraphQL query over the stored users, wallets, portfolios and prices.
+// @Summary      Execute a GraphQL query
+// @Description  Takes a query, its variables and operationName as JSON, or as the query, variables and operationName
+// @Description  query parameters of a GET. Only queries are supported. The schema is available by introspection.
+// @Description  Requests that cannot be executed are answered with a 400 and their errors; once executed the answer is
+// @Description  a 200 with the data and the errors of the fields that failed, with their kind as extensions.code.
+// @Tags         graphql
+// @Accept       json
+// @Produce      json
+// @Param        request body GraphQLRequest true "GraphQL request"
+// @Success      200 {object} graphql.Response
+// @Failure      400 {object} graphql.Response
+// @Failure      403 {object} errors.Problem
+// @Failure      413 {object} errors.Problem
+// @Failure      429 {object} errors.Problem
+// @Router       /api/v1/graphql [post]
+func GraphQLController(c *gin.Context, db *gorm.DB, schema *graphql.Schema) {
+	request := GraphQLRequest{}
+
+	if c.Request.Method == http.MethodGet {
+		request.Query = c.Query("query")
+		request.OperationName = c.Query("operationName")
+
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				errors.HandleHttpError(c, errors.NewBadRequestError("variables must be a JSON object"))
+				return
+			}
+		}
+	} else {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, utils.MaxBodySize))
+		var tooLarge *http.MaxBytesError
+		if er.As(err, &tooLarge) {
+			errors.HandleHttpError(c, errors.NewHttpError(http.StatusRequestEntityTooLarge, "the request is larger than 1 MiB"))
+			return
+		}
+		if err != nil || json.Unmarshal(body, &request) != nil {
+			errors.HandleHttpError(c, errors.NewBadRequestError("the body must be a JSON GraphQL request"))
+			return
+		}
+	}
+
+	if request.Query == "" {
+		errors.HandleHttpError(c, errors.NewBadRequestError("query is required"))
+		return
+	}
+
+	// me is only answered for users, as the endpoints of users are
+	userID := 0
+	if _, apiKey := c.Get(utils.ContextAPIKey); !apiKey {
+		userID = c.GetInt(utils.ContextUserID)
+	}
+
+	response := schema.Exec(graph.WithRequest(c.Request.Context(), db, userID), request.Query, request.OperationName, request.Variables)
+
+	// queries that could not be executed have no data, not even null
+	status := http.StatusOK
+	if response.Data == nil {
+		status = http.StatusBadRequest
+	}
+
+	c.JSON(status, response)
+}
@@ -1,12 +1,234 @@
 package controllers
 
 import (
-	"github.com/gin-gonic/gin"
+	"context"
 	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/breaker"
+	"github.com/0xbase-Corp/portfolio_svc/shared/callstats"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/migrations"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+const (
+	statusOK          = "ok"
+	statusDegraded    = "degraded"
+	statusUnavailable = "unavailable"
+)
+
+// pingTimeout bounds the database check of a probe.
+const pingTimeout = 2 * time.Second
+
+type (
+	// Check is the outcome of one dependency check.
+	Check struct {
+		Status    string `json:"status"`
+		LatencyMs int64  `json:"latency_ms"`
+		Error     string `json:"error,omitempty"`
+		Version   *int   `json:"version,omitempty"`  // migrations only: latest applied
+		Expected  *int   `json:"expected,omitempty"` // migrations only: latest embedded
+	}
+
+	// ReadinessResponse tells whether the instance can serve traffic and why not.
+	ReadinessResponse struct {
+		Status string           `json:"status"`
+		Checks map[string]Check `json:"checks"`
+	}
+
+	// ProviderHealth combines the recent calls, circuit breaker and quota of an upstream provider.
+	ProviderHealth struct {
+		Status string `json:"status"`
+		callstats.Stats
+		Breaker *breaker.Status  `json:"circuit_breaker,omitempty"`
+		Quota   *ratelimit.Usage `json:"quota,omitempty"`
+	}
+
+	// HealthDetailsResponse reports every dependency of the service.
+	HealthDetailsResponse struct {
+		Status    string            `json:"status"`
+		Checks    map[string]Check  `json:"checks"`
+		Providers []ProviderHealth  `json:"providers"`
+		Workers   lifecycle.Workers `json:"workers"`
+	}
 )
 
-func HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Server is live and accepting connections",
-	})
+// healthProviders are reported by the details endpoint, called or not.
+var healthProviders = []string{
+	utils.ProviderBtcCom,
+	utils.ProviderMoralis,
+	utils.ProviderDebank,
+	utils.ProviderCoingecko,
+	utils.ProviderENS,
+	utils.ProviderSNS,
+}
+
+// LivenessController godoc
+//
+// @Summary      Liveness probe
+// @Description  Returns 200 while the process is running. It checks no dependency, so a failing database does not get the instance restarted.
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} map[string]string
+// @Router       /livez [get]
+func LivenessController(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": statusOK})
+}
+
+// ReadinessController godoc
+//
+// @Summary      Readiness probe
+// @Description  Returns 200 when the database answers and its schema is at the version of the embedded migrations, 503 otherwise or while shutting down.
+// @Tags         health
+// @Produce      json
+// @Success      200 {object} ReadinessResponse
+// @Failure      503 {object} ReadinessResponse
+// @Router       /readyz [get]
+func ReadinessController(c *gin.Context, db *gorm.DB) {
+	resp := ReadinessResponse{Status: statusOK, Checks: dependencyChecks(c.Request.Context(), db)}
+
+	for _, check := range resp.Checks {
+		if check.Status != statusOK {
+			resp.Status = statusUnavailable
+		}
+	}
+
+	if resp.Status != statusOK {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// HealthDetailsController godoc
+//
+// @Summary      Detailed health
+// @Description  Reports the database and migration checks, the latency, last error, circuit breaker and quota of every upstream provider, and the background work still running.
+// @Description  Served only when HEALTH_TOKEN is set, to requests carrying it as their bearer token. Answers 503 when the database or its schema is unavailable; providers that are down only degrade the status.
+// @Tags         health
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} HealthDetailsResponse
+// @Failure      401 {object} errors.Problem
+// @Failure      503 {object} HealthDetailsResponse
+// @Router       /health/details [get]
+func HealthDetailsController(c *gin.Context, db *gorm.DB) {
+	resp := HealthDetailsResponse{
+		Status:    statusOK,
+		Checks:    dependencyChecks(c.Request.Context(), db),
+		Providers: providerHealth(),
+		Workers:   lifecycle.Running(),
+	}
+
+	for _, provider := range resp.Providers {
+		if provider.Status != statusOK {
+			resp.Status = statusDegraded
+		}
+	}
+
+	for _, check := range resp.Checks {
+		if check.Status != statusOK {
+			resp.Status = statusUnavailable
+		}
+	}
+
+	if resp.Status == statusUnavailable {
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// dependencyChecks pings the database and compares the schema with the embedded migrations.
+func dependencyChecks(ctx context.Context, db *gorm.DB) map[string]Check {
+	checks := make(map[string]Check)
+
+	if lifecycle.ShuttingDown() {
+		checks["shutdown"] = Check{Status: statusUnavailable, Error: "shutting down"}
+	}
+
+	start := time.Now()
+	database := Check{Status: statusOK}
+	if err := ping(ctx, db); err != nil {
+		database.Status = statusUnavailable
+		database.Error = err.Error()
+	}
+	database.LatencyMs = time.Since(start).Milliseconds()
+	checks["database"] = database
+
+	if database.Status != statusOK {
+		checks["migrations"] = Check{Status: statusUnavailable, Error: "database unavailable"}
+		return checks
+	}
+
+	start = time.Now()
+	current, expected, err := migrations.CheckVersion(db.WithContext(ctx))
+	schema := Check{Status: statusOK, LatencyMs: time.Since(start).Milliseconds(), Version: &current, Expected: &expected}
+	if err != nil {
+		schema.Status = statusUnavailable
+		schema.Error = err.Error()
+	}
+	checks["migrations"] = schema
+
+	return checks
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+
+	return sqlDB.PingContext(ctx)
+}
+
+func providerHealth() []ProviderHealth {
+	breakers := make(map[string]breaker.Status)
+	for _, status := range breaker.Snapshot() {
+		breakers[status.Provider] = status
+	}
+
+	quotas := make(map[string]ratelimit.Usage)
+	for _, usage := range ratelimit.Snapshot() {
+		quotas[usage.Provider] = usage
+	}
+
+	providers := make([]ProviderHealth, 0, len(healthProviders))
+	for _, name := range healthProviders {
+		provider := ProviderHealth{Status: statusOK, Stats: callstats.Get(name)}
+
+		if status, ok := breakers[name]; ok {
+			provider.Breaker = &status
+			if status.State != breaker.StateClosed {
+				provider.Status = statusUnavailable
+			}
+		}
+
+		if usage, ok := quotas[name]; ok {
+			provider.Quota = &usage
+			if usage.DailyQuota > 0 && usage.Remaining == 0 {
+				provider.Status = statusUnavailable
+			}
+		}
+
+		// the last call failed but the breaker is still closed
+		if provider.Status == statusOK && provider.LastErrorAt != nil &&
+			(provider.LastSuccessAt == nil || provider.LastErrorAt.After(*provider.LastSuccessAt)) {
+			provider.Status = statusDegraded
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers
 }
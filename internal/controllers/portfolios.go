@@ -1,27 +1,49 @@
 package controllers
 
 import (
-	"net/http"
+	"context"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/internal/responses"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
 	"github.com/0xbase-Corp/portfolio_svc/providers/bitcoin"
 	"github.com/0xbase-Corp/portfolio_svc/providers/debank"
 	"github.com/0xbase-Corp/portfolio_svc/providers/solana"
 	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
 type (
 	PortfolioAddresses struct {
-		BTC []string `json:"btc"`
-		Sol []string `json:"sol"`
-		EVM []string `json:"evm"`
+		BTC       []string `json:"btc"`
+		Sol       []string `json:"sol"`
+		EVM       []string `json:"evm"`
+		Addresses []string `json:"addresses"` // any chain, detected from the address format
+	}
+
+	// DetectedPortfolioResponse is returned instead of the plain list when addresses were detected from a flat list.
+	DetectedPortfolioResponse struct {
+		Portfolio []*responses.PortfolioResponse `json:"portfolio"`
+		Addresses []DetectedAddress              `json:"addresses"`
+		Rejected  []errors.ErrorDetail           `json:"rejected,omitempty"` // entries whose chain could not be detected
+	}
+
+	// portfolioRequest holds the validated addresses of a portfolio request by chain.
+	portfolioRequest struct {
+		btc, sol, evm []string
+		requested     map[string]string // names requested for addresses
+		flat          bool              // whether addresses were sent as one list to detect chains from
+		inputs        []string          // the flat list as sent
+		detected      []DetectedAddress
+		rejected      []errors.ErrorDetail
 	}
 
 	ChannelMap struct {
@@ -38,80 +60,197 @@ type (
 // AllPortfolioController defines the route and Swagger annotations for fetching all portfolio information.
 // @Summary      Fetch all portfolio information
 // @Description  Retrieves information for all portfolios including Bitcoin, Solana, and EVM addresses.
+// @Description  Addresses can be sorted into the btc, sol and evm lists or sent as one addresses list, in which case
+// @Description  the chain is detected per address and the response is a DetectedPortfolioResponse.
+// @Description  ENS (.eth) and SNS (.sol) names are accepted wherever EVM or Solana addresses are.
 // @Tags         portfolio
 // @Accept       json
 // @Produce      json
 // @Param        addresses body PortfolioAddresses true "Portfolio Addresses"
 // @Success      200 {object} []responses.PortfolioResponse
-// @Failure      400 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Success      200 {object} DetectedPortfolioResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      429 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Failure      502 {object} errors.Problem
+// @Failure      503 {object} errors.Problem
 // @Router       /api/v1/all-portfolio [post]
 func AllPortfolioController(c *gin.Context, db *gorm.DB) {
+	request, ok := bindPortfolioRequest(c)
+	if !ok {
+		return
+	}
+
+	btcAddresses, solanaAddresses, debankAddresses, requested := request.btc, request.sol, request.evm, request.requested
+
+	// detected portfolios echo the flat list as given, so it is part of the key
+	key := portfolioKey("all", requested, btcAddresses, solanaAddresses, debankAddresses)
+	if request.flat {
+		key = portfolioKey("detected", requested, btcAddresses, solanaAddresses, debankAddresses) + ":" + strings.Join(request.inputs, ",")
+	}
+
+	serveCached(c, key, portfolioTTL(btcAddresses, solanaAddresses, debankAddresses), func(ctx context.Context) (interface{}, error) {
+		wg := &sync.WaitGroup{}
+		mutex := &sync.Mutex{}
+		errorCh := make(chan error)
+		bitcoinAPIClient := &bitcoin.BitcoinAPI{}
+		solanaAPIClient := &solana.SolanaAPI{}
+		debankAPIClient := &debank.DebankAPI{}
+
+		channelMap := createChannelMap(ctx, btcAddresses, solanaAddresses, debankAddresses, wg, mutex, errorCh, db, bitcoinAPIClient, solanaAPIClient, debankAPIClient)
+
+		go func() {
+			wg.Wait()
+			closeChannels(channelMap)
+			close(errorCh)
+		}()
+
+		if apiErr := errors.HandleChannelErrors(errorCh); apiErr != nil {
+			return nil, apiErr
+		}
+
+		allResponses := processResponses(ctx, db, channelMap, requested)
+
+		if request.flat {
+			return DetectedPortfolioResponse{Portfolio: allResponses, Addresses: request.detected, Rejected: request.rejected}, nil
+		}
+
+		return allResponses, nil
+	})
+}
+
+// bindPortfolioRequest binds and validates the addresses of a portfolio request, resolving names and detecting
+// the chains of the flat list. It answers the problem and returns false when the request is invalid.
+func bindPortfolioRequest(c *gin.Context) (*portfolioRequest, bool) {
 	requestBody := PortfolioAddresses{}
 
 	if err := c.BindJSON(&requestBody); err != nil {
-		errors.HandleHttpError(c, errors.NewBadRequestError("error"+err.Error()))
-		return
+		errors.HandleHttpError(c, errors.NewBadRequestError("invalid request body: "+err.Error()))
+		return nil, false
 	}
 
-	btcAddresses := utils.UniqueAddress(requestBody.BTC)
-	solanaAddresses := utils.UniqueAddress(requestBody.Sol)
-	debankAddresses := utils.UniqueAddress(requestBody.EVM)
+	// names in the chain lists are replaced by their addresses before anything is appended to them
+	requested := make(map[string]string)
+	nameDetails := make([]errors.ErrorDetail, 0)
+	for _, list := range []struct {
+		field, chain string
+		inputs       *[]string
+	}{
+		{"sol", utils.Solana, &requestBody.Sol},
+		{"evm", utils.Debank, &requestBody.EVM},
+	} {
+		resolved, listNames, details, err := resolveNames(c.Request.Context(), list.field, list.chain, *list.inputs)
+		if err != nil {
+			errors.HandleHttpError(c, err)
+			return nil, false
+		}
 
-	if len(btcAddresses) == 0 && len(solanaAddresses) == 0 && len(debankAddresses) == 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError("empty addresses"))
-		return
+		*list.inputs = resolved
+		nameDetails = append(nameDetails, details...)
+		for address, name := range listNames {
+			requested[address] = name
+		}
 	}
 
-	wg := &sync.WaitGroup{}
-	mutex := &sync.Mutex{}
-	errorCh := make(chan error)
-	bitcoinAPIClient := &bitcoin.BitcoinAPI{}
-	solanaAPIClient := &solana.SolanaAPI{}
-	debankAPIClient := &debank.DebankAPI{}
+	detected, rejected, err := detectAddresses(c.Request.Context(), requestBody.Addresses)
+	if err != nil {
+		errors.HandleHttpError(c, err)
+		return nil, false
+	}
 
-	channelMap := createChannelMap(btcAddresses, solanaAddresses, debankAddresses, wg, mutex, errorCh, db, bitcoinAPIClient, solanaAPIClient, debankAPIClient)
+	for _, address := range detected {
+		if address.Name != "" {
+			requested[address.Address] = address.Name
+		}
 
-	go func() {
-		wg.Wait()
-		closeChannels(channelMap)
-		close(errorCh)
-	}()
+		switch address.Chain {
+		case chainFamilies[utils.Bitcoin]:
+			requestBody.BTC = append(requestBody.BTC, address.Address)
+		case chainFamilies[utils.Solana]:
+			requestBody.Sol = append(requestBody.Sol, address.Address)
+		case chainFamilies[utils.Debank]:
+			requestBody.EVM = append(requestBody.EVM, address.Address)
+		}
+	}
 
-	errs := errors.HandleChannelErrors(errorCh, c)
-	if len(errs) > 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError(strings.Join(errs, "; ")))
-		return
+	btcAddresses, btcDetails := validateAddresses("btc", utils.Bitcoin, requestBody.BTC)
+	solanaAddresses, solanaDetails := validateAddresses("sol", utils.Solana, requestBody.Sol)
+	debankAddresses, debankDetails := validateAddresses("evm", utils.Debank, requestBody.EVM)
+
+	if details := append(append(append(nameDetails, btcDetails...), solanaDetails...), debankDetails...); len(details) > 0 {
+		errors.HandleHttpError(c, errors.NewValidationError("invalid addresses", details))
+		return nil, false
 	}
 
-	allResponses := processResponses(channelMap)
+	if len(btcAddresses) == 0 && len(solanaAddresses) == 0 && len(debankAddresses) == 0 {
+		if len(rejected) > 0 {
+			errors.HandleHttpError(c, errors.NewValidationError("no address matched a supported chain", rejected))
+			return nil, false
+		}
 
-	c.JSON(http.StatusOK, allResponses)
+		errors.HandleHttpError(c, errors.NewBadRequestError("empty addresses"))
+		return nil, false
+	}
+
+	return &portfolioRequest{
+		btc:       btcAddresses,
+		sol:       solanaAddresses,
+		evm:       debankAddresses,
+		requested: requested,
+		flat:      len(requestBody.Addresses) > 0,
+		inputs:    requestBody.Addresses,
+		detected:  detected,
+		rejected:  rejected,
+	}, true
+}
+
+// portfolioTTL is the shortest cache TTL of the chains with addresses.
+func portfolioTTL(btcAddresses, solanaAddresses, debankAddresses []string) time.Duration {
+	ttl := time.Duration(-1)
+	for chain, addresses := range map[string][]string{
+		utils.Bitcoin: btcAddresses,
+		utils.Solana:  solanaAddresses,
+		utils.Debank:  debankAddresses,
+	} {
+		if len(addresses) > 0 && (ttl < 0 || services.CacheTTL(chain) < ttl) {
+			ttl = services.CacheTTL(chain)
+		}
+	}
+
+	return ttl
 }
 
-func createChannelMap(btcAddresses, solanaAddresses, debankAddresses []string, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan error, db *gorm.DB, bitcoinAPIClient *bitcoin.BitcoinAPI, solanaAPIClient *solana.SolanaAPI, debankAPIClient *debank.DebankAPI) ChannelMap {
+func createChannelMap(ctx context.Context, btcAddresses, solanaAddresses, debankAddresses []string, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan error, db *gorm.DB, bitcoinAPIClient *bitcoin.BitcoinAPI, solanaAPIClient *solana.SolanaAPI, debankAPIClient *debank.DebankAPI) ChannelMap {
 	channelMap := ChannelMap{
 		btcChs:    make(map[string]chan *models.GlobalWallet),
 		solanaChs: make(map[string]chan *models.GlobalWallet),
 		debankChs: make(map[string]chan *models.GlobalWallet),
 	}
 
+	metrics.ObserveFanout(utils.Bitcoin, len(btcAddresses))
+	metrics.ObserveFanout(utils.Solana, len(solanaAddresses))
+	metrics.ObserveFanout(utils.Debank, len(debankAddresses))
+
+	// the fetches are tracked so that a shutdown lets their saves finish
 	for _, btcAddress := range btcAddresses {
-		channelMap.btcChs[btcAddress] = make(chan *models.GlobalWallet, 1)
+		address, ch := btcAddress, make(chan *models.GlobalWallet, 1)
+		channelMap.btcChs[address] = ch
 		wg.Add(1)
-		go fetchAndSaveBtc(db, bitcoinAPIClient, btcAddress, channelMap.btcChs[btcAddress], wg, mutex, errorCh)
+		lifecycle.Go(func() { fetchAndSaveBtc(ctx, db.WithContext(ctx), bitcoinAPIClient, address, ch, wg, mutex, errorCh) })
 	}
 
 	for _, solAddress := range solanaAddresses {
-		channelMap.solanaChs[solAddress] = make(chan *models.GlobalWallet, 1)
+		address, ch := solAddress, make(chan *models.GlobalWallet, 1)
+		channelMap.solanaChs[address] = ch
 		wg.Add(1)
-		go fetchAndSaveSolana(db, solanaAPIClient, solAddress, channelMap.solanaChs[solAddress], wg, mutex, errorCh)
+		lifecycle.Go(func() { fetchAndSaveSolana(ctx, db.WithContext(ctx), solanaAPIClient, address, ch, wg, mutex, errorCh) })
 	}
 
 	for _, evmAddress := range debankAddresses {
-		channelMap.debankChs[evmAddress] = make(chan *models.GlobalWallet, 1)
+		address, ch := evmAddress, make(chan *models.GlobalWallet, 1)
+		channelMap.debankChs[address] = ch
 		wg.Add(1)
-		go fetchAndSaveDebank(db, debankAPIClient, evmAddress, channelMap.debankChs[evmAddress], wg, mutex, errorCh)
+		lifecycle.Go(func() { fetchAndSaveDebank(ctx, db.WithContext(ctx), debankAPIClient, address, ch, wg, mutex, errorCh) })
 	}
 
 	return channelMap
@@ -129,7 +268,7 @@ func closeChannels(channelMap ChannelMap) {
 	}
 }
 
-func processResponses(channelMap ChannelMap) []*responses.PortfolioResponse {
+func processResponses(ctx context.Context, db *gorm.DB, channelMap ChannelMap, requested map[string]string) []*responses.PortfolioResponse {
 	var allResponses []*responses.PortfolioResponse
 
 	for _, ch := range channelMap.btcChs {
@@ -138,12 +277,12 @@ func processResponses(channelMap ChannelMap) []*responses.PortfolioResponse {
 	}
 
 	for _, ch := range channelMap.solanaChs {
-		responses := processSolanaResponses(ch)
+		responses := processSolanaResponses(nameWallets(ctx, db, utils.Solana, ch, requested))
 		allResponses = append(allResponses, responses...)
 	}
 
 	for _, ch := range channelMap.debankChs {
-		responses := processDebankResponses(ch)
+		responses := processDebankResponses(nameWallets(ctx, db, utils.Debank, ch, requested))
 		allResponses = append(allResponses, responses...)
 	}
 
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+)
+
+//	@BasePath	/api/v1
+
+// ProviderQuotaController godoc
+//
+// @Summary      Report upstream provider quotas
+// @Description  Returns today's call count, daily quota and remaining budget of every external data provider.
+// @Description  Only answered to authenticated callers, users or api keys.
+// @Tags         providers
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} []ratelimit.Usage
+// @Failure      401 {object} errors.Problem
+// @Router       /providers/quota [get]
+func ProviderQuotaController(c *gin.Context) {
+	c.JSON(http.StatusOK, ratelimit.Snapshot())
+}
+
+// sendStoredWallet sends the wallet already stored in the database, flagged as stale, instead of calling
+// the provider. It returns false when nothing is stored yet, in which case the caller has to fetch it.
+func sendStoredWallet(load func() (*models.GlobalWallet, error), ch chan<- *models.GlobalWallet, mutex *sync.Mutex) bool {
+	walletResponse, err := load()
+	if err != nil {
+		return false
+	}
+	walletResponse.Stale = true
+
+	// Use a mutex to safely append to the channel.
+	mutex.Lock()
+	ch <- walletResponse
+	mutex.Unlock()
+
+	return true
+}
@@ -1,9 +1,10 @@
 package controllers
 
 import (
+	"context"
+	er "errors"
 	"net/http"
 	"strconv"
-	"strings"
 	"sync"
 
 	"github.com/gin-gonic/gin"
@@ -11,9 +12,12 @@ import (
 
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/internal/responses"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
 	"github.com/0xbase-Corp/portfolio_svc/providers"
-	"github.com/0xbase-Corp/portfolio_svc/providers/solana"
+	"github.com/0xbase-Corp/portfolio_svc/shared/breaker"
 	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
@@ -25,51 +29,49 @@ import (
 // @Tags         solana
 // @Accept       json
 // @Produce      json
-// @Param        addresses  query      array  true  "Solana Addresses" Format(string)
+// @Param        addresses  query      array  true  "Solana Addresses or .sol names" Format(string)
 // @Success      200 {object} []responses.PortfolioResponse
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      429 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Failure      502 {object} errors.Problem
+// @Failure      503 {object} errors.Problem
 // @Router       /portfolio/solana [get]
 func SolanaController(c *gin.Context, db *gorm.DB, apiClient providers.APIClient) {
-	addresses := c.Query("addresses")
-	solanaAddresses := strings.Split(addresses, ",")
-
-	solanaAddresses = utils.UniqueAddress(solanaAddresses)
-
-	if len(solanaAddresses) == 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError("empty btc addresses"))
+	solanaAddresses, requested, ok := queryAddresses(c, utils.Solana)
+	if !ok {
 		return
 	}
 
-	wg := &sync.WaitGroup{}
-	mutex := &sync.Mutex{}
-	ch := make(chan *models.GlobalWallet, len(solanaAddresses)) // len(solanaAddresses) specifies the buffer size of the channel
-	errorCh := make(chan error, len(solanaAddresses))
+	serveCached(c, portfolioKey(utils.Solana, requested, solanaAddresses), services.CacheTTL(utils.Solana), func(ctx context.Context) (interface{}, error) {
+		wg := &sync.WaitGroup{}
+		mutex := &sync.Mutex{}
+		ch := make(chan *models.GlobalWallet, len(solanaAddresses)) // len(solanaAddresses) specifies the buffer size of the channel
+		errorCh := make(chan error, len(solanaAddresses))
 
-	for _, solAddress := range solanaAddresses {
-		wg.Add(1)
-
-		go fetchAndSaveSolana(db, apiClient, solAddress, ch, wg, mutex, errorCh)
-	}
+		for _, solAddress := range solanaAddresses {
+			wg.Add(1)
 
-	// Use a goroutine to close the channel after all goroutines have finished
-	go func() {
-		wg.Wait()
-		close(errorCh)
-		close(ch)
-	}()
+			// tracked so that a shutdown lets the save finish
+			address := solAddress
+			lifecycle.Go(func() { fetchAndSaveSolana(ctx, db.WithContext(ctx), apiClient, address, ch, wg, mutex, errorCh) })
+		}
 
-	errs := errors.HandleChannelErrors(errorCh, c)
-	if len(errs) > 0 {
-		errors.HandleHttpError(c, errors.NewBadRequestError(strings.Join(errs, "; ")))
-		return
-	}
+		// Use a goroutine to close the channel after all goroutines have finished
+		go func() {
+			wg.Wait()
+			close(errorCh)
+			close(ch)
+		}()
 
-	// Collect all results from the channel and process to genernic response for solana
-	responses := processSolanaResponses(ch)
+		if apiErr := errors.HandleChannelErrors(errorCh); apiErr != nil {
+			return nil, apiErr
+		}
 
-	c.JSON(http.StatusOK, responses)
+		// Collect all results from the channel and process to genernic response for solana
+		return processSolanaResponses(nameWallets(ctx, db, utils.Solana, ch, requested)), nil
+	})
 }
 
 //	@BasePath	/api/v1
@@ -84,9 +86,9 @@ func SolanaController(c *gin.Context, db *gorm.DB, apiClient providers.APIClient
 // @Param        offset query int false "Pagination offset" Format(int)
 // @Param        limit query int false "Pagination limit" Format(int)
 // @Success      200 {object} models.GlobalWallet
-// @Failure      400 {object} errors.APIError
-// @Failure      404 {object} errors.APIError
-// @Failure      500 {object} errors.APIError
+// @Failure      400 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
 // @Router       /portfolio/solana-wallet/{wallet_id} [get]
 func GetSolanaController(c *gin.Context, db *gorm.DB) {
 	wallet := models.GlobalWallet{}
@@ -135,80 +137,33 @@ func GetSolanaController(c *gin.Context, db *gorm.DB) {
 		First(&wallet).Error
 
 	if err != nil {
-		errors.HandleHttpError(c, errors.NewNotFoundError("wallet not found"))
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "wallet not found"))
 		return
 	}
 
 	c.JSON(http.StatusOK, wallet)
 }
 
-// helper to save data
-// TODO: write a common interface in provider which saves the data in database
-func saveSolana(db *gorm.DB, solanaAddress string, apiResponse solana.SolanaApiResponse) (*models.GlobalWallet, error) {
-	// Begin a new transaction
-	tx := db.Begin()
-
-	wallet, err := models.GetOrCreateWallet(tx, solanaAddress, utils.Solana)
-	if err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Assuming wallet is the GlobalWallet record found or created
-	walletID := wallet.WalletID
-
-	// Initialize solanaAsset and set the WalletID
-	solanaAsset := models.SolanaAssetsMoralisV1{}
-	solanaAsset.WalletID = walletID
-
-	// Attempt to save the Solana asset data along with the associated tokens and NFTs.
-	if err := models.SaveSolanaData(tx, &solanaAsset, apiResponse.Tokens, apiResponse.NFTs); err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// save the solana price feed
-	// for now hard code the USD -> TODO: change
-	if err := HandleCoingeckoPrice(tx, utils.Solana, "usd"); err != nil {
-		tx.Rollback()
-		return &models.GlobalWallet{}, err
-	}
-
-	// Commit the transaction
-	if err := tx.Commit().Error; err != nil {
-		return &models.GlobalWallet{}, err
-	}
-
-	// get the data
-	walletResponse, err := models.GetGlobalWalletWithSolanaInfo(db, solanaAddress)
-	if err != nil {
-		return &models.GlobalWallet{}, err
-	}
-
-	return walletResponse, nil
-}
-
 // Fetch and save the data for one address
 // TODO: write a common interface in provider which saves the data in database
-func fetchAndSaveSolana(db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+func fetchAndSaveSolana(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
 	defer wg.Done()
 
-	body, err := apiClient.FetchData(address)
-	if err != nil {
-		errorCh <- err
-		return
+	loadStored := func() (*models.GlobalWallet, error) {
+		return models.GetGlobalWalletWithSolanaInfo(db, address)
 	}
 
-	// ignore the Address which returns error or empty response
-	resp := solana.SolanaApiResponse{}
-	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
-		errorCh <- err
+	// serve the stored wallet while moralis is unavailable or its daily budget is nearly spent
+	if services.UseStoredData(utils.ProviderMoralis) && sendStoredWallet(loadStored, ch, mutex) {
 		return
 	}
 
-	// Save data to the database
-	walletResponse, err := saveSolana(db, address, resp)
+	walletResponse, err := services.RefreshSolana(ctx, db, apiClient, address)
 	if err != nil {
+		if er.Is(err, breaker.ErrOpen) && sendStoredWallet(loadStored, ch, mutex) {
+			return
+		}
+
 		errorCh <- err
 		return
 	}
@@ -226,6 +181,7 @@ func processSolanaResponses(ch <-chan *models.GlobalWallet) []*responses.Portfol
 		if walletResponse == nil || walletResponse.SolanaAssetsMoralisV1 == nil {
 			continue
 		}
+		metrics.ObserveWalletStaleness(utils.Solana, walletResponse.LastUpdatedAt)
 
 		for _, token := range *walletResponse.SolanaAssetsMoralisV1.Tokens {
 			solResponse := &responses.ChainsResponse{}
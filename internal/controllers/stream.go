@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/responses"
+	"github.com/0xbase-Corp/portfolio_svc/providers/bitcoin"
+	"github.com/0xbase-Corp/portfolio_svc/providers/debank"
+	"github.com/0xbase-Corp/portfolio_svc/providers/solana"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// Events of the portfolio stream.
+const (
+	EventAddresses = "addresses" // the detected and rejected entries of a flat list, sent first
+	EventWallet    = "wallet"    // a wallet was loaded
+	EventError     = "error"     // a wallet could not be loaded
+	EventDone      = "done"      // every wallet was handled, carries the whole portfolio
+)
+
+var (
+	// streamHeartbeat is how often an idle portfolio stream sends a comment.
+	streamHeartbeat = 15 * time.Second
+
+	// streamFetches returns the fetch of the wallets of each chain.
+	streamFetches = func() map[string]walletFetch {
+		bitcoinAPIClient := &bitcoin.BitcoinAPI{}
+		solanaAPIClient := &solana.SolanaAPI{}
+		debankAPIClient := &debank.DebankAPI{}
+
+		return map[string]walletFetch{
+			utils.Bitcoin: func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+				fetchAndSaveBtc(ctx, db, bitcoinAPIClient, address, ch, wg, mutex, errorCh)
+			},
+			utils.Solana: func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+				fetchAndSaveSolana(ctx, db, solanaAPIClient, address, ch, wg, mutex, errorCh)
+			},
+			utils.Debank: func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+				fetchAndSaveDebank(ctx, db, debankAPIClient, address, ch, wg, mutex, errorCh)
+			},
+		}
+	}
+)
+
+type (
+	// WalletEvent is the data of a wallet event.
+	WalletEvent struct {
+		Chain     string                         `json:"chain"`
+		Address   string                         `json:"address"`
+		Portfolio []*responses.PortfolioResponse `json:"portfolio"`
+	}
+
+	// WalletErrorEvent is the data of an error event.
+	WalletErrorEvent struct {
+		Chain   string          `json:"chain"`
+		Address string          `json:"address"`
+		Problem *errors.Problem `json:"problem"`
+	}
+
+	// AddressesEvent is the data of an addresses event.
+	AddressesEvent struct {
+		Addresses []DetectedAddress    `json:"addresses"`
+		Rejected  []errors.ErrorDetail `json:"rejected,omitempty"`
+	}
+
+	streamEvent struct {
+		name string
+		data interface{}
+	}
+
+	// walletFetch fetches and saves the wallet of address, sending it to ch or its error to errorCh.
+	walletFetch func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error)
+)
+
+// StreamAllPortfolioController godoc
+//
+// StreamAllPortfolioController streams the portfolio of AllPortfolioController as server-sent events.
+// @Summary      Stream all portfolio information
+// @Description  Takes the body of /all-portfolio and answers a text/event-stream instead of waiting for every wallet.
+// @Description  A wallet event (WalletEvent) is sent as each wallet is loaded and an error event (WalletErrorEvent) for
+// @Description  each one that fails. A flat addresses list is answered first by an addresses event (AddressesEvent).
+// @Description  The stream ends with a done event carrying the []responses.PortfolioResponse of the loaded wallets.
+// @Description  Invalid requests are answered with a problem before the stream starts. While wallets load, a comment is
+// @Description  sent every 15 seconds to keep proxies from closing the stream.
+// @Tags         portfolio
+// @Accept       json
+// @Produce      text/event-stream
+// @Param        addresses body PortfolioAddresses true "Portfolio Addresses"
+// @Success      200 {object} []responses.PortfolioResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      429 {object} errors.Problem
+// @Router       /api/v1/all-portfolio/stream [post]
+func StreamAllPortfolioController(c *gin.Context, db *gorm.DB) {
+	request, ok := bindPortfolioRequest(c)
+	if !ok {
+		return
+	}
+
+	fetches := streamFetches()
+	addresses := map[string][]string{
+		utils.Bitcoin: request.btc,
+		utils.Solana:  request.sol,
+		utils.Debank:  request.evm,
+	}
+
+	// buffered for every wallet, so that the fetches never wait on a client that went away
+	events := make(chan streamEvent, len(request.btc)+len(request.sol)+len(request.evm))
+	wg := &sync.WaitGroup{}
+	mutex := &sync.Mutex{}
+
+	// the saves outlive a client that goes away, but keep its trace and request ID. The gin context is reused
+	// once the handler returns, the fetches must not touch it
+	ctx := context.WithoutCancel(c.Request.Context())
+	path := c.Request.URL.Path
+
+	for chain, chainAddresses := range addresses {
+		metrics.ObserveFanout(chain, len(chainAddresses))
+
+		for _, chainAddress := range chainAddresses {
+			chain, address, fetch := chain, chainAddress, fetches[chain]
+			wg.Add(1)
+
+			// tracked so that a shutdown lets the save finish
+			lifecycle.Go(func() {
+				defer wg.Done()
+				events <- streamWallet(ctx, path, db.WithContext(ctx), chain, address, request.requested[address], fetch, mutex)
+			})
+		}
+	}
+
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+
+	// the stream lasts as long as the slowest wallet, which may be longer than HTTP_WRITE_TIMEOUT
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		slog.WarnContext(ctx, "failed to lift the write deadline of a stream", slog.Any("error", err))
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no") // nginx would otherwise hold the events back
+
+	if request.flat {
+		c.SSEvent(EventAddresses, AddressesEvent{Addresses: request.detected, Rejected: request.rejected})
+		c.Writer.Flush()
+	}
+
+	// comments keep proxies from closing the stream while slow wallets load, clients ignore them
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	portfolio := make([]*responses.PortfolioResponse, 0)
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			// the fetches still save their wallets, their events are buffered
+			return
+
+		case <-heartbeat.C:
+			_, _ = io.WriteString(c.Writer, ": heartbeat\n\n")
+
+		case event, ok := <-events:
+			if !ok {
+				c.SSEvent(EventDone, portfolio)
+				c.Writer.Flush()
+				return
+			}
+
+			if wallet, ok := event.data.(WalletEvent); ok {
+				portfolio = append(portfolio, wallet.Portfolio...)
+			}
+			c.SSEvent(event.name, event.data)
+		}
+
+		c.Writer.Flush()
+	}
+}
+
+// streamWallet loads the wallet of address on chain and returns its event, the wallet or why it failed. path is
+// the request path error problems refer to.
+func streamWallet(ctx context.Context, path string, db *gorm.DB, chain, address, name string, fetch walletFetch, mutex *sync.Mutex) streamEvent {
+	ch := make(chan *models.GlobalWallet, 1)
+	errorCh := make(chan error, 1)
+	fetchWg := &sync.WaitGroup{}
+
+	fetchWg.Add(1)
+	fetch(ctx, db, address, ch, fetchWg, mutex, errorCh)
+	close(ch)
+	close(errorCh)
+
+	if apiErr := errors.HandleChannelErrors(errorCh); apiErr != nil {
+		slog.WarnContext(ctx, "streamed wallet failed", slog.String("chain", chain), slog.String("address", address), slog.Any("error", apiErr))
+
+		problem := apiErr.Problem(path, logging.RequestID(ctx))
+		return streamEvent{name: EventError, data: WalletErrorEvent{Chain: chain, Address: address, Problem: problem}}
+	}
+
+	var portfolio []*responses.PortfolioResponse
+	switch chain {
+	case utils.Bitcoin:
+		portfolio = processBtcResponses(ch)
+	case utils.Solana:
+		portfolio = processSolanaResponses(nameWallets(ctx, db, chain, ch, map[string]string{address: name}))
+	case utils.Debank:
+		portfolio = processDebankResponses(nameWallets(ctx, db, chain, ch, map[string]string{address: name}))
+	}
+
+	return streamEvent{name: EventWallet, data: WalletEvent{Chain: chain, Address: address, Portfolio: portfolio}}
+}
@@ -0,0 +1,200 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+const (
+	streamBtcAddress = "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"
+	streamEvmAddress = "0x71c7656ec7ab88b098defb751b7401b5f6d8976f"
+)
+
+// sseEvent is an event read back from a stream, or a comment when name is empty.
+type sseEvent struct {
+	name, data string
+}
+
+func parseStream(body string) []sseEvent {
+	events := make([]sseEvent, 0)
+	for _, block := range strings.Split(strings.TrimSpace(body), "\n\n") {
+		event := sseEvent{}
+		for _, line := range strings.Split(block, "\n") {
+			switch {
+			case strings.HasPrefix(line, "event:"):
+				event.name = strings.TrimPrefix(line, "event:")
+			case strings.HasPrefix(line, "data:"):
+				event.data = strings.TrimPrefix(line, "data:")
+			case strings.HasPrefix(line, ":"):
+				event.data = strings.TrimSpace(strings.TrimPrefix(line, ":"))
+			}
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// stubFetches replaces the fetch of every chain by fetch for the duration of the test.
+func stubFetches(t *testing.T, fetch walletFetch) {
+	t.Helper()
+
+	previous := streamFetches
+	streamFetches = func() map[string]walletFetch {
+		return map[string]walletFetch{utils.Bitcoin: fetch, utils.Solana: fetch, utils.Debank: fetch}
+	}
+	t.Cleanup(func() { streamFetches = previous })
+}
+
+func streamRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	// queries are built but never run
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() = %v", err)
+	}
+
+	router := gin.New()
+	router.Use(middlewares.ErrorHandler())
+	router.POST("/stream", func(c *gin.Context) { StreamAllPortfolioController(c, db) })
+
+	return router
+}
+
+func streamRequest(ctx context.Context, body string) *http.Request {
+	return httptest.NewRequest(http.MethodPost, "/stream", bytes.NewBufferString(body)).WithContext(ctx)
+}
+
+func TestStreamEvents(t *testing.T) {
+	stubFetches(t, func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+		defer wg.Done()
+
+		if address == streamEvmAddress {
+			errorCh <- errors.NewUpstreamError(utils.ProviderDebank, http.StatusTooManyRequests, 0, nil)
+			return
+		}
+		ch <- &models.GlobalWallet{
+			BlockchainType: utils.Bitcoin,
+			BitcoinBtcComV1: &models.BitcoinBtcComV1{
+				BitcoinAddressInfo: &models.BitcoinAddressInfo{Balance: decimal.NewFromInt(100000000)},
+				CoingeckoPriceFeed: &models.CoingeckoPriceFeed{Price: decimal.NewFromInt(60000)},
+			},
+		}
+	})
+
+	w := httptest.NewRecorder()
+	streamRouter(t).ServeHTTP(w, streamRequest(context.Background(), `{"addresses": ["`+streamBtcAddress+`", "`+streamEvmAddress+`"]}`))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	events := parseStream(w.Body.String())
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, event.name)
+	}
+	if len(names) != 4 || names[0] != EventAddresses || names[3] != EventDone ||
+		!(names[1] == EventWallet && names[2] == EventError || names[1] == EventError && names[2] == EventWallet) {
+		t.Fatalf("events = %v, want addresses, the wallet and error in any order, then done", names)
+	}
+
+	for _, event := range events[1:3] {
+		switch event.name {
+		case EventWallet:
+			var wallet WalletEvent
+			if err := json.Unmarshal([]byte(event.data), &wallet); err != nil || wallet.Address != streamBtcAddress || len(wallet.Portfolio) != 1 {
+				t.Errorf("wallet event = %s, want the portfolio of %s", event.data, streamBtcAddress)
+			}
+		case EventError:
+			var failure WalletErrorEvent
+			if err := json.Unmarshal([]byte(event.data), &failure); err != nil || failure.Address != streamEvmAddress || failure.Problem == nil {
+				t.Errorf("error event = %s, want the problem of %s", event.data, streamEvmAddress)
+			}
+		}
+	}
+
+	var done []json.RawMessage
+	if err := json.Unmarshal([]byte(events[3].data), &done); err != nil || len(done) != 1 {
+		t.Errorf("done event = %s, want the one loaded wallet", events[3].data)
+	}
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	previous := streamHeartbeat
+	streamHeartbeat = 5 * time.Millisecond
+	t.Cleanup(func() { streamHeartbeat = previous })
+
+	stubFetches(t, func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+		defer wg.Done()
+
+		time.Sleep(50 * time.Millisecond)
+		errorCh <- errors.NewUpstreamError(utils.ProviderBtcCom, http.StatusBadGateway, 0, nil)
+	})
+
+	w := httptest.NewRecorder()
+	streamRouter(t).ServeHTTP(w, streamRequest(context.Background(), `{"btc": ["`+streamBtcAddress+`"]}`))
+
+	events := parseStream(w.Body.String())
+	if len(events) < 3 || events[0] != (sseEvent{data: "heartbeat"}) || events[len(events)-1].name != EventDone {
+		t.Errorf("events = %v, want heartbeats while the wallet loads, then its events", events)
+	}
+}
+
+func TestStreamClientGoneAway(t *testing.T) {
+	release, saved := make(chan struct{}), make(chan struct{})
+	stubFetches(t, func(ctx context.Context, db *gorm.DB, address string, ch chan<- *models.GlobalWallet, wg *sync.WaitGroup, mutex *sync.Mutex, errorCh chan<- error) {
+		defer wg.Done()
+
+		<-release
+		if ctx.Err() != nil {
+			t.Errorf("the fetch was canceled with the request: %v", ctx.Err())
+		}
+		close(saved)
+		errorCh <- errors.NewUpstreamError(utils.ProviderBtcCom, http.StatusBadGateway, 0, nil)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	w := httptest.NewRecorder()
+	go func() {
+		defer close(served)
+		streamRouter(t).ServeHTTP(w, streamRequest(ctx, `{"btc": ["`+streamBtcAddress+`"]}`))
+	}()
+
+	cancel()
+	select {
+	case <-served:
+	case <-time.After(time.Second):
+		t.Fatal("the stream did not end when its client went away")
+	}
+	if strings.Contains(w.Body.String(), "event:") {
+		t.Errorf("events were sent to a client that went away: %s", w.Body)
+	}
+
+	close(release)
+	select {
+	case <-saved:
+	case <-time.After(time.Second):
+		t.Fatal("the wallet was not loaded after its client went away")
+	}
+}
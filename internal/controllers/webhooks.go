@@ -0,0 +1,347 @@
+package controllers
+
+import (
+	er "errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// webhookDeliveriesLimit is the number of deliveries listed, the latest ones.
+const webhookDeliveriesLimit = 100
+
+type (
+	CreateWebhookRequest struct {
+		URL            string           `json:"url" binding:"required"`       // https to a public host
+		Addresses      []string         `json:"addresses" binding:"required"` // addresses or names of any chain
+		Events         []string         `json:"events"`                       // defaults to every event
+		ValueThreshold *decimal.Decimal `json:"value_threshold"`              // usd, required by portfolio.value_threshold
+	}
+
+	WebhookResponse struct {
+		WebhookID      int              `json:"webhook_id"`
+		URL            string           `json:"url"`
+		Secret         string           `json:"secret,omitempty"` // only returned once, on creation
+		Events         []string         `json:"events"`
+		Wallets        []WatchedWallet  `json:"wallets"`
+		ValueThreshold *decimal.Decimal `json:"value_threshold"`
+		LastValue      *decimal.Decimal `json:"last_value"`
+		APIKeyID       *int             `json:"api_key_id"`
+		DisabledAt     *time.Time       `json:"disabled_at"`
+		CreatedAt      time.Time        `json:"created_at"`
+	}
+)
+
+//	@BasePath	/api/v1
+
+// CreateWebhookController godoc
+//
+// @Summary      Create a webhook
+// @Description  Notifies url of the events of the wallets of addresses: wallet.balance_changed, token.received, nft.received,
+// @Description  portfolio.value_threshold when their usd value crosses value_threshold, and refresh.failed. Deliveries are
+// @Description  POSTed with an X-Webhook-Signature of sha256= and the hex HMAC-SHA256 of "<X-Webhook-Timestamp>.<body>"
+// @Description  keyed with the secret, which is only returned in this response, and retried with backoff until answered 2xx.
+// @Description  Webhooks created with an api key belong to that key.
+// @Tags         webhooks
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body CreateWebhookRequest true "CreateWebhookRequest object"
+// @Success      201 {object} WebhookResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /webhooks [post]
+func CreateWebhookController(c *gin.Context, db *gorm.DB) {
+	request := &CreateWebhookRequest{}
+
+	if err := c.ShouldBindJSON(request); err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError(err.Error()))
+		return
+	}
+
+	if err := validateWebhookURL(request.URL); err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError("invalid url: "+err.Error()))
+		return
+	}
+
+	webhookEvents := utils.Unique(request.Events)
+	if len(webhookEvents) == 0 {
+		webhookEvents = services.WebhookEvents
+	}
+
+	for _, event := range webhookEvents {
+		if !isWebhookEvent(event) {
+			errors.HandleHttpError(c, errors.NewBadRequestError("unknown event "+event+", expected one of "+strings.Join(services.WebhookEvents, ", ")))
+			return
+		}
+	}
+
+	if request.ValueThreshold != nil && !request.ValueThreshold.IsPositive() {
+		errors.HandleHttpError(c, errors.NewBadRequestError("value_threshold must be positive"))
+		return
+	}
+	if request.ValueThreshold == nil && containsString(webhookEvents, services.WebhookValueThreshold) {
+		errors.HandleHttpError(c, errors.NewBadRequestError("value_threshold is required by "+services.WebhookValueThreshold))
+		return
+	}
+
+	wallets, ok := watchWallets(c, request.Addresses, "webhook")
+	if !ok {
+		return
+	}
+
+	secret, err := utils.GenerateWebhookSecret()
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while generating webhook secret", err))
+		return
+	}
+
+	userID, apiKeyID := webhookOwner(c)
+
+	webhook := &models.Webhook{
+		UserID:         userID,
+		APIKeyID:       apiKeyID,
+		URL:            request.URL,
+		Secret:         secret,
+		Events:         strings.Join(webhookEvents, ","),
+		Wallets:        strings.Join(wallets, ","),
+		ValueThreshold: request.ValueThreshold,
+	}
+
+	if err := models.CreateWebhook(db, webhook); err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while creating webhook", err))
+		return
+	}
+
+	response := newWebhookResponse(webhook)
+	response.Secret = secret
+
+	c.JSON(http.StatusCreated, response)
+}
+
+//	@BasePath	/api/v1
+
+// ListWebhooksController godoc
+//
+// @Summary      List webhooks
+// @Description  Lists the webhooks of the signed in user, or of the api key called with, including deleted ones.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {object} []WebhookResponse
+// @Failure      401 {object} errors.Problem
+// @Failure      500 {object} errors.Problem
+// @Router       /webhooks [get]
+func ListWebhooksController(c *gin.Context, db *gorm.DB) {
+	userID, apiKeyID := webhookOwner(c)
+
+	webhooks, err := models.GetWebhooks(db, userID, apiKeyID)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while listing webhooks", err))
+		return
+	}
+
+	response := make([]*WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		response = append(response, newWebhookResponse(webhook))
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+//	@BasePath	/api/v1
+
+// DeleteWebhookController godoc
+//
+// @Summary      Delete a webhook
+// @Description  Stops the deliveries of a webhook, pending ones included. Its delivery log is kept.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        webhook_id path int true "Webhook ID" Format(int)
+// @Success      200 {object} WebhookResponse
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Router       /webhooks/{webhook_id} [delete]
+func DeleteWebhookController(c *gin.Context, db *gorm.DB) {
+	webhook, ok := ownedWebhook(c, db)
+	if !ok {
+		return
+	}
+
+	if err := models.DisableWebhook(db, webhook); err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while deleting webhook", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+//	@BasePath	/api/v1
+
+// TestWebhookController godoc
+//
+// @Summary      Test a webhook
+// @Description  Sends a signed webhook.test delivery to the webhook now and returns it with the response status or error.
+// @Description  Test deliveries are not retried.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        webhook_id path int true "Webhook ID" Format(int)
+// @Success      200 {object} models.WebhookDelivery
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Failure      409 {object} errors.Problem
+// @Router       /webhooks/{webhook_id}/test [post]
+func TestWebhookController(c *gin.Context, db *gorm.DB) {
+	webhook, ok := ownedWebhook(c, db)
+	if !ok {
+		return
+	}
+
+	if webhook.IsDisabled() {
+		errors.HandleHttpError(c, errors.NewConflictError("webhook was deleted"))
+		return
+	}
+
+	delivery, err := services.FireTestWebhook(c.Request.Context(), db, webhook)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while recording test delivery", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+//	@BasePath	/api/v1
+
+// ListWebhookDeliveriesController godoc
+//
+// @Summary      List webhook deliveries
+// @Description  Lists the latest deliveries of a webhook, newest first, with their status, attempts and last error.
+// @Tags         webhooks
+// @Produce      json
+// @Security     BearerAuth
+// @Param        webhook_id path int true "Webhook ID" Format(int)
+// @Success      200 {object} []models.WebhookDelivery
+// @Failure      400 {object} errors.Problem
+// @Failure      401 {object} errors.Problem
+// @Failure      404 {object} errors.Problem
+// @Router       /webhooks/{webhook_id}/deliveries [get]
+func ListWebhookDeliveriesController(c *gin.Context, db *gorm.DB) {
+	webhook, ok := ownedWebhook(c, db)
+	if !ok {
+		return
+	}
+
+	deliveries, err := models.GetWebhookDeliveries(db, webhook.WebhookID, webhookDeliveriesLimit)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewInternalError("error while listing webhook deliveries", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// webhookOwner returns the user calling, and the api key it called with if any, which webhooks are scoped to.
+func webhookOwner(c *gin.Context) (userID int, apiKeyID *int) {
+	if value, ok := c.Get(utils.ContextAPIKey); ok {
+		if apiKey, ok := value.(*models.APIKey); ok {
+			apiKeyID = &apiKey.APIKeyID
+		}
+	}
+
+	return c.GetInt(utils.ContextUserID), apiKeyID
+}
+
+// ownedWebhook loads the webhook of the webhook-id path parameter if the caller owns it. Otherwise the error
+// response is written and ok is false.
+func ownedWebhook(c *gin.Context, db *gorm.DB) (*models.Webhook, bool) {
+	webhookID, err := strconv.Atoi(c.Param("webhook-id"))
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewBadRequestError("invalid webhook id"))
+		return nil, false
+	}
+
+	userID, apiKeyID := webhookOwner(c)
+
+	webhook, err := models.GetWebhook(db, userID, apiKeyID, webhookID)
+	if err != nil {
+		errors.HandleHttpError(c, errors.NewDatabaseError(err, "webhook not found"))
+		return nil, false
+	}
+
+	return webhook, true
+}
+
+// validateWebhookURL accepts https urls whose host is a name or a public address. Names resolving to private
+// addresses are refused when delivering. With WEBHOOK_ALLOW_PRIVATE, for development, private addresses and http
+// urls on localhost are accepted too.
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Host == "" || parsed.User != nil {
+		return er.New("expected an absolute url without credentials")
+	}
+
+	host := parsed.Hostname()
+	ip := net.ParseIP(host)
+	local := strings.EqualFold(host, "localhost") || ip != nil && ip.IsLoopback()
+
+	if !configs.EnvConfigVars.WebhookAllowPrivate && (local || ip != nil && !services.PublicIP(ip)) {
+		return services.ErrPrivateAddress
+	}
+
+	if parsed.Scheme == "https" || parsed.Scheme == "http" && local {
+		return nil
+	}
+
+	return er.New("expected an https url")
+}
+
+func newWebhookResponse(webhook *models.Webhook) *WebhookResponse {
+	return &WebhookResponse{
+		WebhookID:      webhook.WebhookID,
+		URL:            webhook.URL,
+		Events:         webhook.EventList(),
+		Wallets:        newWatchedWallets(webhook.WalletList()),
+		ValueThreshold: webhook.ValueThreshold,
+		LastValue:      webhook.LastValue,
+		APIKeyID:       webhook.APIKeyID,
+		DisabledAt:     webhook.DisabledAt,
+		CreatedAt:      webhook.CreatedAt,
+	}
+}
+
+func isWebhookEvent(event string) bool {
+	return containsString(services.WebhookEvents, event)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,496 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	er "errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/events"
+	"github.com/0xbase-Corp/portfolio_svc/shared/lifecycle"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// Types of the messages of the websocket, besides the events, whose type is the event type.
+const (
+	SocketSubscribe    = "subscribe"    // client: watch addresses, resuming after last_event_id when set
+	SocketUnsubscribe  = "unsubscribe"  // client: stop watching addresses
+	SocketSubscribed   = "subscribed"   // server: the addresses now watched, and those rejected
+	SocketUnsubscribed = "unsubscribed" // server: the addresses no longer watched
+	SocketResync       = "resync"       // server: events were missed, reload the portfolio before relying on events
+	SocketError        = "error"        // server: a message could not be handled
+)
+
+const (
+	// socketWriteWait is how long a message may take to be written, a client not reading for that long is dropped
+	socketWriteWait = 10 * time.Second
+
+	// socketMaxMessage is the size limit of client messages
+	socketMaxMessage = 64 * 1024
+)
+
+// socketPriceAssets are the coingecko ids of the prices pushed for the wallets of a chain. Evm token prices
+// come with the debank wallets, as balance events.
+var socketPriceAssets = map[string]string{
+	utils.Bitcoin: utils.Bitcoin,
+	utils.Solana:  utils.Solana,
+}
+
+type (
+	// SocketRequest is a message of a websocket client.
+	SocketRequest struct {
+		Type        string   `json:"type"`
+		Addresses   []string `json:"addresses"`               // addresses or names of any chain
+		LastEventID uint64   `json:"last_event_id,omitempty"` // the last event received before reconnecting
+	}
+
+	// SocketMessage is a message of the server other than an event.
+	SocketMessage struct {
+		Type        string               `json:"type"`
+		Addresses   []DetectedAddress    `json:"addresses,omitempty"`
+		Rejected    []errors.ErrorDetail `json:"rejected,omitempty"`
+		LastEventID uint64               `json:"last_event_id,omitempty"`
+		Problem     *errors.Problem      `json:"problem,omitempty"`
+	}
+
+	// socketRead is a message read from a client, or why it could not be decoded.
+	socketRead struct {
+		request SocketRequest
+		err     error
+	}
+
+	// socketWatch is a wallet watched by a connection.
+	socketWatch struct {
+		address DetectedAddress
+		chain   string
+		unwatch []func()
+	}
+
+	// portfolioSocket is a websocket connection. Its messages are only written by serve, gorilla connections
+	// support a single writer.
+	portfolioSocket struct {
+		ctx      context.Context
+		conn     *websocket.Conn
+		sub      *events.Subscription
+		watched  map[string]*socketWatch // by address
+		lastSent uint64                  // the ID of the last event sent, events replayed on resume are not sent twice
+		path     string
+		user     int
+	}
+
+	// socketUsage counts the connections of a user and the wallets they watch, see WS_MAX_CONNECTIONS_PER_USER
+	// and WS_MAX_WATCHES_PER_USER.
+	socketUsage struct {
+		connections int
+		watches     int
+	}
+)
+
+var (
+	// browsers send their token in the query, so a page of another origin could open a socket with it
+	upgrader = websocket.Upgrader{CheckOrigin: allowedOrigin}
+
+	socketUsersMu sync.Mutex
+	socketUsers   = make(map[int]*socketUsage)
+)
+
+// allowedOrigin lets through clients without an Origin, which are not browsers, browsers of the
+// WS_ALLOWED_ORIGINS, and those of the host the API is served on.
+func allowedOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	for _, allowed := range strings.Split(configs.EnvConfigVars.WsAllowedOrigins, ",") {
+		if allowed = strings.TrimSpace(allowed); allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// openSocket counts a new connection of user, false when they have too many already.
+func openSocket(user int) bool {
+	socketUsersMu.Lock()
+	defer socketUsersMu.Unlock()
+
+	usage, ok := socketUsers[user]
+	if !ok {
+		usage = &socketUsage{}
+		socketUsers[user] = usage
+	}
+	if usage.connections >= configs.EnvConfigVars.WsMaxConnectionsPerUser {
+		return false
+	}
+	usage.connections++
+
+	return true
+}
+
+// closeSocket releases a connection of user and the watches it held.
+func closeSocket(user, watches int) {
+	socketUsersMu.Lock()
+	defer socketUsersMu.Unlock()
+
+	usage := socketUsers[user]
+	usage.connections--
+	usage.watches -= watches
+	if usage.connections <= 0 {
+		delete(socketUsers, user)
+	}
+}
+
+// addWatches counts n more wallets watched by user, false when that would be too many.
+func addWatches(user, n int) bool {
+	socketUsersMu.Lock()
+	defer socketUsersMu.Unlock()
+
+	usage := socketUsers[user]
+	if usage.watches+n > configs.EnvConfigVars.WsMaxWatchesPerUser {
+		return false
+	}
+	usage.watches += n
+
+	return true
+}
+
+// removeWatches releases n wallets watched by user.
+func removeWatches(user, n int) {
+	socketUsersMu.Lock()
+	defer socketUsersMu.Unlock()
+
+	socketUsers[user].watches -= n
+}
+
+// PortfolioSocketController godoc
+//
+// PortfolioSocketController pushes the price ticks and balance changes of the wallets a client subscribes to.
+// @Summary      Live prices and balances
+// @Description  Upgrades to a websocket. Clients send {"type":"subscribe","addresses":[...]} with addresses or names of
+// @Description  any chain, and {"type":"unsubscribe","addresses":[...]}. The server answers subscribed or unsubscribed,
+// @Description  then pushes the events of the watched wallets: price ticks of their native asset and balance changes
+// @Description  found when they are refreshed in the background. Every event has an id; a client reconnecting sends
+// @Description  the last one it received as last_event_id with its subscribe to get the events it missed, or a resync
+// @Description  message when they are no longer kept. The server pings every WS_PING_INTERVAL, and closes with 1013 a
+// @Description  client that falls too far behind, which should then reconnect and resume.
+// @Description  Browsers pass their token in the access_token query parameter, and connect from the WS_ALLOWED_ORIGINS
+// @Description  or the host of the API. A user opens at most WS_MAX_CONNECTIONS_PER_USER sockets, watching at most
+// @Description  WS_MAX_WATCHES_PER_USER wallets in all.
+// @Tags         portfolio
+// @Param        access_token query string false "JWT, for clients that cannot set the Authorization header"
+// @Success      101
+// @Failure      401 {object} errors.Problem
+// @Failure      403
+// @Failure      429 {object} errors.Problem
+// @Router       /api/v1/ws [get]
+func PortfolioSocketController(c *gin.Context) {
+	if _, ok := c.Get(utils.ContextUserID); !ok {
+		errors.HandleHttpError(c, errors.NewUnauthorizedError("authentication required"))
+		return
+	}
+	user := c.GetInt(utils.ContextUserID)
+
+	if !openSocket(user) {
+		max := configs.EnvConfigVars.WsMaxConnectionsPerUser
+		errors.HandleHttpError(c, errors.NewRateLimitedError("too many websockets: at most "+strconv.Itoa(max)+" per user", 0))
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// the upgrader answered the handshake with an error already
+		closeSocket(user, 0)
+		return
+	}
+	defer conn.Close()
+	defer metrics.WebsocketOpened()()
+
+	socket := &portfolioSocket{
+		ctx:     c.Request.Context(),
+		conn:    conn,
+		sub:     events.Subscribe(configs.EnvConfigVars.WsSendBuffer),
+		watched: make(map[string]*socketWatch),
+		path:    c.Request.URL.Path,
+		user:    user,
+	}
+	defer socket.close()
+
+	socket.serve()
+}
+
+func (s *portfolioSocket) serve() {
+	pingInterval := configs.EnvConfigVars.WsPingInterval
+	pongWait := 2 * pingInterval
+
+	s.conn.SetReadLimit(socketMaxMessage)
+	_ = s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	s.conn.SetPongHandler(func(string) error {
+		return s.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	// the connection is only read here, and the reads are handed to serve
+	reads := make(chan socketRead)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(reads)
+
+		for {
+			_, message, err := s.conn.ReadMessage()
+			if err != nil {
+				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+					slog.DebugContext(s.ctx, "websocket read ended", slog.Any("error", err))
+				}
+				return
+			}
+			_ = s.conn.SetReadDeadline(time.Now().Add(pongWait))
+
+			read := socketRead{}
+			read.err = json.Unmarshal(message, &read.request)
+
+			select {
+			case reads <- read:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case read, ok := <-reads:
+			if !ok {
+				return
+			}
+			if err := s.handle(read); err != nil {
+				return
+			}
+
+		case event, ok := <-s.sub.C():
+			if !ok {
+				if er.Is(s.sub.Err(), events.ErrLagged) {
+					s.closeWith(websocket.CloseTryAgainLater, "lagged, resume from "+strconv.FormatUint(s.lastSent, 10))
+				}
+				return
+			}
+			if err := s.sendEvent(event); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			if err := s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(socketWriteWait)); err != nil {
+				return
+			}
+
+		case <-lifecycle.Context().Done():
+			s.closeWith(websocket.CloseGoingAway, "shutting down")
+			return
+		}
+	}
+}
+
+// handle answers a client message, the error is that of writing the answer.
+func (s *portfolioSocket) handle(read socketRead) error {
+	if read.err != nil {
+		return s.sendError(errors.NewBadRequestError("invalid message: " + read.err.Error()))
+	}
+
+	request := read.request
+	switch request.Type {
+	case SocketSubscribe:
+		return s.subscribe(request)
+	case SocketUnsubscribe:
+		return s.unsubscribe(request)
+	}
+
+	return s.sendError(errors.NewBadRequestError("unknown message type " + strconv.Quote(request.Type)))
+}
+
+func (s *portfolioSocket) subscribe(request SocketRequest) error {
+	detected, rejected, err := detectAddresses(s.ctx, request.Addresses)
+	if err != nil {
+		return s.sendError(socketError(err))
+	}
+
+	added := 0
+	for _, address := range detected {
+		if _, ok := s.watched[address.Address]; !ok {
+			added++
+		}
+	}
+	if max := configs.EnvConfigVars.MaxAddressesPerRequest; max > 0 && len(s.watched)+added > max {
+		return s.sendError(errors.NewBadRequestError("too many addresses: at most " + strconv.Itoa(max) + " can be watched per connection"))
+	}
+	if !addWatches(s.user, added) {
+		max := configs.EnvConfigVars.WsMaxWatchesPerUser
+		return s.sendError(errors.NewRateLimitedError("too many addresses: at most "+strconv.Itoa(max)+" can be watched per user", 0))
+	}
+
+	for _, address := range detected {
+		if _, ok := s.watched[address.Address]; ok {
+			continue
+		}
+
+		chain := familyChain(address.Chain)
+		watch := &socketWatch{address: address, chain: chain, unwatch: []func(){services.Watch(chain, address.Address)}}
+		topics := []string{events.WalletTopic(address.Address)}
+		if asset, ok := socketPriceAssets[chain]; ok {
+			watch.unwatch = append(watch.unwatch, services.WatchPrice(asset, "usd"))
+			topics = append(topics, events.PriceTopic(asset, "usd"))
+		}
+
+		s.sub.Add(topics...)
+		s.watched[address.Address] = watch
+	}
+
+	if err := s.send(SocketMessage{Type: SocketSubscribed, Addresses: detected, Rejected: rejected, LastEventID: events.LastID()}); err != nil {
+		return err
+	}
+
+	if request.LastEventID == 0 {
+		return nil
+	}
+
+	missed, ok := events.Since(request.LastEventID, s.sub.Topics()...)
+	if !ok {
+		return s.send(SocketMessage{Type: SocketResync})
+	}
+	for _, event := range missed {
+		if err := s.sendEvent(event); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *portfolioSocket) unsubscribe(request SocketRequest) error {
+	detected, _, err := detectAddresses(s.ctx, request.Addresses)
+	if err != nil {
+		return s.sendError(socketError(err))
+	}
+
+	removed := make([]DetectedAddress, 0, len(detected))
+	for _, address := range detected {
+		watch, ok := s.watched[address.Address]
+		if !ok {
+			continue
+		}
+
+		for _, unwatch := range watch.unwatch {
+			unwatch()
+		}
+		delete(s.watched, address.Address)
+		s.sub.Remove(events.WalletTopic(address.Address))
+		removed = append(removed, watch.address)
+	}
+	removeWatches(s.user, len(removed))
+
+	// price topics are shared by the wallets of a chain
+	for chain, asset := range socketPriceAssets {
+		if !s.watchesChain(chain) {
+			s.sub.Remove(events.PriceTopic(asset, "usd"))
+		}
+	}
+
+	return s.send(SocketMessage{Type: SocketUnsubscribed, Addresses: removed})
+}
+
+func (s *portfolioSocket) watchesChain(chain string) bool {
+	for _, watch := range s.watched {
+		if watch.chain == chain {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *portfolioSocket) sendEvent(event events.Event) error {
+	if event.ID <= s.lastSent {
+		return nil
+	}
+
+	if err := s.send(event); err != nil {
+		return err
+	}
+	s.lastSent = event.ID
+
+	return nil
+}
+
+// socketError maps err like the ErrorHandler middleware does for responses, logging provider and unexpected
+// errors.
+func socketError(err error) *errors.APIError {
+	var (
+		apiErr      *errors.APIError
+		upstreamErr *errors.UpstreamError
+	)
+	switch {
+	case er.As(err, &apiErr):
+		return apiErr
+	case er.As(err, &upstreamErr):
+		return upstreamErr.APIError()
+	}
+
+	return errors.NewInternalError("failed to resolve names", err)
+}
+
+func (s *portfolioSocket) sendError(apiErr *errors.APIError) error {
+	if apiErr.Code >= http.StatusInternalServerError {
+		slog.ErrorContext(s.ctx, "websocket message failed", slog.Any("error", apiErr))
+	}
+
+	return s.send(SocketMessage{Type: SocketError, Problem: apiErr.Problem(s.path, logging.RequestID(s.ctx))})
+}
+
+func (s *portfolioSocket) send(message interface{}) error {
+	_ = s.conn.SetWriteDeadline(time.Now().Add(socketWriteWait))
+	return s.conn.WriteJSON(message)
+}
+
+func (s *portfolioSocket) closeWith(code int, reason string) {
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(socketWriteWait))
+}
+
+// close stops watching the wallets of the connection.
+func (s *portfolioSocket) close() {
+	closeSocket(s.user, len(s.watched))
+	s.sub.Close()
+	for _, watch := range s.watched {
+		for _, unwatch := range watch.unwatch {
+			unwatch()
+		}
+	}
+}
+
+// familyChain returns the wallet blockchain type of a chain family, see chainFamilies.
+func familyChain(family string) string {
+	for chain, name := range chainFamilies {
+		if name == family {
+			return chain
+		}
+	}
+
+	return family
+}
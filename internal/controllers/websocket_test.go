@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+)
+
+// setConfig replaces the configuration for the duration of the test.
+func setConfig(t *testing.T, env *configs.EnvConfigs) {
+	t.Helper()
+
+	previous := configs.EnvConfigVars
+	configs.EnvConfigVars = env
+	t.Cleanup(func() { configs.EnvConfigVars = previous })
+}
+
+func TestAllowedOrigin(t *testing.T) {
+	setConfig(t, &configs.EnvConfigs{WsAllowedOrigins: "https://app.0xbase.io, https://staging.0xbase.io"})
+
+	tests := []struct {
+		name   string
+		origin string
+		want   bool
+	}{
+		{"not a browser", "", true},
+		{"allowed", "https://app.0xbase.io", true},
+		{"allowed with spaces in the list", "https://staging.0xbase.io", true},
+		{"same host", "https://api.0xbase.io", true},
+		{"other origin", "https://evil.example", false},
+		{"allowed host on another scheme", "http://app.0xbase.io", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "https://api.0xbase.io/api/v1/ws", nil)
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+
+			if got := allowedOrigin(r); got != tt.want {
+				t.Errorf("allowedOrigin(%q) = %v, want %v", tt.origin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSocketLimits(t *testing.T) {
+	setConfig(t, &configs.EnvConfigs{WsMaxConnectionsPerUser: 2, WsMaxWatchesPerUser: 3})
+
+	if !openSocket(1) || !openSocket(1) {
+		t.Fatal("openSocket() refused a connection under the limit")
+	}
+	if openSocket(1) {
+		t.Error("openSocket() accepted a third connection")
+	}
+	if !openSocket(2) {
+		t.Error("openSocket() refused the connection of another user")
+	}
+
+	if !addWatches(1, 2) {
+		t.Fatal("addWatches() refused watches under the limit")
+	}
+	if addWatches(1, 2) {
+		t.Error("addWatches() went over the limit of the user")
+	}
+	removeWatches(1, 1)
+	if !addWatches(1, 2) {
+		t.Error("addWatches() did not count removed watches")
+	}
+
+	closeSocket(1, 3)
+	if !openSocket(1) {
+		t.Error("openSocket() did not count the closed connection")
+	}
+	if !addWatches(1, 3) {
+		t.Error("closeSocket() did not release the watches of the connection")
+	}
+
+	closeSocket(1, 3)
+	closeSocket(1, 0)
+	closeSocket(2, 0)
+	if len(socketUsers) != 0 {
+		t.Errorf("socketUsers = %v, want every user released", socketUsers)
+	}
+}
@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultFirst = 20
+	maxFirst     = 100
+)
+
+type (
+	// connection is a page of a list of nodes, paged forward with first and after as Relay connections are.
+	connection[T any] struct {
+		edges    []*edge[T]
+		pageInfo *pageInfo
+
+		total int
+		count func() (int, error) // counts the items when the list was not loaded whole
+	}
+
+	edge[T any] struct {
+		cursor string
+		node   T
+	}
+
+	pageInfo struct {
+		hasNextPage     bool
+		hasPreviousPage bool
+		startCursor     *string
+		endCursor       *string
+	}
+
+	// connectionArgs are the arguments of the fields returning connections.
+	connectionArgs struct {
+		First *int32
+		After *string
+	}
+)
+
+func (c *connection[T]) Edges() []*edge[T] {
+	return c.edges
+}
+
+func (c *connection[T]) Nodes() []T {
+	nodes := make([]T, 0, len(c.edges))
+	for _, edge := range c.edges {
+		nodes = append(nodes, edge.node)
+	}
+
+	return nodes
+}
+
+func (c *connection[T]) PageInfo() *pageInfo {
+	return c.pageInfo
+}
+
+func (c *connection[T]) TotalCount(ctx context.Context) (int32, error) {
+	if c.count == nil {
+		return int32(c.total), nil
+	}
+
+	total, err := c.count()
+	if err != nil {
+		return 0, internalError(ctx, err)
+	}
+
+	return int32(total), nil
+}
+
+func (e *edge[T]) Cursor() string {
+	return e.cursor
+}
+
+func (e *edge[T]) Node() T {
+	return e.node
+}
+
+func (p *pageInfo) HasNextPage() bool {
+	return p.hasNextPage
+}
+
+func (p *pageInfo) HasPreviousPage() bool {
+	return p.hasPreviousPage
+}
+
+func (p *pageInfo) StartCursor() *string {
+	return p.startCursor
+}
+
+func (p *pageInfo) EndCursor() *string {
+	return p.endCursor
+}
+
+// page returns the first and after arguments of a connection field.
+func (args connectionArgs) page() (first int, after string, err error) {
+	first = defaultFirst
+	if args.First != nil {
+		first = int(*args.First)
+	}
+	if first < 0 || first > maxFirst {
+		return 0, "", badInput("first must be between 0 and " + strconv.Itoa(maxFirst))
+	}
+
+	if args.After != nil {
+		after = *args.After
+	}
+
+	return first, after, nil
+}
+
+// offsetConnection returns the page of items the arguments select, for lists loaded whole.
+func offsetConnection[T any](items []T, args connectionArgs) (*connection[T], error) {
+	first, after, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	start := 0
+	if after != "" {
+		value, ok := decodeCursor("offset", after)
+		if start, err = strconv.Atoi(value); !ok || err != nil || start < 0 || start > len(items) {
+			return nil, badInput("invalid cursor " + after)
+		}
+	}
+	end := min(start+first, len(items))
+
+	c := &connection[T]{edges: make([]*edge[T], 0, end-start), total: len(items), pageInfo: &pageInfo{hasNextPage: end < len(items), hasPreviousPage: start > 0}}
+	for i := start; i < end; i++ {
+		c.edges = append(c.edges, &edge[T]{cursor: encodeCursor("offset", strconv.Itoa(i+1)), node: items[i]})
+	}
+	c.setCursors()
+
+	return c, nil
+}
+
+func (c *connection[T]) setCursors() {
+	if len(c.edges) == 0 {
+		return
+	}
+
+	c.pageInfo.startCursor = &c.edges[0].cursor
+	c.pageInfo.endCursor = &c.edges[len(c.edges)-1].cursor
+}
+
+// resolvers returns the resolvers of items.
+func resolvers[S, T any](items []S, resolver func(S) T) []T {
+	list := make([]T, 0, len(items))
+	for _, item := range items {
+		list = append(list, resolver(item))
+	}
+
+	return list
+}
+
+// encodeCursor returns an opaque cursor of value. kind keeps the cursors of a list from being used with
+// another.
+func encodeCursor(kind, value string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + value))
+}
+
+func decodeCursor(kind, cursor string) (string, bool) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.CutPrefix(string(decoded), kind+":")
+}
@@ -0,0 +1,170 @@
+// Package graph is the GraphQL schema of the service: the signed in user, stored wallets, portfolios of
+// wallets, their holdings and NFTs, and prices with their history. It reads what the REST endpoints stored
+// and never calls the providers.
+package graph
+
+import (
+	"context"
+	er "errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/shared/dataloader"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+)
+
+const (
+	// loadWait is how long a loader waits for the other items of a list to join a batch.
+	loadWait = 2 * time.Millisecond
+
+	// maxBatch bounds the keys of a batched query.
+	maxBatch = 500
+)
+
+type contextKey struct{}
+
+// request is what the resolvers of one request share: the database, the caller, what is left of the cost
+// the query may spend and the loaders batching the queries of the items of lists.
+type request struct {
+	db     *gorm.DB
+	userID int // 0 unless signed in with a user token
+	budget atomic.Int64
+
+	wallets *dataloader.Loader[string, *models.GlobalWallet]       // by address
+	assets  *dataloader.Loader[int, *models.GlobalWallet]          // by wallet id, with chain info, tokens and NFTs
+	prices  *dataloader.Loader[string, *models.CoingeckoPriceFeed] // by coingecko id
+}
+
+// WithRequest returns ctx carrying what the resolvers of a request need. userID is that of a caller signed in
+// with a user token, 0 for api keys and anonymous callers.
+func WithRequest(ctx context.Context, db *gorm.DB, userID int) context.Context {
+	r := &request{db: db, userID: userID}
+	r.budget.Store(MaxComplexity)
+
+	r.wallets = dataloader.New(func(ctx context.Context, addresses []string) (map[string]*models.GlobalWallet, error) {
+		wallets, err := models.GetWalletsByAddresses(db.WithContext(ctx), addresses)
+		if err != nil {
+			return nil, err
+		}
+
+		byAddress := make(map[string]*models.GlobalWallet, len(wallets))
+		for _, wallet := range wallets {
+			byAddress[wallet.WalletAddress] = wallet
+		}
+		return byAddress, nil
+	}, loadWait, maxBatch)
+
+	r.assets = dataloader.New(func(ctx context.Context, ids []int) (map[int]*models.GlobalWallet, error) {
+		wallets, err := models.GetGlobalWalletsWithAssets(db.WithContext(ctx), ids)
+		if err != nil {
+			return nil, err
+		}
+
+		byID := make(map[int]*models.GlobalWallet, len(wallets))
+		for _, wallet := range wallets {
+			byID[wallet.WalletID] = wallet
+		}
+		return byID, nil
+	}, loadWait, maxBatch)
+
+	r.prices = dataloader.New(func(ctx context.Context, assets []string) (map[string]*models.CoingeckoPriceFeed, error) {
+		feeds, err := models.GetCoingeckoPriceFeedsByNames(db.WithContext(ctx), assets)
+		if err != nil {
+			return nil, err
+		}
+
+		byName := make(map[string]*models.CoingeckoPriceFeed, len(feeds))
+		for i := range feeds {
+			byName[feeds[i].Name] = &feeds[i]
+		}
+		return byName, nil
+	}, loadWait, maxBatch)
+
+	return context.WithValue(ctx, contextKey{}, r)
+}
+
+func requestOf(ctx context.Context) *request {
+	return ctx.Value(contextKey{}).(*request)
+}
+
+// spend takes cost from the budget of the request, failing the field once the query spent it all.
+func spend(ctx context.Context, cost int64) error {
+	if requestOf(ctx).budget.Add(-cost) < 0 {
+		return &Error{message: "the query is more complex than " + strconv.Itoa(MaxComplexity) + ", select fewer fields", kind: errors.KindValidation}
+	}
+
+	return nil
+}
+
+// loadAssets returns wallet with its chain info, tokens and NFTs.
+func loadAssets(ctx context.Context, wallet *models.GlobalWallet) (*models.GlobalWallet, error) {
+	return requestOf(ctx).assets.Load(ctx, wallet.WalletID)
+}
+
+// loadPrice returns the stored price feed of asset, nil when it is not priced yet.
+func loadPrice(ctx context.Context, asset string) (*models.CoingeckoPriceFeed, error) {
+	feed, err := requestOf(ctx).prices.Load(ctx, asset)
+	if er.Is(err, dataloader.ErrNotFound) {
+		return nil, nil
+	}
+
+	return feed, err
+}
+
+// panicLogger logs the panics of resolvers, which fail their field.
+type panicLogger struct{}
+
+func (panicLogger) LogPanic(ctx context.Context, value interface{}) {
+	slog.ErrorContext(ctx, "graphql resolver panicked", slog.Any("panic", value))
+}
+
+// Error is an error of a field, its kind sent as the code extension.
+type Error struct {
+	message string
+	kind    errors.Kind
+}
+
+func (e *Error) Error() string {
+	return e.message
+}
+
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.kind}
+}
+
+func badInput(message string) error {
+	return &Error{message: message, kind: errors.KindValidation}
+}
+
+// internalError logs err and returns the error the client sees instead, without the cause.
+func internalError(ctx context.Context, err error) error {
+	var apiErr *errors.APIError
+	if er.As(err, &apiErr) && apiErr.Code < 500 {
+		return &Error{message: apiErr.Message, kind: apiErr.Kind}
+	}
+
+	slog.ErrorContext(ctx, "graphql field failed", slog.Any("error", err))
+
+	var upstreamErr *errors.UpstreamError
+	if er.As(err, &upstreamErr) {
+		return &Error{message: upstreamErr.Provider + " is unavailable", kind: errors.KindUpstreamUnavailable}
+	}
+
+	return &Error{message: "internal error", kind: errors.KindInternal}
+}
+
+// resolveAddress returns the chain and normalized address of an address of any chain, or of an ENS or SNS name.
+func resolveAddress(ctx context.Context, input string) (chain, address string, err error) {
+	chain, address, err = services.ResolveAddress(ctx, input)
+	if err != nil {
+		return "", "", internalError(ctx, err)
+	}
+
+	return chain, address, nil
+}
@@ -0,0 +1,45 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+)
+
+// pricedWallet returns a wallet loaded with its assets carrying the stored price of its coin. The wallet is
+// shared by the resolvers of the request, the price feed is set on a copy.
+func pricedWallet(ctx context.Context, wallet *models.GlobalWallet) (*models.GlobalWallet, error) {
+	if !services.HasCoin(wallet.BlockchainType) {
+		return wallet, nil
+	}
+
+	feed, err := loadPrice(ctx, wallet.BlockchainType)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.WithCoinPrice(wallet, feed), nil
+}
+
+// walletValue returns the usd value of a wallet loaded with its assets, as GlobalWallet.ValueUSD has it.
+func walletValue(ctx context.Context, wallet *models.GlobalWallet) (decimal.Decimal, error) {
+	priced, err := pricedWallet(ctx, wallet)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return priced.ValueUSD(), nil
+}
+
+// holdings returns the coin balance and the tokens of a wallet loaded with its assets, coin first.
+func holdings(ctx context.Context, wallet *models.GlobalWallet) ([]*services.Holding, error) {
+	priced, err := pricedWallet(ctx, wallet)
+	if err != nil {
+		return nil, err
+	}
+
+	return services.Holdings(priced), nil
+}
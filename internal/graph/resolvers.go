@@ -0,0 +1,395 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+)
+
+// Resolvers of the object types of the schema, one per type.
+type (
+	userResolver struct {
+		user *models.User
+	}
+
+	alertRuleResolver struct {
+		rule *models.AlertRule
+	}
+
+	walletResolver struct {
+		wallet *models.GlobalWallet
+	}
+
+	holdingResolver struct {
+		holding *services.Holding
+	}
+
+	nftResolver struct {
+		nft *services.NFT
+	}
+
+	evmChainResolver struct {
+		chain models.ChainDetails
+	}
+
+	portfolioResolver struct {
+		wallets []*models.GlobalWallet
+		missing []string // addresses requested that are not stored
+	}
+
+	priceResolver struct {
+		feed *models.CoingeckoPriceFeed
+	}
+
+	pricePointResolver struct {
+		point *models.CoingeckoPriceHistory
+	}
+)
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(u.user.UserId))
+}
+
+func (u *userResolver) Username() string {
+	return u.user.Username
+}
+
+func (u *userResolver) Email() string {
+	return u.user.Email
+}
+
+func (u *userResolver) SignupDate() timeScalar {
+	return timeScalar{u.user.SignupDate}
+}
+
+func (u *userResolver) AlertRules(ctx context.Context, args connectionArgs) (*connection[*alertRuleResolver], error) {
+	if err := spend(ctx, loadCost); err != nil {
+		return nil, err
+	}
+
+	rules, err := models.GetAlertRules(requestOf(ctx).db, u.user.UserId)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return offsetConnection(resolvers(rules, func(rule *models.AlertRule) *alertRuleResolver { return &alertRuleResolver{rule} }), args)
+}
+
+func (a *alertRuleResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(a.rule.RuleID))
+}
+
+func (a *alertRuleResolver) Name() string {
+	return a.rule.Name
+}
+
+func (a *alertRuleResolver) Kind() string {
+	return a.rule.Kind
+}
+
+func (a *alertRuleResolver) Asset() *string {
+	return optionalString(a.rule.Asset)
+}
+
+func (a *alertRuleResolver) Direction() string {
+	return a.rule.Direction
+}
+
+func (a *alertRuleResolver) Threshold() decimalScalar {
+	return decimalScalar{a.rule.Threshold}
+}
+
+func (a *alertRuleResolver) WindowSeconds() *int32 {
+	if a.rule.WindowSeconds <= 0 {
+		return nil
+	}
+
+	seconds := int32(a.rule.WindowSeconds)
+	return &seconds
+}
+
+func (a *alertRuleResolver) Wallets() []string {
+	return a.rule.WalletList()
+}
+
+func (a *alertRuleResolver) Channels() []string {
+	return a.rule.ChannelList()
+}
+
+func (a *alertRuleResolver) Enabled() bool {
+	return a.rule.Enabled
+}
+
+func (a *alertRuleResolver) LastTriggeredAt() *timeScalar {
+	if a.rule.LastTriggeredAt == nil {
+		return nil
+	}
+
+	return &timeScalar{*a.rule.LastTriggeredAt}
+}
+
+func (a *alertRuleResolver) CreatedAt() timeScalar {
+	return timeScalar{a.rule.CreatedAt}
+}
+
+func (w *walletResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(w.wallet.WalletID))
+}
+
+func (w *walletResolver) Address() string {
+	return w.wallet.WalletAddress
+}
+
+func (w *walletResolver) Name() *string {
+	return optionalString(w.wallet.Name)
+}
+
+func (w *walletResolver) Chain() string {
+	return chainValues[w.wallet.BlockchainType]
+}
+
+func (w *walletResolver) LastUpdatedAt() timeScalar {
+	return timeScalar{w.wallet.LastUpdatedAt}
+}
+
+func (w *walletResolver) Balance(ctx context.Context) (*decimalScalar, error) {
+	wallet, err := loadAssets(ctx, w.wallet)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	if balance, ok := services.CoinBalance(wallet); ok {
+		return &decimalScalar{balance}, nil
+	}
+
+	return nil, nil
+}
+
+func (w *walletResolver) ValueUsd(ctx context.Context) (decimalScalar, error) {
+	wallet, err := loadAssets(ctx, w.wallet)
+	if err != nil {
+		return decimalScalar{}, internalError(ctx, err)
+	}
+
+	value, err := walletValue(ctx, wallet)
+	if err != nil {
+		return decimalScalar{}, internalError(ctx, err)
+	}
+
+	return decimalScalar{value}, nil
+}
+
+func (w *walletResolver) Holdings(ctx context.Context, args connectionArgs) (*connection[*holdingResolver], error) {
+	wallet, err := loadAssets(ctx, w.wallet)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	list, err := holdings(ctx, wallet)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return offsetConnection(resolvers(list, func(holding *services.Holding) *holdingResolver { return &holdingResolver{holding} }), args)
+}
+
+func (w *walletResolver) Nfts(ctx context.Context, args connectionArgs) (*connection[*nftResolver], error) {
+	wallet, err := loadAssets(ctx, w.wallet)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return offsetConnection(resolvers(services.NFTs(wallet), func(nft *services.NFT) *nftResolver { return &nftResolver{nft} }), args)
+}
+
+func (w *walletResolver) Chains(ctx context.Context) ([]*evmChainResolver, error) {
+	wallet, err := loadAssets(ctx, w.wallet)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	if wallet.ChainDetails == nil {
+		return []*evmChainResolver{}, nil
+	}
+
+	return resolvers(*wallet.ChainDetails, func(chain models.ChainDetails) *evmChainResolver { return &evmChainResolver{chain} }), nil
+}
+
+func (h *holdingResolver) Asset() string {
+	return h.holding.Asset
+}
+
+func (h *holdingResolver) Symbol() string {
+	return h.holding.Symbol
+}
+
+func (h *holdingResolver) Name() string {
+	return h.holding.Name
+}
+
+func (h *holdingResolver) Network() string {
+	return h.holding.Network
+}
+
+func (h *holdingResolver) Amount() decimalScalar {
+	return decimalScalar{h.holding.Amount}
+}
+
+func (h *holdingResolver) PriceUsd() *decimalScalar {
+	return optionalDecimal(h.holding.PriceUsd)
+}
+
+func (h *holdingResolver) ValueUsd() *decimalScalar {
+	return optionalDecimal(h.holding.ValueUsd)
+}
+
+func (h *holdingResolver) LogoUrl() *string {
+	return optionalString(h.holding.LogoURL)
+}
+
+func (n *nftResolver) Contract() string {
+	return n.nft.Contract
+}
+
+func (n *nftResolver) TokenId() *string {
+	return optionalString(n.nft.TokenID)
+}
+
+func (n *nftResolver) Network() string {
+	return n.nft.Network
+}
+
+func (n *nftResolver) Name() string {
+	return n.nft.Name
+}
+
+func (n *nftResolver) Symbol() *string {
+	return optionalString(n.nft.Symbol)
+}
+
+func (n *nftResolver) Collection() *string {
+	return optionalString(n.nft.Collection)
+}
+
+func (n *nftResolver) Amount() decimalScalar {
+	return decimalScalar{n.nft.Amount}
+}
+
+func (n *nftResolver) ImageUrl() *string {
+	return optionalString(n.nft.ImageURL)
+}
+
+func (n *nftResolver) PriceUsd() *decimalScalar {
+	return optionalDecimal(n.nft.PriceUsd)
+}
+
+func (c *evmChainResolver) ID() string {
+	return c.chain.ID
+}
+
+func (c *evmChainResolver) Name() string {
+	return c.chain.Name
+}
+
+func (c *evmChainResolver) LogoUrl() *string {
+	return optionalString(c.chain.LogoURL)
+}
+
+func (c *evmChainResolver) ValueUsd() decimalScalar {
+	return decimalScalar{c.chain.USDValue}
+}
+
+func (p *portfolioResolver) Wallets(args connectionArgs) (*connection[*walletResolver], error) {
+	return offsetConnection(resolvers(p.wallets, func(wallet *models.GlobalWallet) *walletResolver { return &walletResolver{wallet} }), args)
+}
+
+func (p *portfolioResolver) ValueUsd(ctx context.Context) (decimalScalar, error) {
+	value, err := portfolioValue(ctx, p.wallets)
+	if err != nil {
+		return decimalScalar{}, internalError(ctx, err)
+	}
+
+	return decimalScalar{value}, nil
+}
+
+func (p *portfolioResolver) Missing() []string {
+	return p.missing
+}
+
+func (p *priceResolver) Asset() string {
+	return p.feed.Name
+}
+
+func (p *priceResolver) Currency() string {
+	return p.feed.Currency
+}
+
+func (p *priceResolver) Price() decimalScalar {
+	return decimalScalar{p.feed.Price}
+}
+
+func (p *priceResolver) UpdatedAt() timeScalar {
+	return timeScalar{p.feed.UpdatedAt}
+}
+
+// History pages the price history of the feed, the cursors holding the time of the last price of the page.
+func (p *priceResolver) History(ctx context.Context, args connectionArgs) (*connection[*pricePointResolver], error) {
+	if err := spend(ctx, historyCost); err != nil {
+		return nil, err
+	}
+
+	db := requestOf(ctx).db
+
+	first, after, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	var before *time.Time
+	if after != "" {
+		value, ok := decodeCursor("pricedAt", after)
+		pricedAt, err := time.Parse(time.RFC3339Nano, value)
+		if !ok || err != nil {
+			return nil, badInput("invalid cursor " + after)
+		}
+		before = &pricedAt
+	}
+
+	points := make([]*models.CoingeckoPriceHistory, 0)
+	if first > 0 {
+		// one more than the page tells whether there is a next page
+		if points, err = models.GetCoingeckoPriceHistory(db, p.feed.Name, p.feed.Currency, before, first+1); err != nil {
+			return nil, internalError(ctx, err)
+		}
+	}
+
+	c := &connection[*pricePointResolver]{edges: make([]*edge[*pricePointResolver], 0, len(points)), pageInfo: &pageInfo{hasNextPage: len(points) > first, hasPreviousPage: before != nil}}
+	for i, point := range points {
+		if i == first {
+			break
+		}
+		c.edges = append(c.edges, &edge[*pricePointResolver]{cursor: encodeCursor("pricedAt", point.PricedAt.UTC().Format(time.RFC3339Nano)), node: &pricePointResolver{point}})
+	}
+	c.setCursors()
+
+	feed := p.feed
+	c.count = func() (int, error) {
+		count, err := models.CountCoingeckoPriceHistory(db, feed.Name, feed.Currency)
+		return int(count), err
+	}
+
+	return c, nil
+}
+
+func (p *pricePointResolver) Price() decimalScalar {
+	return decimalScalar{p.point.Price}
+}
+
+func (p *pricePointResolver) PricedAt() timeScalar {
+	return timeScalar{p.point.PricedAt}
+}
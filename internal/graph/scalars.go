@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// chainValues maps wallet blockchain types to the values of the Chain enum, the chain families of REST.
+var chainValues = map[string]string{
+	utils.Bitcoin: "BTC",
+	utils.Solana:  "SOL",
+	utils.Debank:  "EVM",
+}
+
+type (
+	// decimalScalar is the Decimal scalar, an exact decimal number serialized as a string so that clients do
+	// not round it.
+	decimalScalar struct {
+		decimal.Decimal
+	}
+
+	// timeScalar is the Time scalar, a time in RFC 3339 format, in UTC.
+	timeScalar struct {
+		time.Time
+	}
+)
+
+func (decimalScalar) ImplementsGraphQLType(name string) bool {
+	return name == "Decimal"
+}
+
+func (d *decimalScalar) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		s = fmt.Sprint(input)
+	}
+
+	value, err := decimal.NewFromString(s)
+	if err != nil {
+		return er.New("expected a decimal number")
+	}
+	d.Decimal = value
+
+	return nil
+}
+
+func (d decimalScalar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (timeScalar) ImplementsGraphQLType(name string) bool {
+	return name == "Time"
+}
+
+func (t *timeScalar) UnmarshalGraphQL(input interface{}) error {
+	s, _ := input.(string)
+
+	value, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return er.New("expected a time in RFC 3339 format")
+	}
+	t.Time = value
+
+	return nil
+}
+
+func (t timeScalar) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.UTC().Format(time.RFC3339))
+}
+
+// optionalDecimal returns d as a nullable Decimal.
+func optionalDecimal(d *decimal.Decimal) *decimalScalar {
+	if d == nil {
+		return nil
+	}
+
+	return &decimalScalar{*d}
+}
+
+// optionalString returns s as a nullable String, null when empty.
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"context"
+	_ "embed"
+	er "errors"
+	"strconv"
+	"sync"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/dataloader"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// MaxDepth bounds the nesting of queries, deep enough for every path of the schema.
+const MaxDepth = 10
+
+// MaxComplexity bounds the cost a query spends on the fields that resolve names or load more than their
+// source: room for a few portfolios with every field of their wallets, not for the same fields aliased over
+// and over. The fields resolved once it is spent fail.
+const MaxComplexity = 200
+
+// Costs of the fields that resolve names or load more than their source, each time they are resolved.
+const (
+	historyCost   = 1  // a page of the history of a price
+	loadCost      = 5  // a stored wallet, name, list of prices or alert rules loaded by key
+	portfolioCost = 20 // up to MAX_ADDRESSES_PER_REQUEST names resolved and wallets loaded
+)
+
+// maxParallelism bounds the resolvers of a query run at once.
+const maxParallelism = 10
+
+//go:embed schema.graphql
+var schemaDefinition string
+
+// NewSchema returns the schema queries are executed against, with WithRequest contexts. It panics when the
+// resolvers do not match the definition, which is a bug.
+func NewSchema() *graphql.Schema {
+	return graphql.MustParseSchema(schemaDefinition, &query{},
+		graphql.MaxDepth(MaxDepth),
+		graphql.MaxParallelism(maxParallelism),
+		graphql.Logger(panicLogger{}),
+	)
+}
+
+// query resolves the fields of the Query type.
+type query struct{}
+
+func (*query) Me(ctx context.Context) (*userResolver, error) {
+	r := requestOf(ctx)
+	if r.userID == 0 {
+		return nil, nil
+	}
+
+	user, err := models.GetUserById(r.db, r.userID)
+	if er.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return &userResolver{user}, nil
+}
+
+func (*query) Wallet(ctx context.Context, args struct{ Address string }) (*walletResolver, error) {
+	if err := spend(ctx, loadCost); err != nil {
+		return nil, err
+	}
+
+	_, address, err := resolveAddress(ctx, args.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := requestOf(ctx).wallets.Load(ctx, address)
+	if er.Is(err, dataloader.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	return &walletResolver{wallet}, nil
+}
+
+func (*query) Portfolio(ctx context.Context, args struct{ Addresses []string }) (*portfolioResolver, error) {
+	if err := spend(ctx, portfolioCost); err != nil {
+		return nil, err
+	}
+
+	if max := configs.EnvConfigVars.MaxAddressesPerRequest; len(args.Addresses) > max {
+		return nil, badInput("too many addresses: at most " + strconv.Itoa(max) + " per portfolio")
+	}
+
+	addresses := make([]string, 0, len(args.Addresses))
+	for _, input := range args.Addresses {
+		_, address, err := resolveAddress(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+	addresses = utils.UniqueAddress(addresses)
+
+	stored, err := models.GetWalletsByAddresses(requestOf(ctx).db, addresses)
+	if err != nil {
+		return nil, internalError(ctx, err)
+	}
+
+	byAddress := make(map[string]*models.GlobalWallet, len(stored))
+	for _, wallet := range stored {
+		byAddress[wallet.WalletAddress] = wallet
+	}
+
+	result := &portfolioResolver{wallets: make([]*models.GlobalWallet, 0, len(stored)), missing: make([]string, 0)}
+	for _, address := range addresses {
+		if wallet, ok := byAddress[address]; ok {
+			result.wallets = append(result.wallets, wallet)
+		} else {
+			result.missing = append(result.missing, address)
+		}
+	}
+
+	return result, nil
+}
+
+func (*query) Prices(ctx context.Context, args struct{ Assets *[]string }) ([]*priceResolver, error) {
+	if err := spend(ctx, loadCost); err != nil {
+		return nil, err
+	}
+
+	r := requestOf(ctx)
+
+	if args.Assets == nil {
+		feeds, err := models.GetCoingeckoPriceFeeds(r.db)
+		if err != nil {
+			return nil, internalError(ctx, err)
+		}
+
+		list := make([]*priceResolver, 0, len(feeds))
+		for i := range feeds {
+			list = append(list, &priceResolver{&feeds[i]})
+		}
+		return list, nil
+	}
+
+	// assets not priced are left out
+	feeds, errs := r.prices.LoadMany(ctx, *args.Assets)
+
+	list := make([]*priceResolver, 0, len(feeds))
+	for i, feed := range feeds {
+		if errs != nil && errs[i] != nil {
+			if er.Is(errs[i], dataloader.ErrNotFound) {
+				continue
+			}
+			return nil, internalError(ctx, errs[i])
+		}
+		list = append(list, &priceResolver{feed})
+	}
+
+	return list, nil
+}
+
+// portfolioValue returns the usd value of wallets, loading their assets concurrently so that they are batched.
+func portfolioValue(ctx context.Context, wallets []*models.GlobalWallet) (decimal.Decimal, error) {
+	values := make([]decimal.Decimal, len(wallets))
+	errs := make([]error, len(wallets))
+
+	var wg sync.WaitGroup
+	wg.Add(len(wallets))
+	for i, wallet := range wallets {
+		go func(i int, wallet *models.GlobalWallet) {
+			defer wg.Done()
+
+			loaded, err := loadAssets(ctx, wallet)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			values[i], errs[i] = walletValue(ctx, loaded)
+		}(i, wallet)
+	}
+	wg.Wait()
+
+	total := decimal.Zero
+	for i, value := range values {
+		if errs[i] != nil {
+			return decimal.Zero, errs[i]
+		}
+		total = total.Add(value)
+	}
+
+	return total, nil
+}
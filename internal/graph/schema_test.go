@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+)
+
+const testAddress = "0x71c7656ec7ab88b098defb751b7401b5f6d8976f"
+
+// testContext returns the context of a request whose queries are built but not run, so nothing is stored.
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+
+	previous := configs.EnvConfigVars
+	configs.EnvConfigVars = &configs.EnvConfigs{MaxAddressesPerRequest: 20}
+	t.Cleanup(func() { configs.EnvConfigVars = previous })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() = %v", err)
+	}
+
+	return WithRequest(context.Background(), db, 0)
+}
+
+func aliased(n int, field string) string {
+	var b strings.Builder
+	b.WriteString("{ ")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "a%d: %s ", i, field)
+	}
+	b.WriteString("}")
+
+	return b.String()
+}
+
+func TestSchema(t *testing.T) {
+	schema := NewSchema()
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		wantData  string
+		wantError string
+		wantCode  string
+	}{
+		{
+			name:      "decimals are strings",
+			query:     `query ($addresses: [String!]!) { portfolio(addresses: $addresses) { valueUsd missing wallets { totalCount nodes { address } pageInfo { hasNextPage endCursor } } } }`,
+			wantData:  `{"portfolio":{"valueUsd":"0","missing":["` + testAddress + `"],"wallets":{"totalCount":0,"nodes":[],"pageInfo":{"hasNextPage":false,"endCursor":null}}}}`,
+			variables: map[string]interface{}{"addresses": []interface{}{testAddress, "0x" + strings.ToUpper(testAddress[2:])}},
+		},
+		{name: "anonymous callers are not users", query: `{ me { id } }`, wantData: `{"me":null}`},
+		{name: "unknown wallet", query: `{ wallet(address: "` + testAddress + `") { address } }`, wantData: `{"wallet":null}`},
+		{name: "page too large", query: `{ portfolio(addresses: []) { wallets(first: 101) { totalCount } } }`, wantError: "first must be between 0 and 100", wantCode: "validation"},
+		{name: "invalid cursor", query: `{ portfolio(addresses: []) { wallets(after: "nope") { totalCount } } }`, wantError: "invalid cursor", wantCode: "validation"},
+		{name: "too many addresses", query: `{ portfolio(addresses: [` + strings.Repeat(`"`+testAddress+`", `, 21) + `]) { valueUsd } }`, wantError: "too many addresses", wantCode: "validation"},
+		{name: "aliased portfolios", query: aliased(11, `portfolio(addresses: ["`+testAddress+`"]) { valueUsd }`), wantError: "more complex than 200", wantCode: "validation"},
+		{name: "aliased wallets", query: aliased(41, `wallet(address: "`+testAddress+`") { address }`), wantError: "more complex than 200", wantCode: "validation"},
+		{name: "too deep", query: `{ __schema { types { fields { type { ofType { ofType { ofType { ofType { ofType { ofType { name } } } } } } } } } } }`, wantError: "exceeds max depth 10"},
+		{name: "mutations", query: `mutation { me { id } }`, wantError: "no mutations"},
+		{name: "unknown field", query: `{ wallets { id } }`, wantError: `Cannot query field "wallets"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := schema.Exec(testContext(t), tt.query, "", tt.variables)
+
+			if tt.wantError == "" {
+				if len(response.Errors) > 0 {
+					t.Fatalf("Exec() = %v", response.Errors)
+				}
+				if string(response.Data) != tt.wantData {
+					t.Errorf("data = %s, want %s", response.Data, tt.wantData)
+				}
+				return
+			}
+
+			if len(response.Errors) == 0 {
+				t.Fatalf("Exec() = %s, want an error containing %q", response.Data, tt.wantError)
+			}
+			err := response.Errors[0]
+			if !strings.Contains(err.Message, tt.wantError) {
+				t.Errorf("error = %q, want it to contain %q", err.Message, tt.wantError)
+			}
+			if code, _ := json.Marshal(err.Extensions["code"]); tt.wantCode != "" && string(code) != `"`+tt.wantCode+`"` {
+				t.Errorf("extensions.code = %s, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestOffsetConnection(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	first := func(n int32) *int32 { return &n }
+
+	page, err := offsetConnection(items, connectionArgs{First: first(2)})
+	if err != nil {
+		t.Fatalf("offsetConnection() = %v", err)
+	}
+	if fmt.Sprint(page.Nodes()) != "[1 2]" || !page.PageInfo().HasNextPage() || page.PageInfo().HasPreviousPage() {
+		t.Fatalf("first page = %v, %+v", page.Nodes(), page.pageInfo)
+	}
+
+	page, err = offsetConnection(items, connectionArgs{First: first(10), After: page.PageInfo().EndCursor()})
+	if err != nil {
+		t.Fatalf("offsetConnection() = %v", err)
+	}
+	if fmt.Sprint(page.Nodes()) != "[3 4 5]" || page.PageInfo().HasNextPage() || !page.PageInfo().HasPreviousPage() {
+		t.Errorf("next page = %v, %+v", page.Nodes(), page.pageInfo)
+	}
+	if total, _ := page.TotalCount(context.Background()); total != 5 {
+		t.Errorf("totalCount = %d, want 5", total)
+	}
+
+	page, _ = offsetConnection(items, connectionArgs{})
+	if len(page.Nodes()) != 5 || page.PageInfo().StartCursor() == nil {
+		t.Errorf("default page = %v, want every item", page.Nodes())
+	}
+
+	other := encodeCursor("pricedAt", "2")
+	if _, err := offsetConnection(items, connectionArgs{After: &other}); err == nil {
+		t.Error("offsetConnection() accepted the cursor of another list")
+	}
+}
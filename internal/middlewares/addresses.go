@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// MaxAddresses rejects requests carrying more than max wallet addresses, counted from the comma separated
+// `addresses` query parameter and from every list of strings in a JSON body. A max of 0 disables the check.
+// JSON bodies larger than utils.MaxBodySize are rejected with a 413.
+func MaxAddresses(max int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if max <= 0 {
+			c.Next()
+			return
+		}
+
+		count := 0
+		for _, address := range strings.Split(c.Query("addresses"), ",") {
+			if strings.TrimSpace(address) != "" {
+				count++
+			}
+		}
+
+		if c.Request.Body != nil && strings.HasPrefix(c.ContentType(), "application/json") {
+			body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, utils.MaxBodySize))
+			var tooLarge *http.MaxBytesError
+			if er.As(err, &tooLarge) {
+				errors.HandleHttpError(c, errors.NewHttpError(http.StatusRequestEntityTooLarge, "the request is larger than 1 MiB"))
+				c.Abort()
+				return
+			}
+			if err != nil {
+				errors.HandleHttpError(c, errors.NewBadRequestError("error reading request body"))
+				c.Abort()
+				return
+			}
+			// restore the body for the handler
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+			count += countJSONAddresses(body)
+		}
+
+		if count > max {
+			errors.HandleHttpError(c, errors.NewBadRequestError(fmt.Sprintf("too many addresses: %d given, at most %d allowed per request", count, max)))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// countJSONAddresses counts the strings held in the top level lists of a JSON object.
+func countJSONAddresses(body []byte) int {
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		// malformed bodies are reported by the handler
+		return 0
+	}
+
+	count := 0
+	for _, raw := range fields {
+		var list []string
+		if err := json.Unmarshal(raw, &list); err == nil {
+			count += len(list)
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,61 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+func TestMaxAddresses(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name     string
+		max      int
+		target   string
+		body     string
+		want     int
+		wantBody string
+	}{
+		{name: "query under the limit", max: 2, target: "/?addresses=a,b", want: http.StatusOK},
+		{name: "query over the limit", max: 2, target: "/?addresses=a,b,c", want: http.StatusBadRequest},
+		{name: "empty entries are not counted", max: 2, target: "/?addresses=a,,b,", want: http.StatusOK},
+		{name: "body lists are counted", max: 3, target: "/", body: `{"btc":["a","b"],"sol":["c","d"]}`, want: http.StatusBadRequest},
+		{name: "body is kept for the handler", max: 3, target: "/", body: `{"btc":["a"],"sol":["b"]}`, want: http.StatusOK, wantBody: `{"btc":["a"],"sol":["b"]}`},
+		{name: "malformed bodies are left to the handler", max: 1, target: "/", body: `{"btc":`, want: http.StatusOK, wantBody: `{"btc":`},
+		{name: "no limit", max: 0, target: "/?addresses=a,b,c", want: http.StatusOK},
+		{name: "body over 1 MiB", max: 2, target: "/", body: `{"btc":["` + strings.Repeat("a", utils.MaxBodySize) + `"]}`, want: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var handled string
+
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.POST("/", MaxAddresses(tt.max), func(c *gin.Context) {
+				body, _ := io.ReadAll(c.Request.Body)
+				handled = string(body)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if handled != tt.wantBody {
+				t.Errorf("handler read %q, want %q", handled, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// dryRunDB returns a database whose queries are built but not run: users and api keys load as zero values,
+// so every well formed token and api key is valid.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() = %v", err)
+	}
+
+	return db
+}
+
+func setupConfig(t *testing.T) {
+	t.Helper()
+
+	previous := configs.EnvConfigVars
+	configs.EnvConfigVars = &configs.EnvConfigs{Secret: "test-secret", AccessTokenTTL: time.Hour}
+	t.Cleanup(func() { configs.EnvConfigVars = previous })
+}
+
+// caller is what a handler sees of the caller authenticated on its context.
+type caller struct {
+	userID int
+	user   bool
+	apiKey bool
+}
+
+func serve(router *gin.Engine, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestAuthenticate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupConfig(t)
+	db := dryRunDB(t)
+
+	token, err := utils.GenerateAccessToken(7, "user@example.com", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		required bool
+		target   string
+		headers  map[string]string
+		want     int
+		caller   caller
+	}{
+		{name: "anonymous", target: "/", want: http.StatusOK},
+		{name: "anonymous when required", required: true, target: "/", want: http.StatusUnauthorized},
+		{name: "bearer token", target: "/", headers: map[string]string{"Authorization": "Bearer " + token}, want: http.StatusOK, caller: caller{userID: 7, user: true}},
+		{name: "invalid bearer token", target: "/", headers: map[string]string{"Authorization": "Bearer nope"}, want: http.StatusUnauthorized},
+		{name: "invalid bearer token is not retried as an api key", target: "/", headers: map[string]string{"Authorization": "Bearer nope", "x-api-key": "0xb_key"}, want: http.StatusUnauthorized},
+		{name: "api key", target: "/", headers: map[string]string{"x-api-key": "0xb_key"}, want: http.StatusOK, caller: caller{user: true, apiKey: true}},
+		{name: "bearer token wins over api key", target: "/", headers: map[string]string{"Authorization": "Bearer " + token, "x-api-key": "0xb_key"}, want: http.StatusOK, caller: caller{userID: 7, user: true}},
+		{name: "other schemes are anonymous", target: "/", headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, want: http.StatusOK},
+		{name: "query token of a websocket", target: "/?access_token=" + token, headers: map[string]string{"Connection": "Upgrade", "Upgrade": "websocket"}, want: http.StatusOK, caller: caller{userID: 7, user: true}},
+		{name: "query token of a plain request is ignored", target: "/?access_token=" + token, want: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got caller
+
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", Authenticate(db, tt.required), func(c *gin.Context) {
+				_, got.user = c.Get(utils.ContextUserID)
+				got.userID = c.GetInt(utils.ContextUserID)
+				_, got.apiKey = CurrentAPIKey(c)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			for key, value := range tt.headers {
+				req.Header.Set(key, value)
+			}
+
+			if w := serve(router, req); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if got != tt.caller {
+				t.Errorf("caller = %+v, want %+v", got, tt.caller)
+			}
+		})
+	}
+}
+
+func TestRequireCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	user := func(c *gin.Context) { c.Set(utils.ContextUserID, 7) }
+	apiKey := func(scopes string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(utils.ContextUserID, 7)
+			c.Set(utils.ContextAPIKey, &models.APIKey{APIKeyID: 3, UserID: 7, Scopes: scopes})
+		}
+	}
+	anonymous := func(c *gin.Context) {}
+
+	tests := []struct {
+		name    string
+		caller  gin.HandlerFunc
+		require gin.HandlerFunc
+		want    int
+	}{
+		{"user of a user endpoint", user, RequireUser(), http.StatusOK},
+		{"api key of a user endpoint", apiKey(utils.ScopeWalletsRead), RequireUser(), http.StatusForbidden},
+		{"anonymous of a user endpoint", anonymous, RequireUser(), http.StatusUnauthorized},
+		{"user of an authenticated endpoint", user, RequireAuth(), http.StatusOK},
+		{"api key of an authenticated endpoint", apiKey(""), RequireAuth(), http.StatusOK},
+		{"anonymous of an authenticated endpoint", anonymous, RequireAuth(), http.StatusUnauthorized},
+		{"api key with the scope", apiKey(utils.ScopePortfolioRead + "," + utils.ScopeWalletsRead), RequireScope(utils.ScopeWalletsRead), http.StatusOK},
+		{"api key without the scope", apiKey(utils.ScopePortfolioRead), RequireScope(utils.ScopeWalletsRead), http.StatusForbidden},
+		{"user of a scoped endpoint", user, RequireScope(utils.ScopeWalletsRead), http.StatusOK},
+		{"anonymous of a scoped endpoint", anonymous, RequireScope(utils.ScopeWalletsRead), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", tt.caller, tt.require, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			if w := serve(router, httptest.NewRequest(http.MethodGet, "/", nil)); w.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+		})
+	}
+}
+
+func TestRequireToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		authorization string
+		want          int
+	}{
+		{"operator token", "Bearer s3cret", http.StatusOK},
+		{"other token", "Bearer s3cret2", http.StatusUnauthorized},
+		{"token without scheme", "s3cret", http.StatusUnauthorized},
+		{"no token", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(ErrorHandler())
+			router.GET("/", RequireToken("s3cret"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Authorization", tt.authorization)
+
+			if w := serve(router, req); w.Code != tt.want {
+				t.Errorf("status = %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
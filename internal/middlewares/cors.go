@@ -8,7 +8,8 @@ import (
 func CORSMiddleware() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		AllowAllOrigins: true,
-		AllowMethods:    []string{"GET", "POST"},
-		AllowHeaders:    []string{"Origin", "Content-Type", "x-api-key", "AccessKey"}, // x-api-key for solana and AccessKey for debank
+		AllowMethods:    []string{"GET", "POST", "DELETE"},
+		AllowHeaders:    []string{"Origin", "Content-Type", "Authorization", "x-api-key", "AccessKey"}, // Authorization for user tokens, x-api-key for partner api keys
+		ExposeHeaders:   []string{"Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset"},
 	})
 }
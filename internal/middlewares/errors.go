@@ -1,29 +1,52 @@
 package middlewares
 
 import (
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
 	"github.com/gin-gonic/gin"
 )
 
-// ErrorHandler is a middleware for handling errors and responding with appropriate JSON.
+// ErrorHandler is a middleware for handling errors and responding with an RFC 7807 problem document.
 func ErrorHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
 
-		for _, err := range c.Errors {
+		// a single response can be sent, the last error is the one that ended the request
+		if err := c.Errors.Last(); err != nil && !c.Writer.Written() {
 			handleError(c, err)
 		}
 	}
 }
 
-// handleError handles a specific error, sending the appropriate JSON response.
+// handleError handles a specific error, sending the appropriate problem. Provider and unexpected errors are
+// logged, client errors are only visible in the request log line.
 func handleError(c *gin.Context, err *gin.Error) {
 	switch e := err.Err.(type) {
 	case *errors.APIError:
-		c.AbortWithStatusJSON(e.Code, e)
+		if e.Code >= http.StatusInternalServerError {
+			slog.ErrorContext(c.Request.Context(), "request failed", slog.Any("error", e))
+		}
+		AbortWithProblem(c, e)
+	case *errors.UpstreamError:
+		slog.WarnContext(c.Request.Context(), "provider failed", slog.String("provider", e.Provider), slog.Any("error", e))
+		AbortWithProblem(c, e.APIError())
 	default:
-		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Service Unavailable"})
+		slog.ErrorContext(c.Request.Context(), "request failed", slog.Any("error", err.Err))
+		AbortWithProblem(c, errors.NewInternalServerError("internal error"))
 	}
 }
+
+// AbortWithProblem stops the request with the problem document of e.
+func AbortWithProblem(c *gin.Context, e *errors.APIError) {
+	if e.RetryAfter > 0 {
+		c.Header("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+
+	// render.JSON keeps a content type already set
+	c.Header("Content-Type", errors.ProblemContentType)
+	c.AbortWithStatusJSON(e.Code, e.Problem(c.Request.URL.Path, logging.RequestID(c.Request.Context())))
+}
@@ -0,0 +1,86 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"runtime/debug"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+)
+
+// RequestIDHeader carries the request ID in requests and responses.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID accepts the IDs of callers that cannot be used to forge log lines.
+var validRequestID = regexp.MustCompile(`^[\w.:-]{1,128}$`)
+
+// RequestID gives every request an ID, the one of the X-Request-ID header when the caller sent a valid one,
+// echoes it in the response and puts it in the request context so that every log line of the request has it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := RequestIDOf(c.GetHeader(RequestIDHeader))
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		c.Next()
+	}
+}
+
+// Logger logs every request once it is served, at warn level for 5xx responses.
+func Logger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelWarn
+		}
+
+		slog.LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("duration", time.Since(start)),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Int("size", c.Writer.Size()),
+		)
+	}
+}
+
+// Recovery answers a 500 problem to a request whose handler panicked, logging the panic.
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered any) {
+		slog.ErrorContext(c.Request.Context(), "panic serving request", slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+		AbortWithProblem(c, errors.NewInternalError("internal server error", fmt.Errorf("panic: %v", recovered)))
+	})
+}
+
+// RequestIDOf returns the request ID a caller sent when it is valid, a new one otherwise.
+func RequestIDOf(sent string) string {
+	if validRequestID.MatchString(sent) {
+		return sent
+	}
+
+	return newRequestID()
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"caller id", "checkout-42:retry.1", true},
+		{"no id", "", false},
+		{"forged log line", "abc\nlevel=error", false},
+		{"too long", strings.Repeat("a", 129), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(RequestIDHeader, tt.header)
+
+			got := serve(router, req).Header().Get(RequestIDHeader)
+			if tt.keep && got != tt.header {
+				t.Errorf("request id = %q, want the caller's %q", got, tt.header)
+			}
+			if !tt.keep && (got == tt.header || len(got) != 32) {
+				t.Errorf("request id = %q, want a new one", got)
+			}
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(RequestID(), Recovery())
+	router.GET("/panic", func(c *gin.Context) { panic("token 0xb_0123456789abcdef leaked") })
+
+	req := httptest.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set(RequestIDHeader, "req-1")
+	w := serve(router, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, errors.ProblemContentType) {
+		t.Errorf("content type = %q, want %q", got, errors.ProblemContentType)
+	}
+
+	var problem errors.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatalf("body is not a problem: %v: %s", err, w.Body)
+	}
+	if problem.Status != http.StatusInternalServerError || problem.Instance != "/panic" || problem.RequestID != "req-1" {
+		t.Errorf("problem = %+v, want a 500 of /panic for request req-1", problem)
+	}
+	if strings.Contains(w.Body.String(), "0xb_") {
+		t.Errorf("the panic leaked to the client: %s", w.Body)
+	}
+}
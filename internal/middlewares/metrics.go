@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+)
+
+// Metrics records the count and latency of every request by route pattern. Requests matching no route are
+// grouped under "unmatched" so scanners cannot grow the label set.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
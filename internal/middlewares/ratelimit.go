@@ -0,0 +1,245 @@
+package middlewares
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type (
+	// RateLimitStore counts requests per key in fixed windows. Implementations must be safe for concurrent use.
+	RateLimitStore interface {
+		// Increment adds a hit to key in its current window and returns the hit count and when the window resets.
+		Increment(key string, window time.Duration) (count int, resetAt time.Time, err error)
+	}
+
+	// RouteLimit allows Requests per Window for every client identity.
+	RouteLimit struct {
+		Requests int
+		Window   time.Duration
+	}
+
+	// RateLimitConfig holds the default limit and per-route overrides keyed by "METHOD /full/path".
+	RateLimitConfig struct {
+		Default RouteLimit
+		Routes  map[string]RouteLimit
+	}
+
+	// MemoryRateLimitStore is the default in-process RateLimitStore.
+	MemoryRateLimitStore struct {
+		mu      sync.Mutex
+		windows map[string]*rateWindow
+		hits    int
+	}
+
+	rateWindow struct {
+		count   int
+		resetAt time.Time
+	}
+)
+
+// sweepEvery is how many increments the memory store handles between removals of expired windows.
+const sweepEvery = 1000
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{windows: make(map[string]*rateWindow)}
+}
+
+func (s *MemoryRateLimitStore) Increment(key string, window time.Duration) (int, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	s.hits++
+	if s.hits%sweepEvery == 0 {
+		for k, w := range s.windows {
+			if !now.Before(w.resetAt) {
+				delete(s.windows, k)
+			}
+		}
+	}
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &rateWindow{resetAt: now.Add(window)}
+		s.windows[key] = w
+	}
+	w.count++
+
+	return w.count, w.resetAt, nil
+}
+
+// IPRateLimiter limits requests per route for each client IP. It runs before Authenticate so that floods of
+// requests are rejected before their credentials are looked up. A request over its limit is rejected with
+// 429 and Retry-After.
+func IPRateLimiter(store RateLimitStore, config RateLimitConfig) gin.HandlerFunc {
+	return rateLimiter(store, config, func(c *gin.Context, routeLimit RouteLimit) map[string]RouteLimit {
+		return map[string]RouteLimit{"ip:" + c.ClientIP(): routeLimit}
+	})
+}
+
+// RateLimiter limits requests per route for the api key or the user the request was authenticated as. Keys
+// with their own rate limit use it instead of the route limit. A request over its limit is rejected with 429
+// and Retry-After. It must run after Authenticate.
+func RateLimiter(store RateLimitStore, config RateLimitConfig) gin.HandlerFunc {
+	return rateLimiter(store, config, callerLimits)
+}
+
+// rateLimiter limits requests per route for the identities returned by limits, reporting the tightest limit
+// in the X-RateLimit headers, along with those of the limiters that ran before.
+func rateLimiter(store RateLimitStore, config RateLimitConfig, limits func(c *gin.Context, routeLimit RouteLimit) map[string]RouteLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		routeLimit := config.Limit(route)
+
+		var (
+			reportedLimit = routeLimit.Requests
+			remaining     = math.MaxInt
+			resetAt       time.Time
+		)
+
+		for identity, limit := range limits(c, routeLimit) {
+			left, reset, ok := CountRequest(store, route, identity, limit)
+			if !ok {
+				continue
+			}
+
+			if left < remaining {
+				remaining = left
+				reportedLimit = limit.Requests
+				resetAt = reset
+			}
+		}
+
+		if remaining == math.MaxInt {
+			c.Next()
+			return
+		}
+
+		// a limiter that ran before reported a tighter limit
+		if reported, err := strconv.Atoi(c.Writer.Header().Get("X-RateLimit-Remaining")); err == nil && reported <= remaining {
+			c.Next()
+			return
+		}
+
+		c.Header("X-RateLimit-Limit", strconv.Itoa(reportedLimit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(utils.Ternary(remaining > 0, remaining, 0)))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+
+		if remaining < 0 {
+			errors.HandleHttpError(c, errors.NewRateLimitedError("rate limit exceeded", int(math.Ceil(time.Until(resetAt).Seconds()))))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Limit returns the limit of route, its override or the default limit.
+func (config RateLimitConfig) Limit(route string) RouteLimit {
+	if limit, ok := config.Routes[route]; ok {
+		return limit
+	}
+
+	return config.Default
+}
+
+// CountRequest counts a request to route by identity against limit. It returns how many requests identity
+// has left in the window, negative once it is over limit, and when the window resets. ok is false when the
+// request is not limited: limit allows any number of requests, or the counter store failed.
+func CountRequest(store RateLimitStore, route, identity string, limit RouteLimit) (remaining int, resetAt time.Time, ok bool) {
+	if limit.Requests <= 0 {
+		return 0, time.Time{}, false
+	}
+
+	count, resetAt, err := store.Increment(route+"|"+identity, limit.Window)
+	if err != nil {
+		// never take the API down because the counter store is unavailable
+		return 0, time.Time{}, false
+	}
+
+	return limit.Requests - count, resetAt, true
+}
+
+// callerLimits returns the api key or the user the request is counted against, with the limit that applies
+// to it. Anonymous requests are only counted by IP.
+func callerLimits(c *gin.Context, routeLimit RouteLimit) map[string]RouteLimit {
+	limits := make(map[string]RouteLimit, 1)
+
+	if apiKey, ok := CurrentAPIKey(c); ok {
+		limit := routeLimit
+		if apiKey.RateLimit > 0 {
+			limit = RouteLimit{Requests: apiKey.RateLimit, Window: time.Minute}
+		}
+
+		limits["key:"+strconv.Itoa(apiKey.APIKeyID)] = limit
+	} else if userID, ok := c.Get(utils.ContextUserID); ok {
+		limits["user:"+strconv.Itoa(userID.(int))] = routeLimit
+	}
+
+	return limits
+}
+
+// ParseRouteLimit parses a limit written as "<requests>/<window>", e.g. "60/1m".
+func ParseRouteLimit(value string) (RouteLimit, error) {
+	requests, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return RouteLimit{}, fmt.Errorf("invalid rate limit %q, expected <requests>/<window>", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil {
+		return RouteLimit{}, fmt.Errorf("invalid rate limit %q: %w", value, err)
+	}
+
+	d, err := time.ParseDuration(window)
+	if err != nil {
+		return RouteLimit{}, fmt.Errorf("invalid rate limit %q: %w", value, err)
+	}
+
+	return RouteLimit{Requests: n, Window: d}, nil
+}
+
+// ParseRateLimitConfig builds a RateLimitConfig from the default limit and a ";" separated list of
+// route overrides, e.g. "POST /api/v1/all-portfolio=10/1m;GET /api/v1/portfolio/btc=60/1m".
+func ParseRateLimitConfig(defaultLimit, routes string) (RateLimitConfig, error) {
+	config := RateLimitConfig{Routes: make(map[string]RouteLimit)}
+
+	if defaultLimit != "" {
+		limit, err := ParseRouteLimit(defaultLimit)
+		if err != nil {
+			return config, err
+		}
+		config.Default = limit
+	}
+
+	for _, entry := range strings.Split(routes, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return config, fmt.Errorf("invalid route rate limit %q, expected \"METHOD /path=<requests>/<window>\"", entry)
+		}
+
+		limit, err := ParseRouteLimit(value)
+		if err != nil {
+			return config, err
+		}
+
+		config.Routes[strings.Join(strings.Fields(route), " ")] = limit
+	}
+
+	return config, nil
+}
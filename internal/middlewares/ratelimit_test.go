@@ -0,0 +1,182 @@
+package middlewares
+
+import (
+	er "errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+
+	for i := 1; i <= 3; i++ {
+		count, _, err := store.Increment("GET /a|ip:1", time.Minute)
+		if err != nil || count != i {
+			t.Fatalf("Increment() = %d, %v, want %d", count, err, i)
+		}
+	}
+
+	// keys are counted apart
+	if count, _, _ := store.Increment("GET /a|ip:2", time.Minute); count != 1 {
+		t.Errorf("Increment() of another key = %d, want 1", count)
+	}
+
+	// a window starts over once it resets
+	count, resetAt, _ := store.Increment("GET /b|ip:1", 20*time.Millisecond)
+	if count != 1 || time.Until(resetAt) > 20*time.Millisecond {
+		t.Fatalf("Increment() = %d resetting at %v, want 1 within the window", count, resetAt)
+	}
+	time.Sleep(25 * time.Millisecond)
+	if count, _, _ := store.Increment("GET /b|ip:1", 20*time.Millisecond); count != 1 {
+		t.Errorf("Increment() after the window = %d, want 1", count)
+	}
+}
+
+// failingStore is a counter store that is unavailable.
+type failingStore struct{}
+
+func (failingStore) Increment(string, time.Duration) (int, time.Time, error) {
+	return 0, time.Time{}, er.New("store unavailable")
+}
+
+func TestCountRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		store     RateLimitStore
+		limit     RouteLimit
+		remaining []int // left after each request, nil when requests are not limited
+	}{
+		{"counts down past the limit", NewMemoryRateLimitStore(), RouteLimit{Requests: 2, Window: time.Minute}, []int{1, 0, -1}},
+		{"no limit", NewMemoryRateLimitStore(), RouteLimit{}, nil},
+		{"store unavailable", failingStore{}, RouteLimit{Requests: 2, Window: time.Minute}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				remaining, _, ok := CountRequest(tt.store, "GET /a", "ip:1", tt.limit)
+				if tt.remaining == nil {
+					if ok {
+						t.Fatalf("request %d limited, want unlimited", i+1)
+					}
+					continue
+				}
+				if !ok || remaining != tt.remaining[i] {
+					t.Fatalf("request %d = %d, %v, want %d", i+1, remaining, ok, tt.remaining[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseRateLimitConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		defaultLimit string
+		routes       string
+		want         RateLimitConfig
+		wantErr      bool
+	}{
+		{
+			name: "empty",
+			want: RateLimitConfig{Routes: map[string]RouteLimit{}},
+		},
+		{
+			name:         "default and routes",
+			defaultLimit: "60/1m",
+			routes:       "POST  /api/v1/all-portfolio=10/1m; GET /api/v1/portfolio/btc = 5/30s ;",
+			want: RateLimitConfig{
+				Default: RouteLimit{Requests: 60, Window: time.Minute},
+				Routes: map[string]RouteLimit{
+					"POST /api/v1/all-portfolio": {Requests: 10, Window: time.Minute},
+					"GET /api/v1/portfolio/btc":  {Requests: 5, Window: 30 * time.Second},
+				},
+			},
+		},
+		{name: "default without window", defaultLimit: "60", wantErr: true},
+		{name: "default with bad count", defaultLimit: "many/1m", wantErr: true},
+		{name: "default with bad window", defaultLimit: "60/minute", wantErr: true},
+		{name: "route without limit", routes: "GET /api/v1/portfolio/btc", wantErr: true},
+		{name: "route with bad limit", routes: "GET /api/v1/portfolio/btc=5", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRateLimitConfig(tt.defaultLimit, tt.routes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRateLimitConfig() = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRateLimitConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	config := RateLimitConfig{
+		Default: RouteLimit{Requests: 3, Window: time.Minute},
+		Routes:  map[string]RouteLimit{"GET /tight": {Requests: 1, Window: time.Minute}},
+	}
+
+	user := func(id int) gin.HandlerFunc {
+		return func(c *gin.Context) { c.Set(utils.ContextUserID, id) }
+	}
+	apiKey := func(id, rateLimit int) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set(utils.ContextUserID, 7)
+			c.Set(utils.ContextAPIKey, &models.APIKey{APIKeyID: id, UserID: 7, RateLimit: rateLimit})
+		}
+	}
+
+	tests := []struct {
+		name      string
+		path      string
+		ipLimit   RateLimitConfig
+		caller    gin.HandlerFunc
+		want      []int
+		remaining string
+	}{
+		{"route default", "/", config, user(1), []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, "0"},
+		{"route override", "/tight", config, user(1), []int{http.StatusOK, http.StatusTooManyRequests}, "0"},
+		{"own limit of an api key", "/", config, apiKey(1, 2), []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, "0"},
+		{"anonymous callers are limited by IP", "/", config, func(c *gin.Context) {}, []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, "0"},
+		{"tighter IP limit is reported", "/", RateLimitConfig{Default: RouteLimit{Requests: 10, Window: time.Minute}}, user(1), []int{http.StatusOK}, "2"},
+		{"no limit", "/", RateLimitConfig{}, user(1), []int{http.StatusOK, http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryRateLimitStore()
+
+			router := gin.New()
+			router.Use(ErrorHandler(), IPRateLimiter(store, tt.ipLimit), tt.caller, RateLimiter(store, config))
+			router.GET(tt.path, func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			var w *httptest.ResponseRecorder
+			for i, want := range tt.want {
+				w = serve(router, httptest.NewRequest(http.MethodGet, tt.path, nil))
+				if w.Code != want {
+					t.Fatalf("request %d = %d, want %d", i+1, w.Code, want)
+				}
+			}
+
+			if got := w.Header().Get("X-RateLimit-Remaining"); got != tt.remaining {
+				t.Errorf("X-RateLimit-Remaining = %q, want %q", got, tt.remaining)
+			}
+			if w.Code == http.StatusTooManyRequests && w.Header().Get("Retry-After") == "" {
+				t.Error("429 without Retry-After")
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Kinds of alert rules.
+const (
+	AlertPrice           = "price"            // the usd price of asset, a coingecko id, is above or below threshold
+	AlertPortfolioChange = "portfolio_change" // the value of the wallets rose or dropped threshold percent in window
+	AlertAllocation      = "allocation"       // the share of the chain asset in the value of the wallets is above or below threshold percent
+)
+
+type (
+	// AlertRule represents the alert_rules table, a condition a user is notified of on some channels.
+	AlertRule struct {
+		RuleID          int             `gorm:"primaryKey" json:"rule_id"`
+		UserID          int             `gorm:"not null" json:"user_id"`
+		Name            string          `gorm:"type:varchar(255);not null" json:"name"`
+		Kind            string          `gorm:"type:varchar(32);not null" json:"kind"`
+		Asset           string          `gorm:"type:varchar(255)" json:"asset"`
+		Direction       string          `gorm:"type:varchar(8);not null" json:"direction"` // above or below
+		Threshold       decimal.Decimal `gorm:"type:numeric;not null" json:"threshold"`
+		WindowSeconds   int             `json:"window_seconds"`
+		Wallets         string          `gorm:"type:text" json:"wallets"`  // comma separated chain:address
+		Channels        string          `gorm:"type:text" json:"channels"` // comma separated
+		WebhookID       *int            `json:"webhook_id"`
+		Email           string          `gorm:"type:varchar(255)" json:"email"`
+		CooldownSeconds int             `json:"cooldown_seconds"`
+		Enabled         bool            `json:"enabled"`
+		Triggered       bool            `json:"triggered"` // the condition held when the rule was last evaluated
+		LastTriggeredAt *time.Time      `json:"last_triggered_at"`
+		UpdatedAt       time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+		CreatedAt       time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	}
+
+	// AlertSnapshot represents the alert_snapshots table, the value of the wallets of a rule at some time.
+	AlertSnapshot struct {
+		SnapshotID int             `gorm:"primaryKey" json:"snapshot_id"`
+		RuleID     int             `gorm:"not null" json:"rule_id"`
+		Value      decimal.Decimal `gorm:"type:numeric;not null" json:"value"`
+		TakenAt    time.Time       `json:"taken_at"`
+	}
+
+	// AlertHistory represents the alert_history table, the alerts triggered by a rule.
+	AlertHistory struct {
+		HistoryID   int             `gorm:"primaryKey" json:"history_id"`
+		RuleID      int             `gorm:"not null" json:"rule_id"`
+		Value       decimal.Decimal `gorm:"type:numeric" json:"value"`
+		Message     string          `gorm:"type:text" json:"message"`
+		Delivered   string          `gorm:"type:text" json:"delivered"` // comma separated channels
+		Error       string          `gorm:"type:text" json:"error"`     // why the other channels failed
+		TriggeredAt time.Time       `json:"triggered_at"`
+	}
+)
+
+func (AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+func (AlertSnapshot) TableName() string {
+	return "alert_snapshots"
+}
+
+func (AlertHistory) TableName() string {
+	return "alert_history"
+}
+
+// ChannelList returns the channels of the rule as a slice.
+func (r *AlertRule) ChannelList() []string {
+	return splitList(r.Channels)
+}
+
+// WalletList returns the wallets of the rule as chain:address entries.
+func (r *AlertRule) WalletList() []string {
+	return splitList(r.Wallets)
+}
+
+// Window returns the period a portfolio_change rule compares the value over.
+func (r *AlertRule) Window() time.Duration {
+	return time.Duration(r.WindowSeconds) * time.Second
+}
+
+// Cooldown returns the time the rule stays quiet after it triggered.
+func (r *AlertRule) Cooldown() time.Duration {
+	return time.Duration(r.CooldownSeconds) * time.Second
+}
+
+// CoolingDown reports whether the rule triggered less than its cooldown before now.
+func (r *AlertRule) CoolingDown(now time.Time) bool {
+	return r.LastTriggeredAt != nil && now.Sub(*r.LastTriggeredAt) < r.Cooldown()
+}
+
+// CreateAlertRule creates a new alert rule
+func CreateAlertRule(tx *gorm.DB, rule *AlertRule) error {
+	return tx.Create(rule).Error
+}
+
+// GetAlertRules returns the alert rules of a user, newest first
+func GetAlertRules(tx *gorm.DB, userID int) ([]*AlertRule, error) {
+	rules := make([]*AlertRule, 0)
+
+	if err := tx.Where("user_id = ?", userID).Order("created_at DESC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// GetAlertRule returns an alert rule of a user
+func GetAlertRule(tx *gorm.DB, userID, ruleID int) (*AlertRule, error) {
+	rule := &AlertRule{}
+
+	if err := tx.Where("rule_id = ? AND user_id = ?", ruleID, userID).First(rule).Error; err != nil {
+		return nil, err
+	}
+
+	return rule, nil
+}
+
+// GetEnabledAlertRules returns the enabled alert rules of every user
+func GetEnabledAlertRules(tx *gorm.DB) ([]*AlertRule, error) {
+	rules := make([]*AlertRule, 0)
+
+	if err := tx.Where("enabled").Order("rule_id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+// UpdateAlertRule saves every field of a rule, its snapshots are dropped as the wallets may have changed and it
+// is evaluated afresh
+func UpdateAlertRule(tx *gorm.DB, rule *AlertRule) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		rule.UpdatedAt = time.Now().UTC()
+		rule.Triggered = false
+		if err := tx.Save(rule).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("rule_id = ?", rule.RuleID).Delete(&AlertSnapshot{}).Error
+	})
+}
+
+// DeleteAlertRule deletes a rule of a user with its snapshots and history
+func DeleteAlertRule(tx *gorm.DB, userID, ruleID int) error {
+	result := tx.Where("rule_id = ? AND user_id = ?", ruleID, userID).Delete(&AlertRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetAlertRuleTriggered records whether the condition of a rule holds and reports whether that changed. The
+// update only applies to a rule in the other state, so that a single evaluation sees a change, whichever
+// instance runs it
+func SetAlertRuleTriggered(tx *gorm.DB, rule *AlertRule, triggered bool) (bool, error) {
+	result := tx.Model(&AlertRule{}).Where("rule_id = ? AND triggered <> ?", rule.RuleID, triggered).Update("triggered", triggered)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	rule.Triggered = triggered
+
+	return result.RowsAffected > 0, nil
+}
+
+// SaveAlertSnapshot records the value of the wallets of a rule, and drops the snapshots taken before keepSince
+func SaveAlertSnapshot(tx *gorm.DB, snapshot *AlertSnapshot, keepSince time.Time) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("rule_id = ? AND taken_at < ?", snapshot.RuleID, keepSince).Delete(&AlertSnapshot{}).Error
+	})
+}
+
+// GetLatestAlertSnapshot returns the last snapshot of a rule, nil when there is none
+func GetLatestAlertSnapshot(tx *gorm.DB, ruleID int) (*AlertSnapshot, error) {
+	return findAlertSnapshot(tx.Where("rule_id = ?", ruleID).Order("taken_at DESC"))
+}
+
+// GetAlertSnapshotAt returns the last snapshot of a rule taken at or before at, or its first one when all were
+// taken after, nil when there is none
+func GetAlertSnapshotAt(tx *gorm.DB, ruleID int, at time.Time) (*AlertSnapshot, error) {
+	snapshot, err := findAlertSnapshot(tx.Where("rule_id = ? AND taken_at <= ?", ruleID, at).Order("taken_at DESC"))
+	if err != nil || snapshot != nil {
+		return snapshot, err
+	}
+
+	return findAlertSnapshot(tx.Where("rule_id = ?", ruleID).Order("taken_at"))
+}
+
+func findAlertSnapshot(query *gorm.DB) (*AlertSnapshot, error) {
+	snapshots := make([]*AlertSnapshot, 0, 1)
+
+	if err := query.Limit(1).Find(&snapshots).Error; err != nil {
+		return nil, err
+	}
+
+	if len(snapshots) == 0 {
+		return nil, nil
+	}
+
+	return snapshots[0], nil
+}
+
+// RecordAlertTriggered stores an alert in the history of its rule and starts the cooldown of the rule
+func RecordAlertTriggered(tx *gorm.DB, rule *AlertRule, history *AlertHistory) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(history).Error; err != nil {
+			return err
+		}
+
+		rule.LastTriggeredAt = &history.TriggeredAt
+
+		return tx.Model(rule).Update("last_triggered_at", history.TriggeredAt).Error
+	})
+}
+
+// GetAlertHistory returns the latest alerts of a rule, newest first
+func GetAlertHistory(tx *gorm.DB, ruleID, limit int) ([]*AlertHistory, error) {
+	history := make([]*AlertHistory, 0)
+
+	if err := tx.Where("rule_id = ?", ruleID).Order("history_id DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
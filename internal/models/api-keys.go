@@ -0,0 +1,112 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	// APIKey represents the api_keys table. Only the sha256 hash of a key is stored.
+	APIKey struct {
+		APIKeyID   int        `gorm:"primaryKey" json:"api_key_id"`
+		UserID     int        `gorm:"not null" json:"user_id"`
+		Name       string     `gorm:"type:varchar(255);not null" json:"name"`
+		Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"`
+		KeyHash    string     `gorm:"type:varchar(64);unique;not null" json:"-"`
+		Scopes     string     `gorm:"type:text" json:"scopes"` // comma separated
+		RateLimit  int        `gorm:"type:integer" json:"rate_limit"`
+		LastUsedAt *time.Time `json:"last_used_at"`
+		RevokedAt  *time.Time `json:"revoked_at"`
+		CreatedAt  time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	}
+)
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// ScopeList returns the key's scopes as a slice.
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRevoked reports whether the key was revoked.
+func (k *APIKey) IsRevoked() bool {
+	return k.RevokedAt != nil
+}
+
+// CreateAPIKey creates a new api key
+func CreateAPIKey(tx *gorm.DB, apiKey *APIKey) error {
+	if err := tx.Create(apiKey).Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAPIKeysByUserID returns every key of a user, newest first
+func GetAPIKeysByUserID(tx *gorm.DB, userID int) ([]*APIKey, error) {
+	apiKeys := make([]*APIKey, 0)
+
+	if err := tx.Where("user_id = ?", userID).Order("created_at DESC").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+
+	return apiKeys, nil
+}
+
+// GetAPIKeyByHash returns the key with the given sha256 hash
+func GetAPIKeyByHash(tx *gorm.DB, keyHash string) (*APIKey, error) {
+	apiKey := &APIKey{}
+
+	if err := tx.Where("key_hash = ?", keyHash).First(apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// RevokeAPIKey revokes a key owned by the user, returning gorm.ErrRecordNotFound if there is none
+func RevokeAPIKey(tx *gorm.DB, userID, apiKeyID int) (*APIKey, error) {
+	apiKey := &APIKey{}
+
+	if err := tx.Where("api_key_id = ? AND user_id = ?", apiKeyID, userID).First(apiKey).Error; err != nil {
+		return nil, err
+	}
+
+	if apiKey.RevokedAt == nil {
+		now := time.Now().UTC()
+		apiKey.RevokedAt = &now
+
+		if err := tx.Model(apiKey).Update("revoked_at", now).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return apiKey, nil
+}
+
+// TouchAPIKey records that the key was just used
+func TouchAPIKey(tx *gorm.DB, apiKey *APIKey) error {
+	now := time.Now().UTC()
+	apiKey.LastUsedAt = &now
+
+	return tx.Model(apiKey).Update("last_used_at", now).Error
+}
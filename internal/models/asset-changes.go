@@ -0,0 +1,61 @@
+package models
+
+import (
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+// Kinds of assets whose amount can change.
+const (
+	AssetCoin  = "coin"  // the native coin of a chain, btc or sol
+	AssetToken = "token" // a solana or evm token
+	AssetNFT   = "nft"   // a solana or evm nft, amounts are counts
+)
+
+type (
+	// AssetChange is an asset whose amount in a wallet differs between the stored row and the data saved over
+	// it. An asset that was not stored has a zero previous amount.
+	AssetChange struct {
+		Kind     string          `json:"kind"`
+		Asset    string          `json:"asset"` // btc, sol, the mint of a solana token or nft, chain:id of an evm token or nft
+		Symbol   string          `json:"symbol,omitempty"`
+		Previous decimal.Decimal `json:"previous"`
+		Current  decimal.Decimal `json:"current"`
+	}
+)
+
+// Received reports whether the wallet holds more of the asset than before.
+func (c AssetChange) Received() bool {
+	return c.Current.GreaterThan(c.Previous)
+}
+
+// assetAmount is the amount of an asset in stored or saved rows.
+type assetAmount struct {
+	symbol string
+	amount decimal.Decimal
+}
+
+// diffAssets returns the assets of kind whose amount differs between previous and current, by asset. Assets
+// missing from current are changed to zero when replaced is set, as their rows were deleted, and left out
+// otherwise.
+func diffAssets(kind string, previous, current map[string]assetAmount, replaced bool) []AssetChange {
+	changes := make([]AssetChange, 0)
+	for asset, now := range current {
+		before := previous[asset]
+		if before.amount.Equal(now.amount) {
+			continue
+		}
+
+		changes = append(changes, AssetChange{Kind: kind, Asset: asset, Symbol: now.symbol, Previous: before.amount, Current: now.amount})
+	}
+	for asset, before := range previous {
+		if _, ok := current[asset]; !ok && replaced && !before.amount.IsZero() {
+			changes = append(changes, AssetChange{Kind: kind, Asset: asset, Symbol: before.symbol, Previous: before.amount, Current: decimal.Zero})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Asset < changes[j].Asset })
+
+	return changes
+}
@@ -4,18 +4,19 @@ import (
 	"time"
 
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type (
 	// CoingeckoPriceFeed represents a coingecko price feed
 	CoingeckoPriceFeed struct {
-		ID        int       `gorm:"primaryKey" json:"id"` // database id
-		Name      string    `gorm:"type:varchar(255)" json:"name"`
-		Price     float64   `gorm:"type:float" json:"price"`
-		Currency  string    `gorm:"type:varchar(50)" json:"currency"`
-		UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-		CreatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+		ID        int             `gorm:"primaryKey" json:"id"` // database id
+		Name      string          `gorm:"type:varchar(255)" json:"name"`
+		Price     decimal.Decimal `gorm:"type:numeric" json:"price"`
+		Currency  string          `gorm:"type:varchar(50)" json:"currency"`
+		UpdatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+		CreatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	}
 )
 
@@ -70,6 +71,28 @@ func GetCoingeckoPriceFeedByName(tx *gorm.DB, name string) (*CoingeckoPriceFeed,
 	return &coingeckoPriceFeed, nil
 }
 
+// GetCoingeckoPriceFeeds returns every stored price feed.
+func GetCoingeckoPriceFeeds(tx *gorm.DB) ([]CoingeckoPriceFeed, error) {
+	feeds := make([]CoingeckoPriceFeed, 0)
+
+	if err := tx.Order("name").Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
+
+// GetCoingeckoPriceFeedsByNames returns the stored price feeds of names.
+func GetCoingeckoPriceFeedsByNames(tx *gorm.DB, names []string) ([]CoingeckoPriceFeed, error) {
+	feeds := make([]CoingeckoPriceFeed, 0, len(names))
+
+	if err := tx.Where("name IN ?", names).Find(&feeds).Error; err != nil {
+		return nil, err
+	}
+
+	return feeds, nil
+}
+
 // GetCoingeckoPriceFeedByID returns a CoingeckoPriceFeed by its id
 func GetCoingeckoPriceFeedByID(tx *gorm.DB, id int) (*CoingeckoPriceFeed, error) {
 	coingeckoPriceFeed := CoingeckoPriceFeed{}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	// CoingeckoPriceHistory is a daily coingecko price of a crypto.
+	CoingeckoPriceHistory struct {
+		ID        int             `gorm:"primaryKey" json:"id"`
+		Name      string          `gorm:"type:varchar(255)" json:"name"`
+		Currency  string          `gorm:"type:varchar(50)" json:"currency"`
+		Price     decimal.Decimal `gorm:"type:numeric" json:"price"`
+		PricedAt  time.Time       `json:"priced_at"`
+		CreatedAt time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	}
+)
+
+func (CoingeckoPriceHistory) TableName() string {
+	return "coingecko_price_history"
+}
+
+// SaveCoingeckoPriceHistory stores prices, replacing those already stored for the same crypto, currency and time.
+func SaveCoingeckoPriceHistory(tx *gorm.DB, prices []CoingeckoPriceHistory) error {
+	if len(prices) == 0 {
+		return nil
+	}
+
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "name"}, {Name: "currency"}, {Name: "priced_at"}},
+		DoUpdates: clause.AssignmentColumns([]string{"price"}),
+	}).CreateInBatches(prices, 500).Error
+}
+
+// GetCoingeckoPriceHistory returns at most limit prices of a crypto in currency, newest first, priced before
+// the given time when it is set.
+func GetCoingeckoPriceHistory(tx *gorm.DB, name, currency string, before *time.Time, limit int) ([]*CoingeckoPriceHistory, error) {
+	prices := make([]*CoingeckoPriceHistory, 0, limit)
+
+	query := tx.Where("name = ? AND currency = ?", name, currency)
+	if before != nil {
+		query = query.Where("priced_at < ?", *before)
+	}
+
+	if err := query.Order("priced_at DESC").Limit(limit).Find(&prices).Error; err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
+
+// CountCoingeckoPriceHistory returns the number of prices stored for a crypto in currency.
+func CountCoingeckoPriceHistory(tx *gorm.DB, name, currency string) (int64, error) {
+	var count int64
+
+	err := tx.Model(&CoingeckoPriceHistory{}).Where("name = ? AND currency = ?", name, currency).Count(&count).Error
+
+	return count, err
+}
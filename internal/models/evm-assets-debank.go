@@ -4,59 +4,60 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 type (
 	EvmAssetsDebankV1 struct {
-		EvmAssetID    int       `gorm:"primaryKey;autoIncrement" json:"evm_asset_id"` // Primary key
-		WalletID      int       `gorm:"not null;unique" json:"wallet_id"`             // Foreign key to global_wallets
-		TotalUsdValue float64   `gorm:"type:float" json:"total_usd_value"`
-		ChainListJson string    `gorm:"type:text" json:"chain_list_json"`
-		UpdatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-		CreatedAt     time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+		EvmAssetID    int             `gorm:"primaryKey;autoIncrement" json:"evm_asset_id"` // Primary key
+		WalletID      int             `gorm:"not null;unique" json:"wallet_id"`             // Foreign key to global_wallets
+		TotalUsdValue decimal.Decimal `gorm:"type:numeric" json:"total_usd_value"`
+		ChainListJson string          `gorm:"type:text" json:"chain_list_json"`
+		UpdatedAt     time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+		CreatedAt     time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 
 		TokenList *[]TokenList `gorm:"foreignKey:EvmAssetID" json:"token_list,omitempty"`
 		NFTList   *[]NFTList   `gorm:"foreignKey:EvmAssetID" json:"nft_list,omitempty"`
 	}
 
 	ChainDetails struct {
-		ChainID        int       `gorm:"primaryKey" json:"chian_id"`
-		ID             string    `gorm:"type:varchar(255)" json:"id"`
-		WalletID       int       `gorm:"not null" json:"wallet_id"`
-		CommunityID    uint64    `gorm:"type:integer" json:"community_id"`
-		Name           string    `gorm:"type:varchar(255)" json:"name"`
-		LogoURL        string    `gorm:"type:varchar(255)" json:"logo_url"`
-		NativeTokenID  string    `gorm:"type:varchar(255)" json:"native_token_id"`
-		WrappedTokenID string    `gorm:"type:varchar(255)" json:"wrapped_token_id"`
-		USDValue       float64   `gorm:"type:decimal(10,2)" json:"usd_value"`
-		UpdatedAt      time.Time `json:"updated_at" gorm:"default:current_timestamp"`
-		CreatedAt      time.Time `json:"created_at" gorm:"default:current_timestamp"`
+		ChainID        int             `gorm:"primaryKey" json:"chian_id"`
+		ID             string          `gorm:"type:varchar(255)" json:"id"`
+		WalletID       int             `gorm:"not null" json:"wallet_id"`
+		CommunityID    uint64          `gorm:"type:integer" json:"community_id"`
+		Name           string          `gorm:"type:varchar(255)" json:"name"`
+		LogoURL        string          `gorm:"type:varchar(255)" json:"logo_url"`
+		NativeTokenID  string          `gorm:"type:varchar(255)" json:"native_token_id"`
+		WrappedTokenID string          `gorm:"type:varchar(255)" json:"wrapped_token_id"`
+		USDValue       decimal.Decimal `gorm:"type:numeric" json:"usd_value"`
+		UpdatedAt      time.Time       `json:"updated_at" gorm:"default:current_timestamp"`
+		CreatedAt      time.Time       `json:"created_at" gorm:"default:current_timestamp"`
 	}
 
 	TokenList struct {
-		TokenID         int       `gorm:"primaryKey" json:"token_id"`  // database id
-		ID              string    `gorm:"type:varchar(255)" json:"id"` // token id
-		EvmAssetID      int       `gorm:"not null" json:"evm_asset_id"`
-		Chain           string    `gorm:"type:varchar(255)" json:"chain"`
-		Name            string    `gorm:"type:varchar(255)" json:"name"`
-		Symbol          string    `gorm:"type:varchar(255)" json:"symbol"`
-		DisplaySymbol   string    `gorm:"type:varchar(255)" json:"display_symbol"`
-		OptimizedSymbol string    `gorm:"type:varchar(255)" json:"optimized_symbol"`
-		Decimals        int       `gorm:"type:integer" json:"decimals"`
-		LogoURL         string    `gorm:"type:varchar(255)" json:"logo_url"`
-		ProtocolID      string    `gorm:"type:varchar(255)" json:"protocol_id"`
-		Price           float64   `gorm:"type:float" json:"price"`
-		Price24hChange  float64   `gorm:"type:float" json:"price_24h_change"`
-		IsVerified      bool      `gorm:"type:boolean" json:"is_verified"`
-		IsCore          bool      `gorm:"type:boolean" json:"is_core"`
-		IsWallet        bool      `gorm:"type:boolean" json:"is_wallet"`
-		TimeAt          float64   `gorm:"type:float" json:"time_at"`
-		Amount          float64   `gorm:"type:float" json:"amount"`
-		RawAmount       float64   `gorm:"type:numeric" json:"raw_amount"`
-		RawAmountHexStr string    `gorm:"type:varchar(255)" json:"raw_amount_hex_str"`
-		UpdatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-		CreatedAt       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+		TokenID         int             `gorm:"primaryKey" json:"token_id"`  // database id
+		ID              string          `gorm:"type:varchar(255)" json:"id"` // token id
+		EvmAssetID      int             `gorm:"not null" json:"evm_asset_id"`
+		Chain           string          `gorm:"type:varchar(255)" json:"chain"`
+		Name            string          `gorm:"type:varchar(255)" json:"name"`
+		Symbol          string          `gorm:"type:varchar(255)" json:"symbol"`
+		DisplaySymbol   string          `gorm:"type:varchar(255)" json:"display_symbol"`
+		OptimizedSymbol string          `gorm:"type:varchar(255)" json:"optimized_symbol"`
+		Decimals        int             `gorm:"type:integer" json:"decimals"`
+		LogoURL         string          `gorm:"type:varchar(255)" json:"logo_url"`
+		ProtocolID      string          `gorm:"type:varchar(255)" json:"protocol_id"`
+		Price           decimal.Decimal `gorm:"type:numeric" json:"price"`
+		Price24hChange  decimal.Decimal `gorm:"type:numeric" json:"price_24h_change"`
+		IsVerified      bool            `gorm:"type:boolean" json:"is_verified"`
+		IsCore          bool            `gorm:"type:boolean" json:"is_core"`
+		IsWallet        bool            `gorm:"type:boolean" json:"is_wallet"`
+		TimeAt          float64         `gorm:"type:float" json:"time_at"`
+		Amount          decimal.Decimal `gorm:"type:numeric" json:"amount"`
+		RawAmount       decimal.Decimal `gorm:"type:numeric" json:"raw_amount"`
+		RawAmountHexStr string          `gorm:"type:varchar(255)" json:"raw_amount_hex_str"`
+		UpdatedAt       time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+		CreatedAt       time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	}
 
 	NFTList struct {
@@ -77,7 +78,7 @@ type (
 		ContractName string          `gorm:"type:varchar(255)" json:"contract_name"`
 		IsERC1155    bool            `gorm:"type:boolean" json:"is_erc1155"`
 		Amount       int64           `gorm:"type:integer" json:"amount"`
-		USDPrice     float64         `gorm:"type:float" json:"usd_price"`
+		USDPrice     decimal.Decimal `gorm:"type:numeric" json:"usd_price"`
 		Attributes   json.RawMessage `gorm:"type:jsonb" json:"attributes"`
 		PayToken     json.RawMessage `gorm:"type:jsonb" json:"pay_token"`
 		UpdatedAt    time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
@@ -89,6 +90,16 @@ func (EvmAssetsDebankV1) TableName() string {
 	return "evm_assets_debank_v1"
 }
 
+// Quantity returns the exact token amount from the raw integer amount and decimals, falling back to
+// the amount debank reports when the raw amount is missing.
+func (t *TokenList) Quantity() decimal.Decimal {
+	if t.RawAmount.IsZero() {
+		return t.Amount
+	}
+
+	return t.RawAmount.Shift(-int32(t.Decimals))
+}
+
 func (ChainDetails) TableName() string {
 	return "chain_details"
 }
@@ -207,20 +218,28 @@ func SaveChainDetails(tx *gorm.DB, walletID int, chainDetails []*ChainDetails) e
 	return nil
 }
 
-func SaveTokenListByEvmAssetsDebankV1ID(tx *gorm.DB, evmAssetID int, tokens []*TokenList) error {
+// SaveTokenListByEvmAssetsDebankV1ID creates or updates the tokens of an evm asset and returns the changes of
+// their amounts over the stored ones. Tokens missing from tokens are left as stored.
+func SaveTokenListByEvmAssetsDebankV1ID(tx *gorm.DB, evmAssetID int, tokens []*TokenList) ([]AssetChange, error) {
 	// Find token list by evmAsset id
 	existingTokens, err := FindTokenListByEnvassID(tx, evmAssetID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	existingTokensByID := make(map[string]*TokenList)
+	previous := make(map[string]assetAmount)
 	for _, existingToken := range existingTokens {
 		existingTokensByID[existingToken.ID] = existingToken
+		previous[existingToken.Chain+":"+existingToken.ID] = assetAmount{existingToken.Symbol, existingToken.Amount}
 	}
 
+	current := make(map[string]assetAmount)
+
 	// Iterate through the provided token details
 	for _, token := range tokens {
+		current[token.Chain+":"+token.ID] = assetAmount{token.Symbol, token.Amount}
+
 		// Check if the token exists
 		existingToken, exists := existingTokensByID[token.ID]
 
@@ -228,35 +247,43 @@ func SaveTokenListByEvmAssetsDebankV1ID(tx *gorm.DB, evmAssetID int, tokens []*T
 			// If the token does not exist, create a new one
 			token.EvmAssetID = evmAssetID
 			if err := tx.Create(&token).Error; err != nil {
-				return err
+				return nil, err
 			}
 		} else {
 			// If the token exists, update its details
 			token.EvmAssetID = evmAssetID
 			token.TokenID = existingToken.TokenID
 			if err := tx.Model(&existingToken).Updates(token).Error; err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	return nil
+	return diffAssets(AssetToken, previous, current, false), nil
 }
 
-func SaveNFTSListByEvmAssetsDebankV1ID(tx *gorm.DB, evmAssetID int, nfts []*NFTList) error {
+// SaveNFTSListByEvmAssetsDebankV1ID creates or updates the nfts of an evm asset and returns the changes of
+// their amounts over the stored ones. NFTs missing from nfts are left as stored.
+func SaveNFTSListByEvmAssetsDebankV1ID(tx *gorm.DB, evmAssetID int, nfts []*NFTList) ([]AssetChange, error) {
 	// Find nft list by evmAsset id
 	existingNFTList, err := FindNFTSListByEnvassID(tx, evmAssetID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	existingNFTListByID := make(map[string]*NFTList)
+	previous := make(map[string]assetAmount)
 	for _, existingNFTList := range existingNFTList {
 		existingNFTListByID[existingNFTList.ID] = existingNFTList
+		previous[existingNFTList.Chain+":"+existingNFTList.ID] = assetAmount{existingNFTList.Name, decimal.NewFromInt(existingNFTList.Amount)}
 	}
 
+	current := make(map[string]assetAmount)
+
 	// Iterate through the provided nft details
 	for _, nft := range nfts {
+		current[nft.Chain+":"+nft.ID] = assetAmount{nft.Name, decimal.NewFromInt(nft.Amount)}
+
 		// Check if the nft exists
 		existingNFTList, exists := existingNFTListByID[nft.ID]
 
@@ -264,17 +291,17 @@ func SaveNFTSListByEvmAssetsDebankV1ID(tx *gorm.DB, evmAssetID int, nfts []*NFTL
 			// If the ntf does not exist, create a new one
 			nft.EvmAssetID = evmAssetID
 			if err := tx.Create(&nft).Error; err != nil {
-				return err
+				return nil, err
 			}
 		} else {
 			// If the nft exists, update its details
 			nft.EvmAssetID = evmAssetID
 			nft.NFTID = existingNFTList.NFTID
 			if err := tx.Model(&existingNFTList).Updates(nft).Error; err != nil {
-				return err
+				return nil, err
 			}
 		}
 	}
 
-	return nil
+	return diffAssets(AssetNFT, previous, current, false), nil
 }
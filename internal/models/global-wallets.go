@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -12,10 +13,12 @@ type GlobalWallet struct {
 	WalletID       int       `gorm:"primary_key" json:"wallet_id"`
 	PortfolioID    int       `gorm:"not null" json:"portfolio_id"`
 	WalletAddress  string    `gorm:"type:varchar(255);unique;not null" json:"wallet_address"`
+	Name           string    `gorm:"type:varchar(255)" json:"name,omitempty"` // ENS or SNS name
 	BlockchainType string    `gorm:"type:varchar(255);not null" json:"blockchain_type"`
 	APIEndpoint    string    `gorm:"type:text" json:"api_endpoint"`
 	APIVersion     string    `gorm:"type:varchar(50)" json:"api_version"`
 	LastUpdatedAt  time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"last_updated_at"`
+	Stale          bool      `gorm:"-" json:"stale,omitempty"` // served from storage because the provider could not be called
 
 	// relations use in json responses (optional)
 	SolanaAssetsMoralisV1 *SolanaAssetsMoralisV1 `gorm:"foreignKey:WalletID" json:"solana_assets_moralis_v1,omitempty"`
@@ -28,6 +31,38 @@ func (GlobalWallet) TableName() string {
 	return "global_wallets"
 }
 
+// ValueUSD returns the usd value of a wallet loaded with its chain info: the coin balance at the stored price
+// for bitcoin and solana wallets, whose tokens are not priced, and the debank total for evm wallets.
+func (w *GlobalWallet) ValueUSD() decimal.Decimal {
+	value := decimal.Zero
+
+	if btc := w.BitcoinBtcComV1; btc != nil && btc.BitcoinAddressInfo != nil && btc.CoingeckoPriceFeed != nil {
+		value = value.Add(btc.BitcoinAddressInfo.BalanceBTC().Mul(btc.CoingeckoPriceFeed.Price))
+	}
+
+	if sol := w.SolanaAssetsMoralisV1; sol != nil && sol.CoingeckoPriceFeed != nil {
+		value = value.Add(sol.Solana.Mul(sol.CoingeckoPriceFeed.Price))
+	}
+
+	if evm := w.EvmAssetsDebankV1; evm != nil {
+		value = value.Add(evm.TotalUsdValue)
+	}
+
+	return value
+}
+
+// GetGlobalWalletWithChainInfo returns the wallet of address with the info of chain, bitcoin, solana or debank.
+func GetGlobalWalletWithChainInfo(tx *gorm.DB, chain, address string) (*GlobalWallet, error) {
+	switch chain {
+	case utils.Bitcoin:
+		return GetGlobalWalletWithBitcoinInfo(tx, address)
+	case utils.Solana:
+		return GetGlobalWalletWithSolanaInfo(tx, address)
+	default:
+		return GetGlobalWalletWithEvmDebankInfo(tx, address)
+	}
+}
+
 // Get the btc data along with it relations based on btcAddress
 func GetGlobalWalletWithBitcoinInfo(tx *gorm.DB, btcAddress string) (*GlobalWallet, error) {
 	wallet := &GlobalWallet{}
@@ -49,7 +84,9 @@ func GetGlobalWalletWithBitcoinInfo(tx *gorm.DB, btcAddress string) (*GlobalWall
 	}
 
 	// Set the CoingeckoPriceFeed to the wallet
-	wallet.BitcoinBtcComV1.CoingeckoPriceFeed = coingeckoPriceFeed
+	if wallet.BitcoinBtcComV1 != nil {
+		wallet.BitcoinBtcComV1.CoingeckoPriceFeed = coingeckoPriceFeed
+	}
 
 	return wallet, nil
 }
@@ -75,7 +112,9 @@ func GetGlobalWalletWithSolanaInfo(tx *gorm.DB, solAddress string) (*GlobalWalle
 	}
 
 	// Set the CoingeckoPriceFeed to the wallet
-	wallet.SolanaAssetsMoralisV1.CoingeckoPriceFeed = coingeckoPriceFeed
+	if wallet.SolanaAssetsMoralisV1 != nil {
+		wallet.SolanaAssetsMoralisV1.CoingeckoPriceFeed = coingeckoPriceFeed
+	}
 
 	return wallet, nil
 }
@@ -109,6 +148,50 @@ func GetWallet(tx *gorm.DB, walletAddress string) (*GlobalWallet, error) {
 	return wallet, nil
 }
 
+// GetWalletsByAddresses returns the stored wallets of addresses, of any chain.
+func GetWalletsByAddresses(tx *gorm.DB, addresses []string) ([]*GlobalWallet, error) {
+	wallets := make([]*GlobalWallet, 0, len(addresses))
+
+	if err := tx.Where("wallet_address IN ?", addresses).Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+
+	return wallets, nil
+}
+
+// GetGlobalWalletsWithAssets returns the wallets of walletIDs with the info of their chain, tokens and NFTs
+// included, in one query per table whatever the number of wallets. The price feeds are not set.
+func GetGlobalWalletsWithAssets(tx *gorm.DB, walletIDs []int) ([]*GlobalWallet, error) {
+	wallets := make([]*GlobalWallet, 0, len(walletIDs))
+
+	err := tx.Where("wallet_id IN ?", walletIDs).
+		Preload("SolanaAssetsMoralisV1.Tokens", func(tx *gorm.DB) *gorm.DB { return tx.Order("token_id") }).
+		Preload("SolanaAssetsMoralisV1.NFTS", func(tx *gorm.DB) *gorm.DB { return tx.Order("nft_id") }).
+		Preload("BitcoinBtcComV1.BitcoinAddressInfo").
+		Preload("EvmAssetsDebankV1.TokenList", func(tx *gorm.DB) *gorm.DB { return tx.Order("token_id") }).
+		Preload("EvmAssetsDebankV1.NFTList", func(tx *gorm.DB) *gorm.DB { return tx.Order("nft_id") }).
+		Preload("ChainDetails").
+		Find(&wallets).Error
+
+	if err != nil {
+		return nil, err
+	}
+
+	return wallets, nil
+}
+
+// GetWalletsByBlockchainType returns every stored wallet of a chain, oldest update first.
+func GetWalletsByBlockchainType(tx *gorm.DB, blockchainType string) ([]*GlobalWallet, error) {
+	wallets := make([]*GlobalWallet, 0)
+
+	err := tx.Where("blockchain_type = ?", blockchainType).Order("last_updated_at").Find(&wallets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return wallets, nil
+}
+
 func UpdateWalletLastUpdateAt(tx *gorm.DB, wallet *GlobalWallet) (*GlobalWallet, error) {
 	now, err := utils.GetDBTime()
 	if err != nil {
@@ -124,6 +207,17 @@ func UpdateWalletLastUpdateAt(tx *gorm.DB, wallet *GlobalWallet) (*GlobalWallet,
 	return wallet, nil
 }
 
+// UpdateWalletName stores the ENS or SNS name of a wallet without touching its last update time.
+func UpdateWalletName(tx *gorm.DB, wallet *GlobalWallet, name string) error {
+	if err := tx.Model(wallet).UpdateColumn("name", name).Error; err != nil {
+		return err
+	}
+
+	wallet.Name = name
+
+	return nil
+}
+
 func CreateWallet(tx *gorm.DB, walletAddress, blockchainType string) (*GlobalWallet, error) {
 	wallet := &GlobalWallet{
 		WalletAddress:  walletAddress,
@@ -137,13 +231,16 @@ func CreateWallet(tx *gorm.DB, walletAddress, blockchainType string) (*GlobalWal
 	return wallet, nil
 }
 
-func GetOrCreateWallet(tx *gorm.DB, walletAddress, blockchainType string) (*GlobalWallet, error) {
-	wallet, err := GetWallet(tx, walletAddress)
+// GetOrCreateWallet returns the wallet of walletAddress, touching its last update, or creates it. created is
+// set when the wallet was not stored before.
+func GetOrCreateWallet(tx *gorm.DB, walletAddress, blockchainType string) (wallet *GlobalWallet, created bool, err error) {
+	wallet, err = GetWallet(tx, walletAddress)
 	if err == gorm.ErrRecordNotFound {
 		wallet, err = CreateWallet(tx, walletAddress, blockchainType)
+		created = true
 	} else {
 		wallet, err = UpdateWalletLastUpdateAt(tx, wallet)
 	}
 
-	return wallet, err
+	return wallet, created, err
 }
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	// ProviderUsage represents the provider_usage table, one row per provider per day.
+	ProviderUsage struct {
+		Provider  string    `gorm:"primaryKey;type:varchar(50)" json:"provider"`
+		UsageDate time.Time `gorm:"primaryKey;type:date" json:"usage_date"`
+		CallCount int64     `gorm:"type:bigint" json:"call_count"`
+		UpdatedAt time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+	}
+
+	// ProviderUsageStore persists provider call counts, see ratelimit.UsageStore.
+	ProviderUsageStore struct {
+		DB *gorm.DB
+	}
+)
+
+func (ProviderUsage) TableName() string {
+	return "provider_usage"
+}
+
+// AddProviderUsage adds calls to the provider's counter for the day and returns the new count.
+func AddProviderUsage(tx *gorm.DB, provider string, day time.Time, calls int64) (int64, error) {
+	var count int64
+
+	err := tx.Raw(`
+		INSERT INTO provider_usage (provider, usage_date, call_count, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT (provider, usage_date)
+		DO UPDATE SET call_count = provider_usage.call_count + EXCLUDED.call_count, updated_at = CURRENT_TIMESTAMP
+		RETURNING call_count`, provider, day, calls).Scan(&count).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetProviderUsage returns the provider's call count for the day, 0 when nothing was recorded.
+func GetProviderUsage(tx *gorm.DB, provider string, day time.Time) (int64, error) {
+	usage := ProviderUsage{}
+
+	err := tx.Where("provider = ? AND usage_date = ?", provider, day).First(&usage).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return usage.CallCount, nil
+}
+
+func (s *ProviderUsageStore) AddUsage(provider string, day time.Time, calls int64) (int64, error) {
+	return AddProviderUsage(s.DB, provider, day, calls)
+}
+
+func (s *ProviderUsageStore) GetUsage(provider string, day time.Time) (int64, error) {
+	return GetProviderUsage(s.DB, provider, day)
+}
@@ -3,19 +3,20 @@ package models
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 // SolanaAssetsMoralisV1 represents the solana_assets_moralis_v1 table.
 type (
 	SolanaAssetsMoralisV1 struct {
-		SolanaAssetID    uint      `gorm:"primaryKey;autoIncrement" json:"solana_asset_id"`
-		WalletID         int       `gorm:"not null;unique" json:"wallet_id"`
-		Lamports         string    `gorm:"type:varchar(255)" json:"lamports"`
-		Solana           string    `gorm:"type:varchar(255)" json:"solana"`
-		TotalTokensCount int       `gorm:"type:integer" json:"total_tokens_count"`
-		TotalNftsCount   int       `gorm:"type:integer" json:"total_nfts_count"`
-		LastUpdatedAt    time.Time `gorm:"default:CURRENT_TIMESTAMP"  json:"last_updated_at"`
+		SolanaAssetID    uint            `gorm:"primaryKey;autoIncrement" json:"solana_asset_id"`
+		WalletID         int             `gorm:"not null;unique" json:"wallet_id"`
+		Lamports         decimal.Decimal `gorm:"type:numeric" json:"lamports"`
+		Solana           decimal.Decimal `gorm:"type:numeric" json:"solana"`
+		TotalTokensCount int             `gorm:"type:integer" json:"total_tokens_count"`
+		TotalNftsCount   int             `gorm:"type:integer" json:"total_nfts_count"`
+		LastUpdatedAt    time.Time       `gorm:"default:CURRENT_TIMESTAMP"  json:"last_updated_at"`
 
 		// relations use in json responses (optional)
 		Tokens             *[]Token            `gorm:"foreignKey:SolanaAssetID" json:"tokens,omitempty"`
@@ -25,31 +26,31 @@ type (
 
 	// Token represents the tokens table.
 	Token struct {
-		TokenID                int       `gorm:"primary_key" json:"token_id"`
-		SolanaAssetID          int       `gorm:"not null" json:"solana_asset_id"`
-		AssociatedTokenAddress string    `gorm:"type:varchar(255)" json:"associated_token_address"`
-		Mint                   string    `gorm:"type:varchar(255)" json:"mint"`
-		AmountRaw              string    `gorm:"type:varchar(255)" json:"amount_raw"`
-		Amount                 string    `gorm:"type:varchar(255)" json:"amount"`
-		Decimals               string    `gorm:"type:varchar(255)" json:"decimals"`
-		Name                   string    `gorm:"type:varchar(255)" json:"name"`
-		Symbol                 string    `gorm:"type:varchar(50)" json:"symbol"`
-		UpdatedAt              time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-		CreatedAt              time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+		TokenID                int             `gorm:"primary_key" json:"token_id"`
+		SolanaAssetID          int             `gorm:"not null" json:"solana_asset_id"`
+		AssociatedTokenAddress string          `gorm:"type:varchar(255)" json:"associated_token_address"`
+		Mint                   string          `gorm:"type:varchar(255)" json:"mint"`
+		AmountRaw              decimal.Decimal `gorm:"type:numeric" json:"amount_raw"`
+		Amount                 decimal.Decimal `gorm:"type:numeric" json:"amount"`
+		Decimals               string          `gorm:"type:varchar(255)" json:"decimals"`
+		Name                   string          `gorm:"type:varchar(255)" json:"name"`
+		Symbol                 string          `gorm:"type:varchar(50)" json:"symbol"`
+		UpdatedAt              time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+		CreatedAt              time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	}
 
 	// NFT represents the nfts table.
 	NFT struct {
-		NFTID                  int       `gorm:"primary_key" json:"nft_id"`
-		SolanaAssetID          int       `gorm:"not null" json:"solana_asset_id"`
-		AssociatedTokenAddress string    `gorm:"type:varchar(255)" json:"associated_token_address"`
-		Mint                   string    `gorm:"type:varchar(255)" json:"mint"`
-		AmountRaw              string    `gorm:"type:varchar(255)" json:"amount_raw"`
-		Decimals               string    `gorm:"type:varchar(255)" json:"decimals"`
-		Name                   string    `gorm:"type:varchar(255)" json:"name"`
-		Symbol                 string    `gorm:"type:varchar(50)" json:"symbol"`
-		UpdatedAt              time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
-		CreatedAt              time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+		NFTID                  int             `gorm:"primary_key" json:"nft_id"`
+		SolanaAssetID          int             `gorm:"not null" json:"solana_asset_id"`
+		AssociatedTokenAddress string          `gorm:"type:varchar(255)" json:"associated_token_address"`
+		Mint                   string          `gorm:"type:varchar(255)" json:"mint"`
+		AmountRaw              decimal.Decimal `gorm:"type:numeric" json:"amount_raw"`
+		Decimals               string          `gorm:"type:varchar(255)" json:"decimals"`
+		Name                   string          `gorm:"type:varchar(255)" json:"name"`
+		Symbol                 string          `gorm:"type:varchar(50)" json:"symbol"`
+		UpdatedAt              time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"updated_at"`
+		CreatedAt              time.Time       `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
 	}
 )
 
@@ -65,28 +66,57 @@ func (NFT) TableName() string {
 	return "nfts"
 }
 
-func SaveSolanaData(tx *gorm.DB, solanaAsset *SolanaAssetsMoralisV1, tokens []Token, nfts []NFT) error {
+// SaveSolanaData saves a SolanaAssetsMoralisV1 record and replaces its tokens and NFTs, and returns the changes
+// of the sol balance and of the token and NFT amounts over the stored ones.
+func SaveSolanaData(tx *gorm.DB, solanaAsset *SolanaAssetsMoralisV1, tokens []Token, nfts []NFT) ([]AssetChange, error) {
 	// Check if a SolanaAssetsMoralisV1 record already exists for the wallet.
 	existingAsset := SolanaAssetsMoralisV1{}
 	result := tx.Where("wallet_id = ?", solanaAsset.WalletID).First(&existingAsset)
 
 	if result.Error != nil && result.Error != gorm.ErrRecordNotFound {
 		// Return any other error encountered during the query.
-		return result.Error
+		return nil, result.Error
 	}
 
+	previousTokens := make(map[string]assetAmount)
+	previousNFTs := make(map[string]assetAmount)
+
 	if result.RowsAffected == 0 {
 		// If the record does not exist, create a new one.
 		if result := tx.Create(solanaAsset); result.Error != nil {
-			return result.Error
+			return nil, result.Error
 		}
 	} else {
 		// If the record exists, update it with the new information.
 		if err := tx.Model(&existingAsset).Updates(solanaAsset).Error; err != nil {
-			return err
+			return nil, err
 		}
 		// Use the ID of the existing record for associated tokens and NFTs.
 		solanaAsset.SolanaAssetID = existingAsset.SolanaAssetID
+
+		// The tokens and NFTs held are those of the response, the stored ones are replaced.
+		existingTokens := make([]Token, 0)
+		if err := tx.Where("solana_asset_id = ?", existingAsset.SolanaAssetID).Order("token_id").Find(&existingTokens).Error; err != nil {
+			return nil, err
+		}
+		for _, token := range existingTokens {
+			previousTokens[token.Mint] = assetAmount{token.Symbol, token.Amount}
+		}
+
+		existingNFTs := make([]NFT, 0)
+		if err := tx.Where("solana_asset_id = ?", existingAsset.SolanaAssetID).Order("nft_id").Find(&existingNFTs).Error; err != nil {
+			return nil, err
+		}
+		for _, nft := range existingNFTs {
+			previousNFTs[nft.Mint] = assetAmount{nft.Symbol, nft.AmountRaw}
+		}
+
+		if err := tx.Where("solana_asset_id = ?", existingAsset.SolanaAssetID).Delete(&Token{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("solana_asset_id = ?", existingAsset.SolanaAssetID).Delete(&NFT{}).Error; err != nil {
+			return nil, err
+		}
 	}
 
 	// Set SolanaAssetID for each Token and NFT.
@@ -98,18 +128,29 @@ func SaveSolanaData(tx *gorm.DB, solanaAsset *SolanaAssetsMoralisV1, tokens []To
 	}
 
 	// Save each Token
+	currentTokens := make(map[string]assetAmount)
 	for _, token := range tokens {
 		if result := tx.Create(&token); result.Error != nil {
-			return result.Error
+			return nil, result.Error
 		}
+		currentTokens[token.Mint] = assetAmount{token.Symbol, currentTokens[token.Mint].amount.Add(token.Amount)}
 	}
 
 	// Save each NFT
+	currentNFTs := make(map[string]assetAmount)
 	for _, nft := range nfts {
 		if result := tx.Create(&nft); result.Error != nil {
-			return result.Error
+			return nil, result.Error
 		}
+		currentNFTs[nft.Mint] = assetAmount{nft.Symbol, currentNFTs[nft.Mint].amount.Add(nft.AmountRaw)}
 	}
 
-	return nil
+	previousSol := map[string]assetAmount{"sol": {"SOL", existingAsset.Solana}}
+	currentSol := map[string]assetAmount{"sol": {"SOL", solanaAsset.Solana}}
+
+	changes := diffAssets(AssetCoin, previousSol, currentSol, false)
+	changes = append(changes, diffAssets(AssetToken, previousTokens, currentTokens, true)...)
+	changes = append(changes, diffAssets(AssetNFT, previousNFTs, currentNFTs, true)...)
+
+	return changes, nil
 }
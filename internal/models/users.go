@@ -1,10 +1,11 @@
 package models
 
 import (
-	"log"
 	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
 type (
@@ -16,6 +17,8 @@ type (
 		HashedPublicKey  string    `gorm:"type:text" json:"hashed_public_key"`
 		OtherUserDetails string    `gorm:"type:text" json:"other_user_details"`
 		SignupDate       time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"signup_date"`
+		// tokens issued before this time are rejected, set when an operator revokes the user's tokens
+		TokensValidAfter *time.Time `json:"tokens_valid_after,omitempty"`
 	}
 )
 
@@ -65,18 +68,41 @@ func UpdateUser(tx *gorm.DB, user *User) error {
 	return nil
 }
 
-func GetAllUsers(db *gorm.DB) []User {
+// GetAllUsers returns every user ordered by id.
+func GetAllUsers(tx *gorm.DB) ([]User, error) {
+	users := make([]User, 0)
 
-	var users []User
+	if err := tx.Order("user_id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
 
-	result := db.Find(&users)
-	if result.Error != nil {
-		log.Fatalf("Error retrieving users: %v", result.Error)
+// RevokeUserTokens invalidates every token issued to the user so far. The user signs in again to get a new one.
+func RevokeUserTokens(tx *gorm.DB, userId int) (*User, error) {
+	user, err := GetUserById(tx, userId)
+	if err != nil {
+		return nil, err
 	}
 
-	// Print out the retrieved users
-	// for _, user := range users {
-	// 	fmt.Printf("User ID: %v,  User Name: %v, Email: %v\n", user.UserId, user.UserName, user.Email)
-	// }
-	return users
+	now, err := utils.GetDBTime()
+	if err != nil {
+		return nil, err
+	}
+
+	// token issue times have a one second resolution
+	revokedAt := now.UTC().Truncate(time.Second)
+	if err := tx.Model(user).UpdateColumn("tokens_valid_after", revokedAt).Error; err != nil {
+		return nil, err
+	}
+	user.TokensValidAfter = &revokedAt
+
+	return user, nil
+}
+
+// TokenRevoked reports whether a token issued at issuedAt was revoked. Issue times have a one second resolution,
+// so tokens issued in the second of the revocation are revoked too.
+func (u *User) TokenRevoked(issuedAt time.Time) bool {
+	return u.TokensValidAfter != nil && !issuedAt.After(*u.TokensValidAfter)
 }
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUserTokenRevoked(t *testing.T) {
+	revokedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		user     User
+		issuedAt time.Time
+		want     bool
+	}{
+		{"never revoked", User{}, revokedAt, false},
+		{"issued before", User{TokensValidAfter: &revokedAt}, revokedAt.Add(-time.Second), true},
+		{"issued in the same second", User{TokensValidAfter: &revokedAt}, revokedAt, true},
+		{"issued after", User{TokensValidAfter: &revokedAt}, revokedAt.Add(time.Second), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.TokenRevoked(tt.issuedAt); got != tt.want {
+				t.Errorf("TokenRevoked(%v) = %v, want %v", tt.issuedAt, got, tt.want)
+			}
+		})
+	}
+}
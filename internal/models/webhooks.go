@@ -0,0 +1,232 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Statuses of a webhook delivery.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+type (
+	// Webhook represents the webhooks table, a url notified of the events of some wallets.
+	Webhook struct {
+		WebhookID      int              `gorm:"primaryKey" json:"webhook_id"`
+		UserID         int              `gorm:"not null" json:"user_id"`
+		APIKeyID       *int             `json:"api_key_id"`
+		URL            string           `gorm:"type:text;not null" json:"url"`
+		Secret         string           `gorm:"type:varchar(64);not null" json:"-"`
+		Events         string           `gorm:"type:text" json:"events"`  // comma separated
+		Wallets        string           `gorm:"type:text" json:"wallets"` // comma separated chain:address
+		ValueThreshold *decimal.Decimal `gorm:"type:numeric" json:"value_threshold"`
+		LastValue      *decimal.Decimal `gorm:"type:numeric" json:"last_value"`
+		DisabledAt     *time.Time       `json:"disabled_at"`
+		CreatedAt      time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	}
+
+	// WebhookDelivery represents the webhook_deliveries table, the log of the events sent to a webhook.
+	WebhookDelivery struct {
+		DeliveryID     int        `gorm:"primaryKey" json:"delivery_id"`
+		WebhookID      int        `gorm:"not null" json:"webhook_id"`
+		Event          string     `gorm:"type:varchar(64);not null" json:"event"`
+		EventID        uint64     `json:"event_id"`
+		Payload        string     `gorm:"type:text;not null" json:"payload"`
+		Status         string     `gorm:"type:varchar(16);not null" json:"status"`
+		Attempts       int        `json:"attempts"`
+		ResponseStatus int        `json:"response_status"`
+		Error          string     `gorm:"type:text" json:"error"`
+		NextAttemptAt  time.Time  `json:"next_attempt_at"`
+		DeliveredAt    *time.Time `json:"delivered_at"`
+		CreatedAt      time.Time  `gorm:"default:CURRENT_TIMESTAMP" json:"created_at"`
+	}
+)
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// EventList returns the events of the webhook as a slice.
+func (w *Webhook) EventList() []string {
+	return splitList(w.Events)
+}
+
+// HasEvent reports whether the webhook is notified of event.
+func (w *Webhook) HasEvent(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WalletList returns the wallets of the webhook as chain:address entries.
+func (w *Webhook) WalletList() []string {
+	return splitList(w.Wallets)
+}
+
+// IsDisabled reports whether the webhook was deleted.
+func (w *Webhook) IsDisabled() bool {
+	return w.DisabledAt != nil
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+
+	return strings.Split(list, ",")
+}
+
+// CreateWebhook creates a new webhook
+func CreateWebhook(tx *gorm.DB, webhook *Webhook) error {
+	return tx.Create(webhook).Error
+}
+
+// GetWebhooks returns the webhooks of a user, or only those of an api key when apiKeyID is set, newest first
+func GetWebhooks(tx *gorm.DB, userID int, apiKeyID *int) ([]*Webhook, error) {
+	webhooks := make([]*Webhook, 0)
+
+	query := tx.Where("user_id = ?", userID)
+	if apiKeyID != nil {
+		query = query.Where("api_key_id = ?", *apiKeyID)
+	}
+
+	if err := query.Order("created_at DESC").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook of a user, which must belong to the api key when apiKeyID is set
+func GetWebhook(tx *gorm.DB, userID int, apiKeyID *int, webhookID int) (*Webhook, error) {
+	webhook := &Webhook{}
+
+	query := tx.Where("webhook_id = ? AND user_id = ?", webhookID, userID)
+	if apiKeyID != nil {
+		query = query.Where("api_key_id = ?", *apiKeyID)
+	}
+
+	if err := query.First(webhook).Error; err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// GetWebhookByID returns a webhook by its id
+func GetWebhookByID(tx *gorm.DB, webhookID int) (*Webhook, error) {
+	webhook := &Webhook{}
+
+	if err := tx.Where("webhook_id = ?", webhookID).First(webhook).Error; err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// GetActiveWebhooks returns the webhooks that are not disabled
+func GetActiveWebhooks(tx *gorm.DB) ([]*Webhook, error) {
+	webhooks := make([]*Webhook, 0)
+
+	if err := tx.Where("disabled_at IS NULL").Order("webhook_id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// GetActiveWebhooksByWallet returns the webhooks that are not disabled and notified of the events of the wallet
+// of address on chain
+func GetActiveWebhooksByWallet(tx *gorm.DB, chain, address string) ([]*Webhook, error) {
+	webhooks := make([]*Webhook, 0)
+
+	err := tx.Where("disabled_at IS NULL AND ? = ANY(string_to_array(wallets, ','))", chain+":"+address).
+		Order("webhook_id").
+		Find(&webhooks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// DisableWebhook stops the deliveries of a webhook, its delivery log is kept
+func DisableWebhook(tx *gorm.DB, webhook *Webhook) error {
+	if webhook.DisabledAt != nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	webhook.DisabledAt = &now
+
+	return tx.Model(webhook).Update("disabled_at", now).Error
+}
+
+// UpdateWebhookLastValue records the value the wallets of a webhook were last evaluated at
+func UpdateWebhookLastValue(tx *gorm.DB, webhook *Webhook, value decimal.Decimal) error {
+	webhook.LastValue = &value
+
+	return tx.Model(webhook).Update("last_value", value).Error
+}
+
+// CreateWebhookDelivery records a delivery to make
+func CreateWebhookDelivery(tx *gorm.DB, delivery *WebhookDelivery) error {
+	return tx.Create(delivery).Error
+}
+
+// GetWebhookDeliveries returns the latest deliveries of a webhook, newest first
+func GetWebhookDeliveries(tx *gorm.DB, webhookID, limit int) ([]*WebhookDelivery, error) {
+	deliveries := make([]*WebhookDelivery, 0)
+
+	if err := tx.Where("webhook_id = ?", webhookID).Order("delivery_id DESC").Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are due, and postpones them by lease so that
+// other instances do not send them too. Rows locked by another instance are skipped.
+func ClaimWebhookDeliveries(tx *gorm.DB, limit int, lease time.Duration) ([]*WebhookDelivery, error) {
+	deliveries := make([]*WebhookDelivery, 0)
+
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`SELECT * FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at LIMIT ? FOR UPDATE SKIP LOCKED`, DeliveryPending, time.Now().UTC(), limit).
+			Scan(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int, 0, len(deliveries))
+		for _, delivery := range deliveries {
+			ids = append(ids, delivery.DeliveryID)
+		}
+
+		return tx.Model(&WebhookDelivery{}).Where("delivery_id IN ?", ids).Update("next_attempt_at", time.Now().UTC().Add(lease)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// SaveWebhookDeliveryAttempt records the outcome of an attempt of a delivery
+func SaveWebhookDeliveryAttempt(tx *gorm.DB, delivery *WebhookDelivery) error {
+	return tx.Model(delivery).Select("status", "attempts", "response_status", "error", "next_attempt_at", "delivered_at").Updates(delivery).Error
+}
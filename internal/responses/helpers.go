@@ -1,9 +1,11 @@
 package responses
 
 import (
-	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+	"github.com/shopspring/decimal"
 )
 
+var hundred = decimal.NewFromInt(100)
+
 // filder only IsVerified = true
 func FilterVerifiedResponses(responses []*ChainsResponse) []*ChainsResponse {
 	filteredResponses := []*ChainsResponse{}
@@ -19,17 +21,17 @@ func FilterVerifiedResponses(responses []*ChainsResponse) []*ChainsResponse {
 
 // Add this function to calculate chains totals
 func AssetTotalsOnChainsAndCalculatePercentages(responses []*ChainsResponse) []*ChainsResponse {
-	totalAssetAmount := make(map[string]float64)
+	totalAssetAmount := make(map[string]decimal.Decimal)
 
 	// Calculate the total value per chain
 	for _, response := range responses {
-		totalAssetAmount[response.AssetSymbol] += response.Quantity
+		totalAssetAmount[response.AssetSymbol] = totalAssetAmount[response.AssetSymbol].Add(response.Quantity)
 	}
 
 	// Calculate and update the percentage of each asset within its chain
 	for _, response := range responses {
-		if total, ok := totalAssetAmount[response.AssetSymbol]; ok && total > 0 {
-			response.AssetPercentage = utils.Ternary(total != 0, (response.Quantity/total)*100, 0)
+		if total, ok := totalAssetAmount[response.AssetSymbol]; ok && total.IsPositive() {
+			response.AssetPercentage = percentage(response.Quantity, total)
 		}
 	}
 
@@ -75,14 +77,15 @@ func GroupByAssetSymbolToList(responses []*ChainsResponse) []*PortfolioResponse
 }
 
 func CalculatePortfolioResponse(portfolioResponse []*PortfolioResponse) []*PortfolioResponse {
-	var totalPortfolioPrice float64
+	var totalPortfolioPrice decimal.Decimal
 	for _, portfolio := range portfolioResponse {
-		var quantity, total_price float64
+		var quantity, total_price decimal.Decimal
 
 		// Iterate over the chain data to accumulate values
 		for _, chain := range portfolio.ChainsInfo {
-			quantity += chain.Quantity
-			total_price += chain.TotalPrice
+			quantity = quantity.Add(chain.Quantity)
+			total_price = total_price.Add(chain.TotalPrice)
+			portfolio.Stale = portfolio.Stale || chain.Stale
 		}
 
 		// Update the PortfolioResponse struct with the accumulated values
@@ -91,30 +94,41 @@ func CalculatePortfolioResponse(portfolioResponse []*PortfolioResponse) []*Portf
 		portfolio.UnitPrice = MostCommonUnitPrice(portfolio)
 
 		// Add the TotalPrice of each PortfolioResponse to the totalPortfolioPrice
-		totalPortfolioPrice += portfolio.TotalPrice
+		totalPortfolioPrice = totalPortfolioPrice.Add(portfolio.TotalPrice)
 	}
 
 	// Loop through portfolioResponse and update the PortfolioPercentage
 	for _, portfolio := range portfolioResponse {
-		portfolio.PortfolioPercentage = utils.Ternary(totalPortfolioPrice != 0, (portfolio.TotalPrice/totalPortfolioPrice)*100, 0)
+		portfolio.PortfolioPercentage = percentage(portfolio.TotalPrice, totalPortfolioPrice)
 	}
 
 	return portfolioResponse
 }
 
-func MostCommonUnitPrice(portfolio *PortfolioResponse) float64 {
-	unitPriceCount := make(map[float64]int)
-	var mostCommonUnitPrice float64
+func MostCommonUnitPrice(portfolio *PortfolioResponse) decimal.Decimal {
+	// equal decimals can have different exponents, so count them by their canonical string
+	unitPriceCount := make(map[string]int)
+	var mostCommonUnitPrice decimal.Decimal
 	maxCount := 0
 
 	// Count occurrences of unit_price values
 	for _, chain := range portfolio.ChainsInfo {
-		unitPriceCount[chain.UnitPrice]++
-		if unitPriceCount[chain.UnitPrice] > maxCount {
-			maxCount = unitPriceCount[chain.UnitPrice]
+		key := chain.UnitPrice.String()
+		unitPriceCount[key]++
+		if unitPriceCount[key] > maxCount {
+			maxCount = unitPriceCount[key]
 			mostCommonUnitPrice = chain.UnitPrice
 		}
 	}
 
 	return mostCommonUnitPrice
 }
+
+// percentage returns part as a percentage of total, or 0 when total is zero.
+func percentage(part, total decimal.Decimal) decimal.Decimal {
+	if total.IsZero() {
+		return decimal.Zero
+	}
+
+	return part.Div(total).Mul(hundred)
+}
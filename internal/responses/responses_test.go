@@ -0,0 +1,108 @@
+package responses
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+)
+
+func TestBTCResponse(t *testing.T) {
+	tests := []struct {
+		name         string
+		satoshis     string
+		price        string
+		wantQuantity string
+		wantTotal    string
+	}{
+		{"one bitcoin", "100000000", "60000", "1", "60000"},
+		{"one satoshi", "1", "60000", "0.00000001", "0.0006"},
+		{"fractions", "123456789", "61234.56", "1.23456789", "75598.2215342784"},
+		{"empty wallet", "0", "60000", "0", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wallet := &models.GlobalWallet{
+				BlockchainType: "bitcoin",
+				BitcoinBtcComV1: &models.BitcoinBtcComV1{
+					BitcoinAddressInfo: &models.BitcoinAddressInfo{Balance: decimal.RequireFromString(tt.satoshis)},
+					CoingeckoPriceFeed: &models.CoingeckoPriceFeed{Price: decimal.RequireFromString(tt.price)},
+				},
+			}
+
+			chain := &ChainsResponse{}
+			chain.BTCResponse(wallet)
+			portfolio := &PortfolioResponse{}
+			portfolio.BitcoinPortfolioResponse(wallet)
+
+			for _, got := range []struct{ quantity, total decimal.Decimal }{{chain.Quantity, chain.TotalPrice}, {portfolio.Quantity, portfolio.TotalPrice}} {
+				if !got.quantity.Equal(decimal.RequireFromString(tt.wantQuantity)) {
+					t.Errorf("quantity = %s BTC, want %s", got.quantity, tt.wantQuantity)
+				}
+				if !got.total.Equal(decimal.RequireFromString(tt.wantTotal)) {
+					t.Errorf("total = %s, want %s", got.total, tt.wantTotal)
+				}
+			}
+		})
+	}
+}
+
+func TestDebankTokenResponseQuantity(t *testing.T) {
+	tests := []struct {
+		name      string
+		rawAmount string
+		decimals  int
+		amount    string
+		want      string
+	}{
+		{"ether", "1500000000000000000", 18, "1.5", "1.5"},
+		{"usdc", "2500000", 6, "2.5", "2.5"},
+		{"exact beyond float precision", "123456789012345678901", 18, "123.456789012345678", "123.456789012345678901"},
+		{"no decimals", "42", 0, "42", "42"},
+		{"no raw amount", "0", 18, "3.25", "3.25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &models.TokenList{
+				RawAmount: decimal.RequireFromString(tt.rawAmount),
+				Decimals:  tt.decimals,
+				Amount:    decimal.RequireFromString(tt.amount),
+				Price:     decimal.NewFromInt(2),
+			}
+
+			response := &ChainsResponse{}
+			response.DebankTokenResponse(&models.GlobalWallet{}, token)
+
+			want := decimal.RequireFromString(tt.want)
+			if !response.Quantity.Equal(want) {
+				t.Errorf("quantity = %s, want %s", response.Quantity, want)
+			}
+			if !response.TotalPrice.Equal(want.Mul(decimal.NewFromInt(2))) {
+				t.Errorf("total = %s, want twice %s", response.TotalPrice, want)
+			}
+		})
+	}
+}
+
+func TestPercentage(t *testing.T) {
+	tests := []struct {
+		part, total string
+		want        string
+	}{
+		{"1", "4", "25"},
+		{"1", "3", "33.33333333333333"},
+		{"0", "10", "0"},
+		{"5", "0", "0"},
+		{"10", "10", "100"},
+	}
+
+	for _, tt := range tests {
+		got := percentage(decimal.RequireFromString(tt.part), decimal.RequireFromString(tt.total))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("percentage(%s, %s) = %s, want %s", tt.part, tt.total, got, tt.want)
+		}
+	}
+}
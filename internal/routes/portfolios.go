@@ -1,38 +1,126 @@
 package routes
 
 import (
+	"net/http"
+
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"gorm.io/gorm"
 
 	"github.com/0xbase-Corp/portfolio_svc/internal/controllers"
+	"github.com/0xbase-Corp/portfolio_svc/internal/graph"
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
 	"github.com/0xbase-Corp/portfolio_svc/providers/bitcoin"
 	"github.com/0xbase-Corp/portfolio_svc/providers/debank"
 	"github.com/0xbase-Corp/portfolio_svc/providers/solana"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
-var PortfolioRoutes = func(router *gin.Engine, db *gorm.DB) {
-	router.GET("/healthy", controllers.HealthCheck)
+// PortfolioRoutes registers the API routes, running the given middleware (auth, rate limiting, request guards) on /api/v1.
+var PortfolioRoutes = func(router *gin.Engine, db *gorm.DB, middleware ...gin.HandlerFunc) {
+	// unknown routes answer a problem document like every other error
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		middlewares.AbortWithProblem(c, errors.NewNotFoundError("no route for "+c.Request.URL.Path))
+	})
+	router.NoMethod(func(c *gin.Context) {
+		middlewares.AbortWithProblem(c, errors.NewHttpError(http.StatusMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+	})
+
+	router.GET("/livez", controllers.LivenessController)
+
+	// kept for existing probes, same as /livez
+	router.GET("/healthy", controllers.LivenessController)
+
+	router.GET("/readyz", func(c *gin.Context) { controllers.ReadinessController(c, db) })
+
+	// the details expose upstream errors and quotas, only operators read them
+	if token := configs.EnvConfigVars.HealthToken; token != "" {
+		router.GET("/health/details", middlewares.RequireToken(token), func(c *gin.Context) { controllers.HealthDetailsController(c, db) })
+	}
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	bitcoinAPIClient := &bitcoin.BitcoinAPI{}
 	solanaAPIClient := &solana.SolanaAPI{}
 	debankAPIClient := &debank.DebankAPI{}
 
-	v1 := router.Group("/api/v1")
+	v1 := router.Group("/api/v1", middleware...)
+
+	portfolioRead := middlewares.RequireScope(utils.ScopePortfolioRead)
+	walletsRead := middlewares.RequireScope(utils.ScopeWalletsRead)
+
+	v1.GET("/portfolio/solana", portfolioRead, func(c *gin.Context) { controllers.SolanaController(c, traced(c, db), solanaAPIClient) })
+
+	v1.GET("/portfolio/solana-wallet/:wallet-id", walletsRead, func(c *gin.Context) { controllers.GetSolanaController(c, traced(c, db)) })
+
+	v1.GET("/portfolio/btc", portfolioRead, func(c *gin.Context) { controllers.BitcoinController(c, traced(c, db), bitcoinAPIClient) })
+
+	v1.GET("/portfolio/btc-wallet/:wallet-id", walletsRead, func(c *gin.Context) { controllers.GetBtcDataController(c, traced(c, db)) })
+
+	v1.GET("/portfolio/debank", portfolioRead, func(c *gin.Context) { controllers.DebankController(c, traced(c, db), debankAPIClient) })
+
+	v1.POST("/all-portfolio", portfolioRead, func(c *gin.Context) { controllers.AllPortfolioController(c, traced(c, db)) })
+
+	v1.POST("/all-portfolio/stream", portfolioRead, func(c *gin.Context) { controllers.StreamAllPortfolioController(c, traced(c, db)) })
+
+	v1.GET("/ws", portfolioRead, controllers.PortfolioSocketController)
+
+	// graphql reads stored data only, like the wallet endpoints
+	schema := graph.NewSchema()
 
-	v1.GET("/portfolio/solana", func(c *gin.Context) { controllers.SolanaController(c, db, solanaAPIClient) })
+	v1.GET("/graphql", walletsRead, func(c *gin.Context) { controllers.GraphQLController(c, traced(c, db), schema) })
 
-	v1.GET("/portfolio/solana-wallet/:wallet-id", func(c *gin.Context) { controllers.GetSolanaController(c, db) })
+	v1.POST("/graphql", walletsRead, func(c *gin.Context) { controllers.GraphQLController(c, traced(c, db), schema) })
 
-	v1.GET("/portfolio/btc", func(c *gin.Context) { controllers.BitcoinController(c, db, bitcoinAPIClient) })
+	v1.POST("/generate-hash", func(c *gin.Context) { controllers.AuthGenerateHash(c, traced(c, db)) })
 
-	v1.GET("/portfolio/btc-wallet/:wallet-id", func(c *gin.Context) { controllers.GetBtcDataController(c, db) })
+	v1.POST("/verify-hash", func(c *gin.Context) { controllers.AuthVerifyHashKey(c, traced(c, db)) })
 
-	v1.GET("/portfolio/debank", func(c *gin.Context) { controllers.DebankController(c, db, debankAPIClient) })
+	// quotas and usage are for our own clients, like /health/details is for operators
+	v1.GET("/providers/quota", middlewares.RequireAuth(), controllers.ProviderQuotaController)
 
-	v1.POST("/all-portfolio", func(c *gin.Context) { controllers.AllPortfolioController(c, db) })
+	// api keys are managed by signed in users only
+	apiKeys := v1.Group("/api-keys", middlewares.RequireUser())
 
-	v1.POST("/generate-hash", func(c *gin.Context) { controllers.AuthGenerateHash(c, db) })
+	apiKeys.POST("", func(c *gin.Context) { controllers.CreateAPIKeyController(c, traced(c, db)) })
 
-	v1.POST("/verify-hash", func(c *gin.Context) { controllers.AuthVerifyHashKey(c, db) })
+	apiKeys.GET("", func(c *gin.Context) { controllers.ListAPIKeysController(c, traced(c, db)) })
+
+	apiKeys.DELETE("/:api-key-id", func(c *gin.Context) { controllers.RevokeAPIKeyController(c, traced(c, db)) })
+
+	// webhooks created with an api key belong to it, users manage all of theirs
+	webhooks := v1.Group("/webhooks", middlewares.RequireAuth(), middlewares.RequireScope(utils.ScopeWebhooks))
+
+	webhooks.POST("", func(c *gin.Context) { controllers.CreateWebhookController(c, traced(c, db)) })
+
+	webhooks.GET("", func(c *gin.Context) { controllers.ListWebhooksController(c, traced(c, db)) })
+
+	webhooks.DELETE("/:webhook-id", func(c *gin.Context) { controllers.DeleteWebhookController(c, traced(c, db)) })
+
+	webhooks.POST("/:webhook-id/test", func(c *gin.Context) { controllers.TestWebhookController(c, traced(c, db)) })
+
+	webhooks.GET("/:webhook-id/deliveries", func(c *gin.Context) { controllers.ListWebhookDeliveriesController(c, traced(c, db)) })
+
+	// alert rules are managed by signed in users only
+	alerts := v1.Group("/alerts", middlewares.RequireUser())
+
+	alerts.POST("", func(c *gin.Context) { controllers.CreateAlertRuleController(c, traced(c, db)) })
+
+	alerts.GET("", func(c *gin.Context) { controllers.ListAlertRulesController(c, traced(c, db)) })
+
+	alerts.GET("/:rule-id", func(c *gin.Context) { controllers.GetAlertRuleController(c, traced(c, db)) })
+
+	alerts.PUT("/:rule-id", func(c *gin.Context) { controllers.UpdateAlertRuleController(c, traced(c, db)) })
+
+	alerts.DELETE("/:rule-id", func(c *gin.Context) { controllers.DeleteAlertRuleController(c, traced(c, db)) })
+
+	alerts.GET("/:rule-id/history", func(c *gin.Context) { controllers.ListAlertHistoryController(c, traced(c, db)) })
+}
 
+// traced returns db bound to the request context, so that its queries are recorded in the request trace.
+func traced(c *gin.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(c.Request.Context())
 }
@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	er "errors"
+	"log/slog"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+)
+
+// kindCodes maps the kinds of APIErrors to the status codes of gRPC.
+var kindCodes = map[errors.Kind]codes.Code{
+	errors.KindValidation:          codes.InvalidArgument,
+	errors.KindUnauthorized:        codes.Unauthenticated,
+	errors.KindForbidden:           codes.PermissionDenied,
+	errors.KindNotFound:            codes.NotFound,
+	errors.KindConflict:            codes.AlreadyExists,
+	errors.KindRateLimited:         codes.ResourceExhausted,
+	errors.KindUpstreamUnavailable: codes.Unavailable,
+	errors.KindInternal:            codes.Internal,
+}
+
+// toStatus returns the status error the caller sees for err, as the ErrorHandler middleware answers REST
+// errors: client errors keep their message, provider failures are logged and name the provider, other failures
+// are logged and hidden.
+func toStatus(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if er.Is(err, gorm.ErrRecordNotFound) {
+		return status.Error(codes.NotFound, "not found")
+	}
+
+	var apiErr *errors.APIError
+	if er.As(err, &apiErr) && apiErr.Code < 500 {
+		return status.Error(kindCodes[apiErr.Kind], apiErr.Message)
+	}
+
+	var upstreamErr *errors.UpstreamError
+	if er.As(err, &upstreamErr) {
+		slog.WarnContext(ctx, "provider failed", slog.String("provider", upstreamErr.Provider), slog.Any("error", err))
+		return status.Error(kindCodes[upstreamErr.APIError().Kind], upstreamErr.Provider+" is unavailable")
+	}
+
+	slog.ErrorContext(ctx, "rpc failed", slog.Any("error", err))
+
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,183 @@
+package rpc
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+
+	portfoliov1 "github.com/0xbase-Corp/portfolio_svc/api/portfolio/v1"
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// requestIDKey is the metadata carrying the request ID, the X-Request-ID header of the REST API.
+const requestIDKey = "x-request-id"
+
+// methodScopes are the scopes api keys need for each method, those of the matching REST endpoints. Methods
+// missing from it, such as those of the reflection service, need no credentials.
+var methodScopes = map[string]string{
+	portfoliov1.PortfolioService_GetPortfolio_FullMethodName:  utils.ScopeWalletsRead,
+	portfoliov1.PortfolioService_GetWallet_FullMethodName:     utils.ScopeWalletsRead,
+	portfoliov1.PortfolioService_ListHoldings_FullMethodName:  utils.ScopeWalletsRead,
+	portfoliov1.PortfolioService_RefreshWallet_FullMethodName: utils.ScopePortfolioRead,
+	portfoliov1.PortfolioService_GetPrices_FullMethodName:     utils.ScopeWalletsRead,
+}
+
+// serverStream is a stream whose handler sees ctx as its context.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// caller is who a call is made by, once authenticated.
+type caller struct {
+	identity  string // user:<id> or key:<id>, empty for the methods that need no credentials
+	rateLimit int    // the requests per minute of api keys with their own limit, 0 for the route limit
+}
+
+// unaryInterceptor logs, rate limits, authenticates and recovers the unary calls.
+func unaryInterceptor(db *gorm.DB, limiter *rateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx = withRequestID(ctx)
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(ctx, recovered)
+			}
+			logCall(ctx, info.FullMethod, start, err)
+		}()
+
+		if err := admit(ctx, db, limiter, info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor logs, rate limits, authenticates and recovers the streaming calls.
+func streamInterceptor(db *gorm.DB, limiter *rateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx := withRequestID(stream.Context())
+		start := time.Now()
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = recoverPanic(ctx, recovered)
+			}
+			logCall(ctx, info.FullMethod, start, err)
+		}()
+
+		if err := admit(ctx, db, limiter, info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// admit limits the calls of method by peer before the credentials are looked up, as IPRateLimiter does, then
+// authenticates the call and limits it by caller, as RateLimiter does.
+func admit(ctx context.Context, db *gorm.DB, limiter *rateLimiter, method string) error {
+	if err := limiter.allow(ctx, method, peerIdentity(ctx), 0); err != nil {
+		return err
+	}
+
+	c, err := authenticate(ctx, db, method)
+	if err != nil {
+		return err
+	}
+
+	return limiter.allow(ctx, method, c.identity, c.rateLimit)
+}
+
+// authenticate checks the credentials of a call of method, a user JWT in the authorization metadata or an
+// api key in x-api-key, as the Authenticate middleware does, and the scope of api keys.
+func authenticate(ctx context.Context, db *gorm.DB, method string) (caller, error) {
+	scope, ok := methodScopes[method]
+	if !ok {
+		return caller{}, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if token, ok := strings.CutPrefix(first(md, "authorization"), "Bearer "); ok {
+		userID, err := services.AuthenticateToken(db.WithContext(ctx), token)
+		if err != nil {
+			return caller{}, toStatus(ctx, err)
+		}
+		return caller{identity: "user:" + strconv.Itoa(userID)}, nil
+	}
+
+	if key := first(md, "x-api-key"); key != "" {
+		apiKey, err := services.AuthenticateAPIKey(db.WithContext(ctx), key)
+		if err != nil {
+			return caller{}, toStatus(ctx, err)
+		}
+		if !apiKey.HasScope(scope) {
+			return caller{}, status.Error(codes.PermissionDenied, "api key is missing scope "+scope)
+		}
+		return caller{identity: "key:" + strconv.Itoa(apiKey.APIKeyID), rateLimit: apiKey.RateLimit}, nil
+	}
+
+	return caller{}, status.Error(codes.Unauthenticated, "authentication required")
+}
+
+// first returns the first value of key in md, empty when there is none.
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+
+	return ""
+}
+
+// withRequestID returns ctx carrying the request ID the caller sent, or a new one, and sends it back in the
+// header of the call.
+func withRequestID(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	id := middlewares.RequestIDOf(first(md, requestIDKey))
+
+	// the header cannot be set on calls that already failed, which are logged anyway
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+
+	return logging.WithRequestID(ctx, id)
+}
+
+// logCall logs a call once it is served, at warn level for the codes of server failures.
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	if serverFailure(code) {
+		level = slog.LevelWarn
+	}
+
+	slog.LogAttrs(ctx, level, "rpc",
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+// recoverPanic logs the panic of a handler and returns the error the caller sees instead.
+func recoverPanic(ctx context.Context, recovered any) error {
+	slog.ErrorContext(ctx, "panic serving rpc", slog.Any("panic", recovered), slog.String("stack", string(debug.Stack())))
+
+	return status.Error(codes.Internal, "internal error")
+}
@@ -0,0 +1,237 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	portfoliov1 "github.com/0xbase-Corp/portfolio_svc/api/portfolio/v1"
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+const testSecret = "test-secret"
+
+// dryRunDB returns a database whose queries are built but not run: users and api keys load as zero values.
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() = %v", err)
+	}
+
+	return db
+}
+
+func setupConfig(t *testing.T) {
+	t.Helper()
+
+	previous := configs.EnvConfigVars
+	configs.EnvConfigVars = &configs.EnvConfigs{Secret: testSecret, AccessTokenTTL: time.Hour}
+	t.Cleanup(func() { configs.EnvConfigVars = previous })
+}
+
+func signToken(t *testing.T, secret string, claims *utils.Claims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatalf("SignedString() = %v", err)
+	}
+
+	return token
+}
+
+func withMetadata(pairs ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+func TestAuthenticate(t *testing.T) {
+	setupConfig(t)
+	db := dryRunDB(t)
+
+	valid, err := utils.GenerateAccessToken(7, "user@example.com", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() = %v", err)
+	}
+	expired := signToken(t, testSecret, &utils.Claims{UserID: 7, ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	forged := signToken(t, "other-secret", &utils.Claims{UserID: 7, ExpiresAt: time.Now().Add(time.Hour).Unix()})
+
+	getPortfolio := portfoliov1.PortfolioService_GetPortfolio_FullMethodName
+
+	tests := []struct {
+		name     string
+		method   string
+		ctx      context.Context
+		want     codes.Code
+		identity string
+	}{
+		{"reflection needs no credentials", "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", context.Background(), codes.OK, ""},
+		{"no metadata", getPortfolio, context.Background(), codes.Unauthenticated, ""},
+		{"no credentials", getPortfolio, withMetadata("x-request-id", "abc"), codes.Unauthenticated, ""},
+		{"basic auth", getPortfolio, withMetadata("authorization", "Basic dXNlcjpwYXNz"), codes.Unauthenticated, ""},
+		{"malformed token", getPortfolio, withMetadata("authorization", "Bearer not-a-jwt"), codes.Unauthenticated, ""},
+		{"expired token", getPortfolio, withMetadata("authorization", "Bearer "+expired), codes.Unauthenticated, ""},
+		{"token of another secret", getPortfolio, withMetadata("authorization", "Bearer "+forged), codes.Unauthenticated, ""},
+		{"valid token", getPortfolio, withMetadata("authorization", "Bearer "+valid), codes.OK, "user:7"},
+		{"token before api key", getPortfolio, withMetadata("authorization", "Bearer "+valid, "x-api-key", "0xb_key"), codes.OK, "user:7"},
+		{"api key without scope", getPortfolio, withMetadata("x-api-key", "0xb_key"), codes.PermissionDenied, ""},
+		{"api key without scope to refresh", portfoliov1.PortfolioService_RefreshWallet_FullMethodName, withMetadata("x-api-key", "0xb_key"), codes.PermissionDenied, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := authenticate(tt.ctx, db, tt.method)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("authenticate() = %v, want %v", err, tt.want)
+			}
+			if c.identity != tt.identity {
+				t.Errorf("identity = %q, want %q", c.identity, tt.identity)
+			}
+		})
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	setupConfig(t)
+	db := dryRunDB(t)
+
+	token, err := utils.GenerateAccessToken(7, "user@example.com", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() = %v", err)
+	}
+
+	info := &grpc.UnaryServerInfo{FullMethod: portfoliov1.PortfolioService_GetWallet_FullMethodName}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		handler grpc.UnaryHandler
+		want    codes.Code
+		called  bool
+	}{
+		{"authenticated", withMetadata("authorization", "Bearer "+token), func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }, codes.OK, true},
+		{"unauthenticated", context.Background(), func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }, codes.Unauthenticated, false},
+		{"handler error", withMetadata("authorization", "Bearer "+token), func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "wallet is not stored")
+		}, codes.NotFound, true},
+		{"handler panic", withMetadata("authorization", "Bearer "+token), func(ctx context.Context, req interface{}) (interface{}, error) { panic("boom") }, codes.Internal, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return tt.handler(ctx, req)
+			}
+
+			_, err := unaryInterceptor(db, nil)(tt.ctx, nil, info, handler)
+			if got := status.Code(err); got != tt.want {
+				t.Errorf("interceptor = %v, want %v", err, tt.want)
+			}
+			if called != tt.called {
+				t.Errorf("handler called = %v, want %v", called, tt.called)
+			}
+		})
+	}
+}
+
+func TestAdmitRateLimits(t *testing.T) {
+	setupConfig(t)
+	db := dryRunDB(t)
+
+	token, err := utils.GenerateAccessToken(7, "user@example.com", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken() = %v", err)
+	}
+
+	method := portfoliov1.PortfolioService_GetPrices_FullMethodName
+	limiter := &rateLimiter{
+		store: middlewares.NewMemoryRateLimitStore(),
+		config: middlewares.RateLimitConfig{
+			Default: middlewares.RouteLimit{Requests: 100, Window: time.Minute},
+			Routes:  map[string]middlewares.RouteLimit{method: {Requests: 2, Window: time.Minute}},
+		},
+	}
+
+	user := withMetadata("authorization", "Bearer "+token)
+	for i, want := range []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted} {
+		if err := admit(user, db, limiter, method); status.Code(err) != want {
+			t.Errorf("call %d = %v, want %v", i+1, err, want)
+		}
+	}
+
+	// other methods have their own count
+	if err := admit(user, db, limiter, portfoliov1.PortfolioService_GetWallet_FullMethodName); err != nil {
+		t.Errorf("call of another method = %v, want it admitted", err)
+	}
+
+	// anonymous floods are limited by peer before their credentials are looked up
+	anonymous := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.9"), Port: 5000}})
+	for i, want := range []codes.Code{codes.Unauthenticated, codes.Unauthenticated, codes.ResourceExhausted} {
+		if err := admit(anonymous, db, limiter, method); status.Code(err) != want {
+			t.Errorf("anonymous call %d = %v, want %v", i+1, err, want)
+		}
+	}
+}
+
+func TestRateLimiterAllow(t *testing.T) {
+	config := middlewares.RateLimitConfig{Default: middlewares.RouteLimit{Requests: 1, Window: time.Minute}}
+	method := portfoliov1.PortfolioService_GetWallet_FullMethodName
+
+	tests := []struct {
+		name      string
+		limiter   *rateLimiter
+		identity  string
+		rateLimit int
+		want      []codes.Code
+	}{
+		{"default limit", &rateLimiter{store: middlewares.NewMemoryRateLimitStore(), config: config}, "user:1", 0, []codes.Code{codes.OK, codes.ResourceExhausted}},
+		{"own limit of an api key", &rateLimiter{store: middlewares.NewMemoryRateLimitStore(), config: config}, "key:1", 3, []codes.Code{codes.OK, codes.OK, codes.OK, codes.ResourceExhausted}},
+		{"no identity", &rateLimiter{store: middlewares.NewMemoryRateLimitStore(), config: config}, "", 0, []codes.Code{codes.OK, codes.OK}},
+		{"no limit configured", &rateLimiter{store: middlewares.NewMemoryRateLimitStore()}, "user:1", 0, []codes.Code{codes.OK, codes.OK}},
+		{"nil limiter", nil, "user:1", 0, []codes.Code{codes.OK, codes.OK}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i, want := range tt.want {
+				if err := tt.limiter.allow(context.Background(), method, tt.identity, tt.rateLimit); status.Code(err) != want {
+					t.Errorf("call %d = %v, want %v", i+1, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewServerReflection(t *testing.T) {
+	tests := []struct {
+		reflection bool
+		want       bool
+	}{
+		{false, false},
+		{true, true},
+	}
+
+	for _, tt := range tests {
+		srv := NewServer(nil, Config{Reflection: tt.reflection})
+
+		_, registered := srv.GetServiceInfo()["grpc.reflection.v1.ServerReflection"]
+		if registered != tt.want {
+			t.Errorf("reflection %v: registered = %v, want %v", tt.reflection, registered, tt.want)
+		}
+	}
+}
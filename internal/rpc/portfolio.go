@@ -0,0 +1,287 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	er "errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+
+	portfoliov1 "github.com/0xbase-Corp/portfolio_svc/api/portfolio/v1"
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/internal/services"
+	"github.com/0xbase-Corp/portfolio_svc/providers/names"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// chains maps wallet blockchain types to the values of the Chain enum, the chain families of REST.
+var chains = map[string]portfoliov1.Chain{
+	utils.Bitcoin: portfoliov1.Chain_CHAIN_BTC,
+	utils.Solana:  portfoliov1.Chain_CHAIN_SOL,
+	utils.Debank:  portfoliov1.Chain_CHAIN_EVM,
+}
+
+// portfolioServer serves PortfolioService. Reads are served from storage, only RefreshWallet calls the
+// providers.
+type portfolioServer struct {
+	portfoliov1.UnimplementedPortfolioServiceServer
+
+	db           *gorm.DB
+	maxAddresses int
+}
+
+func (s *portfolioServer) GetPortfolio(ctx context.Context, req *portfoliov1.GetPortfolioRequest) (*portfoliov1.GetPortfolioResponse, error) {
+	if len(req.Addresses) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "addresses is required")
+	}
+	if len(req.Addresses) > s.maxAddresses {
+		return nil, status.Errorf(codes.InvalidArgument, "too many addresses: %d given, at most %d allowed per request", len(req.Addresses), s.maxAddresses)
+	}
+
+	resp := &portfoliov1.GetPortfolioResponse{Wallets: make([]*portfoliov1.Wallet, 0, len(req.Addresses))}
+	total := decimal.Zero
+	seen := make(map[string]bool, len(req.Addresses))
+
+	for _, input := range req.Addresses {
+		_, address, err := services.ResolveAddress(ctx, input)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		if seen[address] {
+			continue
+		}
+		seen[address] = true
+
+		wallet, err := services.LoadWallet(s.db.WithContext(ctx), address)
+		if er.Is(err, gorm.ErrRecordNotFound) {
+			resp.Missing = append(resp.Missing, input)
+			continue
+		}
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+
+		resp.Wallets = append(resp.Wallets, toWallet(wallet))
+		total = total.Add(wallet.ValueUSD())
+	}
+	resp.ValueUsd = total.String()
+
+	return resp, nil
+}
+
+func (s *portfolioServer) GetWallet(ctx context.Context, req *portfoliov1.GetWalletRequest) (*portfoliov1.GetWalletResponse, error) {
+	wallet, err := s.loadWallet(ctx, req.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &portfoliov1.GetWalletResponse{Wallet: toWallet(wallet)}, nil
+}
+
+func (s *portfolioServer) ListHoldings(ctx context.Context, req *portfoliov1.ListHoldingsRequest) (*portfoliov1.ListHoldingsResponse, error) {
+	size := int(req.PageSize)
+	if size < 0 || size > maxPageSize {
+		return nil, status.Errorf(codes.InvalidArgument, "page_size must be between 0 and %d", maxPageSize)
+	}
+	if size == 0 {
+		size = defaultPageSize
+	}
+
+	wallet, err := s.loadWallet(ctx, req.Address)
+	if err != nil {
+		return nil, err
+	}
+	holdings := services.Holdings(wallet)
+
+	start := 0
+	if req.PageToken != "" {
+		if start, err = decodePageToken(req.PageToken); err != nil || start > len(holdings) {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token "+req.PageToken)
+		}
+	}
+	end := min(start+size, len(holdings))
+
+	resp := &portfoliov1.ListHoldingsResponse{Holdings: make([]*portfoliov1.Holding, 0, end-start), TotalSize: int32(len(holdings))}
+	for _, h := range holdings[start:end] {
+		resp.Holdings = append(resp.Holdings, toHolding(h))
+	}
+	if end < len(holdings) {
+		resp.NextPageToken = encodePageToken(end)
+	}
+
+	return resp, nil
+}
+
+func (s *portfolioServer) RefreshWallet(req *portfoliov1.RefreshWalletRequest, stream portfoliov1.PortfolioService_RefreshWalletServer) error {
+	ctx := stream.Context()
+
+	if err := stream.Send(&portfoliov1.RefreshWalletResponse{Stage: portfoliov1.RefreshWalletResponse_STAGE_RESOLVING, Message: "resolving " + req.Address}); err != nil {
+		return err
+	}
+
+	chain, address, err := services.ResolveAddress(ctx, req.Address)
+	if err != nil {
+		return toStatus(ctx, err)
+	}
+
+	// a wallet refreshed within its max age is served as stored, so that calls cannot drain the provider quotas
+	stored, err := services.LoadWallet(s.db.WithContext(ctx), address)
+	if err != nil && !er.Is(err, gorm.ErrRecordNotFound) {
+		return toStatus(ctx, err)
+	}
+	if err == nil && services.WalletFresh(stored) {
+		message := fmt.Sprintf("stored, refreshed less than %s ago", services.WalletMaxAge(chain))
+		return stream.Send(&portfoliov1.RefreshWalletResponse{Stage: portfoliov1.RefreshWalletResponse_STAGE_DONE, Message: message, Wallet: toWallet(stored)})
+	}
+
+	if err := stream.Send(&portfoliov1.RefreshWalletResponse{Stage: portfoliov1.RefreshWalletResponse_STAGE_FETCHING, Message: fmt.Sprintf("fetching %s wallet %s", chain, address)}); err != nil {
+		return err
+	}
+
+	// the save outlives a caller that goes away, but keeps its trace and request ID
+	refreshed, err := services.RefreshWallet(context.WithoutCancel(ctx), s.db, chain, address)
+	if err != nil {
+		return toStatus(ctx, err)
+	}
+
+	// wallets requested by name keep it, naming is best effort as it is for REST
+	if name := strings.ToLower(strings.TrimSpace(req.Address)); names.IsName(name) && name != refreshed.Name {
+		_ = models.UpdateWalletName(s.db.WithContext(ctx), refreshed, name)
+	}
+
+	wallet, err := services.LoadWallet(s.db.WithContext(ctx), address)
+	if err != nil {
+		return toStatus(ctx, err)
+	}
+
+	return stream.Send(&portfoliov1.RefreshWalletResponse{Stage: portfoliov1.RefreshWalletResponse_STAGE_DONE, Message: "stored", Wallet: toWallet(wallet)})
+}
+
+func (s *portfolioServer) GetPrices(ctx context.Context, req *portfoliov1.GetPricesRequest) (*portfoliov1.GetPricesResponse, error) {
+	var feeds []models.CoingeckoPriceFeed
+	var err error
+	if len(req.Assets) == 0 {
+		feeds, err = models.GetCoingeckoPriceFeeds(s.db.WithContext(ctx))
+	} else {
+		feeds, err = models.GetCoingeckoPriceFeedsByNames(s.db.WithContext(ctx), req.Assets)
+	}
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	// requested prices come in the order of the request
+	if len(req.Assets) > 0 {
+		order := make(map[string]int, len(req.Assets))
+		for i, asset := range req.Assets {
+			if _, ok := order[asset]; !ok {
+				order[asset] = i
+			}
+		}
+		sort.Slice(feeds, func(i, j int) bool { return order[feeds[i].Name] < order[feeds[j].Name] })
+	}
+
+	resp := &portfoliov1.GetPricesResponse{Prices: make([]*portfoliov1.Price, 0, len(feeds))}
+	for i := range feeds {
+		resp.Prices = append(resp.Prices, &portfoliov1.Price{
+			Asset:     feeds[i].Name,
+			Currency:  feeds[i].Currency,
+			Price:     feeds[i].Price.String(),
+			UpdatedAt: timestamppb.New(feeds[i].UpdatedAt),
+		})
+	}
+
+	return resp, nil
+}
+
+// loadWallet returns the stored wallet of an address or name with its assets, a status error when it is
+// invalid or not stored.
+func (s *portfolioServer) loadWallet(ctx context.Context, input string) (*models.GlobalWallet, error) {
+	_, address, err := services.ResolveAddress(ctx, input)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	wallet, err := services.LoadWallet(s.db.WithContext(ctx), address)
+	if er.Is(err, gorm.ErrRecordNotFound) {
+		return nil, status.Error(codes.NotFound, "wallet "+input+" is not stored")
+	}
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	return wallet, nil
+}
+
+func toWallet(wallet *models.GlobalWallet) *portfoliov1.Wallet {
+	w := &portfoliov1.Wallet{
+		Id:            int64(wallet.WalletID),
+		Address:       wallet.WalletAddress,
+		Name:          wallet.Name,
+		Chain:         chains[wallet.BlockchainType],
+		LastUpdatedAt: timestamppb.New(wallet.LastUpdatedAt),
+		ValueUsd:      wallet.ValueUSD().String(),
+	}
+
+	if balance, ok := services.CoinBalance(wallet); ok {
+		w.Balance = balance.String()
+	}
+
+	return w
+}
+
+func toHolding(h *services.Holding) *portfoliov1.Holding {
+	holding := &portfoliov1.Holding{
+		Asset:   h.Asset,
+		Symbol:  h.Symbol,
+		Name:    h.Name,
+		Network: h.Network,
+		Amount:  h.Amount.String(),
+		LogoUrl: h.LogoURL,
+	}
+
+	if h.PriceUsd != nil {
+		holding.PriceUsd = h.PriceUsd.String()
+	}
+	if h.ValueUsd != nil {
+		holding.ValueUsd = h.ValueUsd.String()
+	}
+
+	return holding
+}
+
+// encodePageToken returns an opaque token of the offset of a page.
+func encodePageToken(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("offset:" + strconv.Itoa(offset)))
+}
+
+func decodePageToken(token string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := strings.CutPrefix(string(decoded), "offset:")
+	if !ok {
+		return 0, er.New("not a page token")
+	}
+
+	offset, err := strconv.Atoi(value)
+	if err == nil && offset < 0 {
+		err = er.New("negative offset")
+	}
+
+	return offset, err
+}
@@ -0,0 +1,64 @@
+package rpc
+
+import (
+	"context"
+	"math"
+	"net"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+)
+
+// rateLimiter limits the calls of each method with the limits of the REST API: RATE_LIMIT_DEFAULT, or the
+// RATE_LIMIT_ROUTES entry of the full method, such as /portfolio.v1.PortfolioService/RefreshWallet. Calls are
+// counted in the store of the REST API, apart from its requests. A nil limiter limits nothing.
+type rateLimiter struct {
+	store  middlewares.RateLimitStore
+	config middlewares.RateLimitConfig
+}
+
+// allow counts a call of method against identity, rejecting it with ResourceExhausted and a retry-after
+// header once identity is over its limit: rateLimit requests per minute when it is set, the limit of method
+// otherwise. Calls without identity are not counted.
+func (l *rateLimiter) allow(ctx context.Context, method, identity string, rateLimit int) error {
+	if l == nil || l.store == nil || identity == "" {
+		return nil
+	}
+
+	limit := l.config.Limit(method)
+	if rateLimit > 0 {
+		limit = middlewares.RouteLimit{Requests: rateLimit, Window: time.Minute}
+	}
+
+	remaining, resetAt, ok := middlewares.CountRequest(l.store, method, identity, limit)
+	if !ok || remaining >= 0 {
+		return nil
+	}
+
+	retryAfter := int(math.Ceil(time.Until(resetAt).Seconds()))
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(retryAfter)))
+
+	return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %ds", retryAfter)
+}
+
+// peerIdentity returns the IP the call came from as a rate limit identity, empty when it is unknown.
+func peerIdentity(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return ""
+	}
+
+	return "ip:" + host
+}
@@ -0,0 +1,46 @@
+// Package rpc is the gRPC API of the service for internal consumers, next to the REST API and on its own port.
+// It serves portfolio.v1.PortfolioService over the same service layer as the REST and GraphQL endpoints.
+package rpc
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	"gorm.io/gorm"
+
+	portfoliov1 "github.com/0xbase-Corp/portfolio_svc/api/portfolio/v1"
+	"github.com/0xbase-Corp/portfolio_svc/internal/middlewares"
+)
+
+// Config is how the gRPC server serves its calls.
+type Config struct {
+	// MaxAddresses bounds the addresses of a GetPortfolio call.
+	MaxAddresses int
+
+	// RateLimits and RateLimitStore limit the calls by peer and by caller, as they limit the REST requests;
+	// calls are not limited without a store.
+	RateLimits     middlewares.RateLimitConfig
+	RateLimitStore middlewares.RateLimitStore
+
+	// Reflection registers the reflection service, which lists the methods to any caller.
+	Reflection bool
+}
+
+// NewServer returns a gRPC server of PortfolioService, and of the reflection service when config enables it.
+// Calls are traced and measured; calls of PortfolioService need credentials and are rate limited.
+func NewServer(db *gorm.DB, config Config) *grpc.Server {
+	limiter := &rateLimiter{store: config.RateLimitStore, config: config.RateLimits}
+
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unaryTracing(), unaryMetrics(), unaryInterceptor(db, limiter)),
+		grpc.ChainStreamInterceptor(streamTracing(), streamMetrics(), streamInterceptor(db, limiter)),
+	)
+
+	portfoliov1.RegisterPortfolioServiceServer(srv, &portfolioServer{db: db, maxAddresses: config.MaxAddresses})
+
+	if config.Reflection {
+		// lets grpcurl and the like list and call the methods without the proto files
+		reflection.Register(srv)
+	}
+
+	return srv
+}
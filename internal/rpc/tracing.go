@@ -0,0 +1,96 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
+)
+
+// metadataCarrier reads the W3C traceparent and tracestate of a call from its incoming metadata.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return first(metadata.MD(c), key)
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// unaryTracing records a span per unary call, as the Tracing middleware does per request.
+func unaryTracing() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		ctx, span := startCall(ctx, info.FullMethod)
+		defer func() { endCall(span, err) }()
+
+		return handler(ctx, req)
+	}
+}
+
+// streamTracing records a span per streaming call, as the Tracing middleware does per request.
+func streamTracing() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		ctx, span := startCall(stream.Context(), info.FullMethod)
+		defer func() { endCall(span, err) }()
+
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// startCall starts the server span of a call of method, continuing the trace of the caller when its metadata
+// carries one. The context returned carries the span, so queries and provider calls made with it are its
+// children.
+func startCall(ctx context.Context, method string) (context.Context, trace.Span) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+
+	service, name, _ := strings.Cut(strings.TrimPrefix(method, "/"), "/")
+
+	return tracing.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", name),
+	))
+}
+
+// endCall ends the span of a call, failed for the codes of server failures.
+func endCall(span trace.Span, err error) {
+	code := status.Code(err)
+	span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+
+	if serverFailure(code) {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+
+	span.End()
+}
+
+// serverFailure reports whether code is that of a call the server failed, rather than the caller.
+func serverFailure(code codes.Code) bool {
+	switch code {
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/events"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// Directions of alert rules.
+const (
+	AlertAbove = "above" // for portfolio_change, a rise
+	AlertBelow = "below" // for portfolio_change, a drop
+)
+
+// alertSnapshotInterval is the least time between two snapshots of the value of the wallets of a rule.
+const alertSnapshotInterval = 5 * time.Minute
+
+var hundred = decimal.NewFromInt(100)
+
+// Alert is what a rule notifies its channels of when it triggers.
+type Alert struct {
+	RuleID      int             `json:"rule_id"`
+	Name        string          `json:"name"`
+	Kind        string          `json:"kind"`
+	Asset       string          `json:"asset,omitempty"`
+	Direction   string          `json:"direction"`
+	Threshold   decimal.Decimal `json:"threshold"`
+	Value       decimal.Decimal `json:"value"` // the usd price, or the percent of the change or allocation
+	Message     string          `json:"message"`
+	TriggeredAt time.Time       `json:"triggered_at"`
+}
+
+// RunAlerts evaluates the enabled alert rules until ctx is done: price rules on the prices fetched, portfolio
+// rules on the balance changes and prices of their wallets and every interval. What the rules depend on is
+// watched, so that it is refreshed every interval even when no one requests it.
+func RunAlerts(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		followEvents(ctx, "alerts", func(work context.Context, event events.Event) {
+			if err := evaluateAlerts(work, db.WithContext(work), event); err != nil {
+				slog.ErrorContext(work, "alert rules not evaluated", slog.String("type", event.Type), slog.String("topic", event.Topic), slog.Any("error", err))
+			}
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		snapshotAlerts(ctx, db, interval)
+	}()
+	go func() {
+		defer wg.Done()
+		watchAlertDependencies(ctx, db, interval)
+	}()
+
+	wg.Wait()
+}
+
+// evaluateAlerts evaluates the rules event may trigger.
+func evaluateAlerts(ctx context.Context, db *gorm.DB, event events.Event) error {
+	index, err := loadAlertRules(db)
+	if err != nil {
+		return err
+	}
+
+	var price *decimal.Decimal
+	if tick, ok := event.Data.(PriceTick); ok && tick.Currency == "usd" {
+		price = &tick.Price
+	}
+
+	values := newPortfolioValues(db)
+	for _, rule := range index.affectedBy(event) {
+		if err := evaluateAlert(ctx, db, rule, price, values); err != nil {
+			slog.ErrorContext(ctx, "alert rule not evaluated", slog.Int("rule_id", rule.RuleID), slog.Any("error", err))
+		}
+	}
+
+	return nil
+}
+
+// snapshotAlerts evaluates the portfolio rules every interval until ctx is done, so that their snapshots are
+// taken, and they trigger, even when none of their wallets changed.
+func snapshotAlerts(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	work := context.WithoutCancel(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		index, err := loadAlertRules(db.WithContext(work))
+		if err != nil {
+			slog.WarnContext(work, "loading alert rules failed", slog.Any("error", err))
+			continue
+		}
+
+		values := newPortfolioValues(db.WithContext(work))
+		for _, rule := range index.portfolioRules() {
+			if err := evaluateAlert(work, db.WithContext(work), rule, nil, values); err != nil {
+				slog.ErrorContext(work, "alert rule not evaluated", slog.Int("rule_id", rule.RuleID), slog.Any("error", err))
+			}
+		}
+	}
+}
+
+// evaluateAlert triggers rule when its condition starts holding and it is not cooling down; a rule whose
+// condition keeps holding does not trigger again until it stopped holding. price is the price just fetched for
+// price rules, which are not evaluated without one. values are the values of the wallets of the rules evaluated
+// for the same event.
+func evaluateAlert(ctx context.Context, db *gorm.DB, rule *models.AlertRule, price *decimal.Decimal, values *portfolioValues) error {
+	now := time.Now().UTC()
+
+	var (
+		value   decimal.Decimal
+		holds   bool
+		message string
+	)
+
+	switch rule.Kind {
+	case models.AlertPrice:
+		if price == nil {
+			return nil
+		}
+
+		value = *price
+		holds = crossed(rule.Direction, value, rule.Threshold)
+		message = fmt.Sprintf("%s is %s %s usd, at %s usd", rule.Asset, rule.Direction, rule.Threshold, value)
+
+	case models.AlertPortfolioChange:
+		total, _, err := values.of(rule.WalletList())
+		if err != nil {
+			return err
+		}
+
+		if err := takeAlertSnapshot(db, rule, total, now); err != nil {
+			return err
+		}
+
+		base, err := models.GetAlertSnapshotAt(db, rule.RuleID, now.Add(-rule.Window()))
+		if err != nil || base == nil || !base.Value.IsPositive() {
+			return err
+		}
+
+		value, holds = portfolioChange(rule.Direction, base.Value, total, rule.Threshold)
+
+		moved := "rose"
+		if rule.Direction == AlertBelow {
+			moved = "dropped"
+		}
+		message = fmt.Sprintf("the portfolio %s %s%% in %s, to %s usd", moved, value.Abs(), rule.Window(), total.Round(2))
+
+	case models.AlertAllocation:
+		total, byChain, err := values.of(rule.WalletList())
+		if err != nil || !total.IsPositive() {
+			return err
+		}
+
+		value = byChain[rule.Asset].Div(total).Mul(hundred).Round(2)
+		holds = crossed(rule.Direction, value, rule.Threshold)
+		message = fmt.Sprintf("%s is %s%% of the portfolio, %s %s%%", rule.Asset, value, rule.Direction, rule.Threshold)
+
+	default:
+		return nil
+	}
+
+	if rule.Triggered == holds {
+		return nil
+	}
+
+	changed, err := models.SetAlertRuleTriggered(db, rule, holds)
+	if err != nil || !changed {
+		return err
+	}
+	InvalidateAlertRules()
+
+	if !holds || rule.CoolingDown(now) {
+		return nil
+	}
+
+	return fireAlert(ctx, db, rule, Alert{
+		RuleID:      rule.RuleID,
+		Name:        rule.Name,
+		Kind:        rule.Kind,
+		Asset:       rule.Asset,
+		Direction:   rule.Direction,
+		Threshold:   rule.Threshold,
+		Value:       value,
+		Message:     message,
+		TriggeredAt: now,
+	})
+}
+
+// portfolioChange returns the percent change from base to total, rounded, and whether it is a rise, or a drop
+// for direction below, of at least threshold percent.
+func portfolioChange(direction string, base, total, threshold decimal.Decimal) (decimal.Decimal, bool) {
+	change := total.Sub(base).Div(base).Mul(hundred)
+
+	moved := change
+	if direction == AlertBelow {
+		moved = change.Neg()
+	}
+
+	return change.Round(2), moved.GreaterThanOrEqual(threshold)
+}
+
+// crossed reports whether value is at or past threshold in direction.
+func crossed(direction string, value, threshold decimal.Decimal) bool {
+	if direction == AlertBelow {
+		return value.LessThanOrEqual(threshold)
+	}
+
+	return value.GreaterThanOrEqual(threshold)
+}
+
+// takeAlertSnapshot records the value of the wallets of rule, at most every alertSnapshotInterval, keeping
+// twice the window of the rule.
+func takeAlertSnapshot(db *gorm.DB, rule *models.AlertRule, value decimal.Decimal, now time.Time) error {
+	latest, err := models.GetLatestAlertSnapshot(db, rule.RuleID)
+	if err != nil {
+		return err
+	}
+
+	if latest != nil && now.Sub(latest.TakenAt) < alertSnapshotInterval {
+		return nil
+	}
+
+	return models.SaveAlertSnapshot(db, &models.AlertSnapshot{RuleID: rule.RuleID, Value: value, TakenAt: now}, now.Add(-2*rule.Window()))
+}
+
+// fireAlert delivers alert on every channel of rule and records it in the history of the rule, with the
+// channels that failed.
+func fireAlert(ctx context.Context, db *gorm.DB, rule *models.AlertRule, alert Alert) error {
+	delivered := make([]string, 0)
+	failures := make([]string, 0)
+
+	for _, name := range rule.ChannelList() {
+		channel, ok := alertChannel(name)
+		if !ok {
+			failures = append(failures, name+": channel not configured")
+			continue
+		}
+
+		if err := channel.Deliver(ctx, db, rule, alert); err != nil {
+			failures = append(failures, name+": "+err.Error())
+			continue
+		}
+
+		delivered = append(delivered, name)
+	}
+	metrics.AlertTriggered(rule.Kind, len(failures) == 0)
+
+	// the cooldown of the rule starts, reloaded with it
+	defer InvalidateAlertRules()
+
+	return models.RecordAlertTriggered(db, rule, &models.AlertHistory{
+		RuleID:      rule.RuleID,
+		Value:       alert.Value,
+		Message:     alert.Message,
+		Delivered:   strings.Join(delivered, ","),
+		Error:       strings.Join(failures, "; "),
+		TriggeredAt: alert.TriggeredAt,
+	})
+}
+
+// watchAlertDependencies watches the wallets of the enabled portfolio rules and the prices the rules depend
+// on until ctx is done.
+func watchAlertDependencies(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	keepWatching(ctx, interval, func() (map[string]struct{}, map[string]struct{}, error) {
+		index, err := loadAlertRules(db.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		wallets := make(map[string]struct{})
+		prices := make(map[string]struct{})
+		for _, rule := range index.rules {
+			if rule.Kind == models.AlertPrice {
+				prices[rule.Asset] = struct{}{}
+				continue
+			}
+
+			for _, wallet := range rule.WalletList() {
+				wallets[wallet] = struct{}{}
+			}
+			addWalletPrices(prices, rule.WalletList())
+		}
+
+		return wallets, prices, nil
+	})
+}
+
+// alertRulesMaxAge is how long the enabled rules are kept in memory. Rules changed through this instance are
+// reloaded at once, those changed through another one after at most this long.
+const alertRulesMaxAge = time.Minute
+
+// alertRuleIndex holds the enabled rules by what they depend on, as positions in rules.
+type alertRuleIndex struct {
+	rules    []models.AlertRule
+	byAsset  map[string][]int // price rules by coingecko id
+	byWallet map[string][]int // portfolio rules by chain:address
+	byCoin   map[string][]int // portfolio rules by the coingecko ids of the native coins of their wallets
+	loadedAt time.Time
+}
+
+var alertRules struct {
+	sync.Mutex
+	index *alertRuleIndex
+}
+
+// InvalidateAlertRules drops the enabled rules kept in memory, for the next evaluation to reload them.
+func InvalidateAlertRules() {
+	alertRules.Lock()
+	defer alertRules.Unlock()
+
+	alertRules.index = nil
+}
+
+// loadAlertRules returns the enabled rules, loading them when they were invalidated or are older than
+// alertRulesMaxAge.
+func loadAlertRules(db *gorm.DB) (*alertRuleIndex, error) {
+	alertRules.Lock()
+	defer alertRules.Unlock()
+
+	if index := alertRules.index; index != nil && time.Since(index.loadedAt) < alertRulesMaxAge {
+		return index, nil
+	}
+
+	rules, err := models.GetEnabledAlertRules(db)
+	if err != nil {
+		return nil, err
+	}
+
+	alertRules.index = newAlertRuleIndex(rules)
+
+	return alertRules.index, nil
+}
+
+func newAlertRuleIndex(rules []*models.AlertRule) *alertRuleIndex {
+	index := &alertRuleIndex{
+		rules:    make([]models.AlertRule, 0, len(rules)),
+		byAsset:  make(map[string][]int),
+		byWallet: make(map[string][]int),
+		byCoin:   make(map[string][]int),
+		loadedAt: time.Now(),
+	}
+
+	for i, rule := range rules {
+		index.rules = append(index.rules, *rule)
+
+		if rule.Kind == models.AlertPrice {
+			index.byAsset[rule.Asset] = append(index.byAsset[rule.Asset], i)
+			continue
+		}
+
+		coins := make(map[string]struct{})
+		for _, wallet := range utils.UniqueAddress(rule.WalletList()) {
+			index.byWallet[wallet] = append(index.byWallet[wallet], i)
+
+			chain, _, _ := strings.Cut(wallet, ":")
+			coin, ok := chainCoins[chain]
+			if !ok {
+				continue
+			}
+			if _, ok := coins[coin]; !ok {
+				coins[coin] = struct{}{}
+				index.byCoin[coin] = append(index.byCoin[coin], i)
+			}
+		}
+	}
+
+	return index
+}
+
+// affectedBy returns copies of the rules event may trigger: for a usd price, the price rules of the asset and
+// the portfolio rules with wallets on a chain whose native coin it is, see chainCoins; for a balance change,
+// the portfolio rules of the wallet.
+func (index *alertRuleIndex) affectedBy(event events.Event) []*models.AlertRule {
+	switch data := event.Data.(type) {
+	case PriceTick:
+		if data.Currency != "usd" {
+			return nil
+		}
+
+		return index.copies(index.byAsset[data.Asset], index.byCoin[data.Asset])
+
+	case BalanceChange:
+		return index.copies(index.byWallet[data.Chain+":"+data.Address])
+	}
+
+	return nil
+}
+
+// portfolioRules returns copies of the rules that are not price rules.
+func (index *alertRuleIndex) portfolioRules() []*models.AlertRule {
+	positions := make([]int, 0, len(index.rules))
+	for i, rule := range index.rules {
+		if rule.Kind != models.AlertPrice {
+			positions = append(positions, i)
+		}
+	}
+
+	return index.copies(positions)
+}
+
+// copies returns copies of the rules at positions, which evaluations may change without changing the index.
+func (index *alertRuleIndex) copies(positions ...[]int) []*models.AlertRule {
+	rules := make([]*models.AlertRule, 0)
+	for _, list := range positions {
+		for _, i := range list {
+			rule := index.rules[i]
+			rules = append(rules, &rule)
+		}
+	}
+
+	return rules
+}
+
+// portfolioValues memoizes the usd values of wallets, given as chain:address, over the evaluation of the rules
+// affected by the same event, so that a wallet several rules share is loaded once.
+type portfolioValues struct {
+	db     *gorm.DB
+	values map[string]decimal.Decimal
+}
+
+func newPortfolioValues(db *gorm.DB) *portfolioValues {
+	return &portfolioValues{db: db, values: make(map[string]decimal.Decimal)}
+}
+
+// of returns the value of wallets in total and by chain, like portfolioValue.
+func (v *portfolioValues) of(wallets []string) (decimal.Decimal, map[string]decimal.Decimal, error) {
+	total := decimal.Zero
+	byChain := make(map[string]decimal.Decimal)
+
+	for _, wallet := range wallets {
+		value, ok := v.values[wallet]
+		if !ok {
+			var err error
+			if value, _, err = portfolioValue(v.db, []string{wallet}); err != nil {
+				return decimal.Zero, nil, err
+			}
+			v.values[wallet] = value
+		}
+
+		chain, _, _ := strings.Cut(wallet, ":")
+		total = total.Add(value)
+		byChain[chain] = byChain[chain].Add(value)
+	}
+
+	return total, byChain, nil
+}
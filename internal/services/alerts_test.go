@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/events"
+)
+
+func TestCrossed(t *testing.T) {
+	tests := []struct {
+		direction string
+		value     string
+		threshold string
+		want      bool
+	}{
+		{AlertAbove, "101", "100", true},
+		{AlertAbove, "100", "100", true},
+		{AlertAbove, "99.99", "100", false},
+		{AlertBelow, "99", "100", true},
+		{AlertBelow, "100", "100", true},
+		{AlertBelow, "100.01", "100", false},
+	}
+
+	for _, tt := range tests {
+		got := crossed(tt.direction, decimal.RequireFromString(tt.value), decimal.RequireFromString(tt.threshold))
+		if got != tt.want {
+			t.Errorf("crossed(%s, %s, %s) = %v, want %v", tt.direction, tt.value, tt.threshold, got, tt.want)
+		}
+	}
+}
+
+func TestPortfolioChange(t *testing.T) {
+	tests := []struct {
+		name       string
+		direction  string
+		base       string
+		total      string
+		threshold  string
+		wantChange string
+		want       bool
+	}{
+		{"rise past threshold", AlertAbove, "1000", "1150", "10", "15", true},
+		{"rise under threshold", AlertAbove, "1000", "1050", "10", "5", false},
+		{"drop watched as rise", AlertAbove, "1000", "800", "10", "-20", false},
+		{"drop past threshold", AlertBelow, "1000", "850", "10", "-15", true},
+		{"drop at threshold", AlertBelow, "1000", "900", "10", "-10", true},
+		{"rise watched as drop", AlertBelow, "1000", "1200", "10", "20", false},
+		{"rounded", AlertAbove, "3", "4", "10", "33.33", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			change, holds := portfolioChange(tt.direction, decimal.RequireFromString(tt.base), decimal.RequireFromString(tt.total), decimal.RequireFromString(tt.threshold))
+			if !change.Equal(decimal.RequireFromString(tt.wantChange)) || holds != tt.want {
+				t.Errorf("portfolioChange() = %s, %v, want %s, %v", change, holds, tt.wantChange, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertRuleIndex(t *testing.T) {
+	index := newAlertRuleIndex([]*models.AlertRule{
+		{RuleID: 1, Kind: models.AlertPrice, Asset: "bitcoin"},
+		{RuleID: 2, Kind: models.AlertPrice, Asset: "solana"},
+		{RuleID: 3, Kind: models.AlertPortfolioChange, Wallets: "bitcoin:bc1qa,bitcoin:bc1qb,solana:So1"},
+		{RuleID: 4, Kind: models.AlertAllocation, Asset: "debank", Wallets: "debank:0xa,bitcoin:bc1qa"},
+	})
+
+	tests := []struct {
+		name string
+		data interface{}
+		want []int
+	}{
+		{"bitcoin price", PriceTick{Asset: "bitcoin", Currency: "usd"}, []int{1, 3, 4}},
+		{"solana price", PriceTick{Asset: "solana", Currency: "usd"}, []int{2, 3}},
+		{"ether price moves evm wallets", PriceTick{Asset: "ethereum", Currency: "usd"}, []int{4}},
+		{"chain names are not coingecko ids", PriceTick{Asset: "debank", Currency: "usd"}, nil},
+		{"price in eur", PriceTick{Asset: "bitcoin", Currency: "eur"}, nil},
+		{"unwatched price", PriceTick{Asset: "usd-coin", Currency: "usd"}, nil},
+		{"shared wallet", BalanceChange{Chain: "bitcoin", Address: "bc1qa"}, []int{3, 4}},
+		{"wallet of one rule", BalanceChange{Chain: "debank", Address: "0xa"}, []int{4}},
+		{"unwatched wallet", BalanceChange{Chain: "solana", Address: "So2"}, nil},
+		{"other event", "hello", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ruleIDs(index.affectedBy(events.Event{Data: tt.data}))
+			if !equalInts(got, tt.want) {
+				t.Errorf("affectedBy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := ruleIDs(index.portfolioRules()); !equalInts(got, []int{3, 4}) {
+		t.Errorf("portfolioRules() = %v, want [3 4]", got)
+	}
+
+	rules := index.affectedBy(events.Event{Data: PriceTick{Asset: "bitcoin", Currency: "usd"}})
+	rules[0].Triggered = true
+	if index.rules[0].Triggered {
+		t.Error("changing an affected rule changed the index")
+	}
+}
+
+func TestEvaluateAlertTransitions(t *testing.T) {
+	// without a transition evaluateAlert returns before it touches the database
+	tests := []struct {
+		name      string
+		triggered bool
+		price     string
+	}{
+		{"keeps holding", true, "120"},
+		{"keeps not holding", false, "80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := &models.AlertRule{Kind: models.AlertPrice, Asset: "bitcoin", Direction: AlertAbove, Threshold: decimal.NewFromInt(100), Triggered: tt.triggered}
+			price := decimal.RequireFromString(tt.price)
+
+			if err := evaluateAlert(context.Background(), nil, rule, &price, nil); err != nil {
+				t.Fatalf("evaluateAlert() = %v", err)
+			}
+			if rule.Triggered != tt.triggered || rule.LastTriggeredAt != nil {
+				t.Errorf("rule = triggered %v at %v, want it unchanged", rule.Triggered, rule.LastTriggeredAt)
+			}
+		})
+	}
+}
+
+func ruleIDs(rules []*models.AlertRule) []int {
+	ids := make([]int, 0, len(rules))
+	for _, rule := range rules {
+		ids = append(ids, rule.RuleID)
+	}
+	sort.Ints(ids)
+
+	return ids
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	er "errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// touchInterval limits how often last_used_at is written for a busy key.
+const touchInterval = time.Minute
+
+// AuthenticateToken returns the id of the user a JWT was issued to. Tokens of deleted users, or issued before
+// an operator revoked them, are no longer valid. Rejected tokens are unauthorized APIErrors.
+func AuthenticateToken(db *gorm.DB, token string) (int, error) {
+	claims, err := utils.ParseToken(token)
+	if err != nil {
+		return 0, errors.NewUnauthorizedError("invalid token")
+	}
+
+	user, err := models.GetUserById(db, claims.UserID)
+	if err != nil && !er.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.NewInternalError("failed to load user", err)
+	}
+	if err != nil || user.TokenRevoked(time.Unix(claims.IssuedAt, 0)) {
+		return 0, errors.NewUnauthorizedError("invalid token")
+	}
+
+	return claims.UserID, nil
+}
+
+// AuthenticateAPIKey returns the api key of key and records its use. Unknown and revoked keys are unauthorized
+// APIErrors.
+func AuthenticateAPIKey(db *gorm.DB, key string) (*models.APIKey, error) {
+	apiKey, err := models.GetAPIKeyByHash(db, utils.HashAPIKey(key))
+	if err != nil || apiKey.IsRevoked() {
+		return nil, errors.NewUnauthorizedError("invalid api key")
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > touchInterval {
+		// last-used tracking must not fail the request
+		_ = models.TouchAPIKey(db, apiKey)
+	}
+
+	return apiKey, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers"
+	"github.com/0xbase-Corp/portfolio_svc/providers/bitcoin"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// RefreshBitcoin fetches a bitcoin address from btc.com and stores it.
+func RefreshBitcoin(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string) (*models.GlobalWallet, error) {
+	wallet, err := refreshBitcoin(ctx, db, apiClient, address)
+	if err != nil {
+		publishRefreshFailure(utils.Bitcoin, address, err)
+	}
+
+	return wallet, err
+}
+
+func refreshBitcoin(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string) (*models.GlobalWallet, error) {
+	body, err := fetchCached(ctx, utils.Bitcoin, apiClient, address)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := bitcoin.BtcApiResponse{}
+	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Status == "fail" {
+		// btc.com answers unknown addresses and its own failures with a 200
+		return nil, errors.NewUpstreamFailure(utils.ProviderBtcCom, http.StatusOK, resp.Message)
+	}
+
+	return SaveBitcoin(ctx, db, address, resp)
+}
+
+// SaveBitcoin stores the btc.com data of an address with the current bitcoin price and returns the wallet.
+func SaveBitcoin(ctx context.Context, db *gorm.DB, btcAddress string, apiResponse bitcoin.BtcApiResponse) (*models.GlobalWallet, error) {
+	ctx, span := tracing.Start(ctx, "save "+utils.Bitcoin+" wallet", trace.WithAttributes(attribute.String("wallet.address", btcAddress)))
+
+	start := time.Now()
+	walletResponse, changes, err := saveBitcoin(ctx, db.WithContext(ctx), btcAddress, apiResponse)
+	metrics.ObserveWalletSave(utils.Bitcoin, time.Since(start), err)
+	tracing.End(span, err)
+
+	if err == nil {
+		publishBalanceChanges(utils.Bitcoin, btcAddress, changes)
+	}
+
+	return walletResponse, err
+}
+
+func saveBitcoin(ctx context.Context, db *gorm.DB, btcAddress string, apiResponse bitcoin.BtcApiResponse) (*models.GlobalWallet, []models.AssetChange, error) {
+	// Begin a new transaction
+	tx := db.Begin()
+
+	wallet, created, err := models.GetOrCreateWallet(tx, btcAddress, utils.Bitcoin)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Assuming wallet is the GlobalWallet record found or created
+	walletID := wallet.WalletID
+
+	// Initialize btcComV1 and set the WalletID
+	btcComV1 := models.BitcoinBtcComV1{}
+	btcComV1.WalletID = uint(walletID)
+
+	// Proceed to call SaveBitcoinData with the updated BitcoinAddressInfo and btcComV1
+	changes, err := models.SaveBitcoinData(tx, &apiResponse.Data, &btcComV1)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// save the bitcoin price feed
+	// for now hard code the USD -> TODO: change
+	if err := HandleCoingeckoPrice(ctx, tx, utils.Bitcoin, "usd"); err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// get the data
+	walletResponse, err := models.GetGlobalWalletWithBitcoinInfo(db, btcAddress)
+	if err != nil {
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// a wallet saved for the first time did not change, it is new
+	if created {
+		changes = nil
+	}
+
+	return walletResponse, changes, nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/0xbase-Corp/portfolio_svc/providers"
+	"github.com/0xbase-Corp/portfolio_svc/shared/cache"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// CacheTTL returns how long the provider responses and portfolios of chain are cached.
+func CacheTTL(chain string) time.Duration {
+	switch chain {
+	case utils.Bitcoin:
+		return configs.EnvConfigVars.CacheBitcoinTTL
+	case utils.Solana:
+		return configs.EnvConfigVars.CacheSolanaTTL
+	case utils.Debank:
+		return configs.EnvConfigVars.CacheDebankTTL
+	}
+
+	return 0
+}
+
+// fetchCached returns the provider response for address, calling the provider at most once per CacheTTL
+// and once for concurrent requests of the same address.
+func fetchCached(ctx context.Context, chain string, apiClient providers.APIClient, address string) ([]byte, error) {
+	body, _, err := cache.Load(ctx, "provider:"+chain+":"+address, CacheTTL(chain), func() ([]byte, error) {
+		return apiClient.FetchData(ctx, address)
+	})
+
+	return body, err
+}
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/mail"
+)
+
+// Channels alerts are delivered on.
+const (
+	AlertChannelWebhook = "webhook" // a delivery to a webhook of the user, see CreateWebhookController
+	AlertChannelEmail   = "email"   // available once RegisterAlertChannel was given an EmailAlertChannel
+)
+
+// AlertChannel delivers the alerts of the rules that list it.
+type AlertChannel interface {
+	// Deliver sends alert to the target rule set for the channel.
+	Deliver(ctx context.Context, db *gorm.DB, rule *models.AlertRule, alert Alert) error
+}
+
+var (
+	alertChannelsMu sync.RWMutex
+	alertChannels   = map[string]AlertChannel{AlertChannelWebhook: WebhookAlertChannel{}}
+)
+
+// RegisterAlertChannel makes channel available to alert rules as name, replacing the channel of that name.
+func RegisterAlertChannel(name string, channel AlertChannel) {
+	alertChannelsMu.Lock()
+	defer alertChannelsMu.Unlock()
+
+	alertChannels[name] = channel
+}
+
+// AlertChannels returns the names of the channels alert rules can use, sorted.
+func AlertChannels() []string {
+	alertChannelsMu.RLock()
+	defer alertChannelsMu.RUnlock()
+
+	names := make([]string, 0, len(alertChannels))
+	for name := range alertChannels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func alertChannel(name string) (AlertChannel, bool) {
+	alertChannelsMu.RLock()
+	defer alertChannelsMu.RUnlock()
+
+	channel, ok := alertChannels[name]
+
+	return channel, ok
+}
+
+// WebhookAlertChannel records an alert.triggered delivery to the webhook of the rule, which is then signed and
+// retried like the other deliveries of the webhook.
+type WebhookAlertChannel struct{}
+
+func (WebhookAlertChannel) Deliver(ctx context.Context, db *gorm.DB, rule *models.AlertRule, alert Alert) error {
+	if rule.WebhookID == nil {
+		return er.New("no webhook set")
+	}
+
+	webhook, err := models.GetWebhook(db, rule.UserID, nil, *rule.WebhookID)
+	if err != nil {
+		return err
+	}
+
+	if webhook.IsDisabled() {
+		return er.New("webhook was deleted")
+	}
+
+	_, err = createDelivery(db, webhook, WebhookAlertTriggered, 0, alert)
+
+	return err
+}
+
+// EmailAlertChannel mails alerts to the email of the account of the rule, never to another address.
+type EmailAlertChannel struct {
+	Sender mail.Sender
+}
+
+func (e EmailAlertChannel) Deliver(ctx context.Context, db *gorm.DB, rule *models.AlertRule, alert Alert) error {
+	user, err := models.GetUserById(db, rule.UserID)
+	if err != nil {
+		return err
+	}
+
+	if user.Email == "" {
+		return er.New("the account has no email")
+	}
+
+	return e.Sender.Send(ctx, mail.Message{
+		To:      []string{user.Email},
+		Subject: "Alert: " + rule.Name,
+		Body: fmt.Sprintf("%s\n\nRule: %s (%s)\nTriggered at: %s\n",
+			alert.Message, rule.Name, rule.Kind, alert.TriggeredAt.Format("2006-01-02 15:04:05 MST")),
+	})
+}
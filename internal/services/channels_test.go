@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/mail"
+)
+
+// recordingSender records the messages it is given instead of sending them.
+type recordingSender struct {
+	sent []mail.Message
+}
+
+func (s *recordingSender) Send(_ context.Context, msg mail.Message) error {
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+// usersDB returns a database whose queries are not run, users load with email.
+func usersDB(t *testing.T, email string) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1 port=1"}), &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatalf("gorm.Open() = %v", err)
+	}
+
+	err = db.Callback().Query().After("gorm:query").Register("test:user", func(tx *gorm.DB) {
+		if user, ok := tx.Statement.Dest.(*models.User); ok {
+			user.UserId, user.Email = 7, email
+		}
+	})
+	if err != nil {
+		t.Fatalf("Register() = %v", err)
+	}
+
+	return db
+}
+
+func TestEmailAlertChannel(t *testing.T) {
+	rule := &models.AlertRule{RuleID: 1, UserID: 7, Name: "bitcoin above 100k", Kind: models.AlertPrice}
+	alert := Alert{RuleID: 1, Message: "bitcoin is at 100001 usd", TriggeredAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)}
+
+	t.Run("mails the account", func(t *testing.T) {
+		sender := &recordingSender{}
+		if err := (EmailAlertChannel{Sender: sender}).Deliver(context.Background(), usersDB(t, "user@example.com"), rule, alert); err != nil {
+			t.Fatalf("Deliver() = %v", err)
+		}
+
+		if len(sender.sent) != 1 {
+			t.Fatalf("sent %d messages, want 1", len(sender.sent))
+		}
+		msg := sender.sent[0]
+		if len(msg.To) != 1 || msg.To[0] != "user@example.com" || msg.Subject != "Alert: bitcoin above 100k" {
+			t.Errorf("message to %v about %q, want the account's email about the rule", msg.To, msg.Subject)
+		}
+		for _, want := range []string{alert.Message, "Rule: bitcoin above 100k (price)", "2026-10-19 12:00:00 UTC"} {
+			if !strings.Contains(msg.Body, want) {
+				t.Errorf("body = %q, want %q", msg.Body, want)
+			}
+		}
+	})
+
+	t.Run("account without email", func(t *testing.T) {
+		sender := &recordingSender{}
+		if err := (EmailAlertChannel{Sender: sender}).Deliver(context.Background(), usersDB(t, ""), rule, alert); err == nil {
+			t.Error("Deliver() = nil, want an error")
+		}
+		if len(sender.sent) != 0 {
+			t.Errorf("sent %v, want nothing", sender.sent)
+		}
+	})
+}
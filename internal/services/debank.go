@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers"
+	"github.com/0xbase-Corp/portfolio_svc/providers/debank"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// RefreshDebank fetches an EVM address from debank and stores it.
+func RefreshDebank(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string) (*models.GlobalWallet, error) {
+	wallet, err := refreshDebank(ctx, db, apiClient, address)
+	if err != nil {
+		publishRefreshFailure(utils.Debank, address, err)
+	}
+
+	return wallet, err
+}
+
+func refreshDebank(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string) (*models.GlobalWallet, error) {
+	body, err := fetchCached(ctx, utils.Debank, apiClient, address)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := debank.EvmDebankTotalBalanceApiResponse{}
+	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return SaveDebank(ctx, db, address, resp)
+}
+
+// SaveDebank stores the debank data of an address and returns the wallet.
+func SaveDebank(ctx context.Context, db *gorm.DB, address string, apiResponse debank.EvmDebankTotalBalanceApiResponse) (*models.GlobalWallet, error) {
+	ctx, span := tracing.Start(ctx, "save "+utils.Debank+" wallet", trace.WithAttributes(attribute.String("wallet.address", address)))
+
+	start := time.Now()
+	walletResponse, changes, err := saveDebank(db.WithContext(ctx), address, apiResponse)
+	metrics.ObserveWalletSave(utils.Debank, time.Since(start), err)
+	tracing.End(span, err)
+
+	if err == nil {
+		publishBalanceChanges(utils.Debank, address, changes)
+	}
+
+	return walletResponse, err
+}
+
+func saveDebank(db *gorm.DB, address string, apiResponse debank.EvmDebankTotalBalanceApiResponse) (*models.GlobalWallet, []models.AssetChange, error) {
+	// Begin a new transaction
+	tx := db.Begin()
+
+	wallet, created, err := models.GetOrCreateWallet(tx, address, utils.Debank)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Initialize EvmAssetsDebankV1 and set the WalletID
+	evmAssetsDebankV1 := models.EvmAssetsDebankV1{
+		WalletID:      wallet.WalletID,
+		TotalUsdValue: apiResponse.TotalUsdValue,
+	}
+
+	// Save EvmAssetsDebankV1
+	if err = models.CreateOrUpdateEvmAssetsDebankV1(tx, &evmAssetsDebankV1); err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Save Chain
+	if err = models.SaveChainDetails(tx, wallet.WalletID, apiResponse.ChainList); err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Save token list
+	tokenChanges, err := models.SaveTokenListByEvmAssetsDebankV1ID(tx, evmAssetsDebankV1.EvmAssetID, apiResponse.TokensList)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Save nft list
+	nftChanges, err := models.SaveNFTSListByEvmAssetsDebankV1ID(tx, evmAssetsDebankV1.EvmAssetID, apiResponse.NFTList)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// get the data
+	walletResponse, err := models.GetGlobalWalletWithEvmDebankInfo(db, address)
+	if err != nil {
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	changes := append(tokenChanges, nftChanges...)
+
+	// a wallet saved for the first time did not change, it is new
+	if created {
+		changes = nil
+	}
+
+	return walletResponse, changes, nil
+}
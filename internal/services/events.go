@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/events"
+)
+
+type (
+	// PriceTick is the data of a price event.
+	PriceTick struct {
+		Asset    string          `json:"asset"` // coingecko id, e.g. bitcoin
+		Currency string          `json:"currency"`
+		Price    decimal.Decimal `json:"price"`
+	}
+
+	// BalanceChange is the data of a balance event, the assets of a wallet whose amount changed when it was
+	// saved over its stored rows.
+	BalanceChange struct {
+		Chain   string               `json:"chain"`
+		Address string               `json:"address"`
+		Changes []models.AssetChange `json:"changes"`
+	}
+
+	// RefreshFailure is the data of a refresh_failed event.
+	RefreshFailure struct {
+		Chain   string `json:"chain"`
+		Address string `json:"address"`
+		Error   string `json:"error"`
+	}
+)
+
+// followEventsBuffer is the number of events a follower may fall behind before it has to catch up.
+const followEventsBuffer = 1024
+
+// followEvents calls handle with every event published until ctx is done, in order and once each. A follower
+// that lagged behind the bus catches up from the events it keeps. handle is given a context that is not
+// cancelled, so that the work of an event finishes when ctx ends.
+func followEvents(ctx context.Context, name string, handle func(ctx context.Context, event events.Event)) {
+	sub := events.SubscribeAll(followEventsBuffer)
+	defer func() { sub.Close() }()
+
+	work := context.WithoutCancel(ctx)
+
+	last := events.LastID()
+	follow := func(event events.Event) {
+		if event.ID <= last {
+			return
+		}
+		last = event.ID
+
+		handle(work, event)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.C():
+			if ok {
+				follow(event)
+				continue
+			}
+
+			sub = events.SubscribeAll(followEventsBuffer)
+
+			missed, complete := events.SinceAll(last)
+			if !complete {
+				slog.WarnContext(work, "event follower lagged, some events were missed", slog.String("follower", name), slog.Uint64("last_event_id", last))
+			}
+			for _, event := range missed {
+				follow(event)
+			}
+		}
+	}
+}
+
+// publishPrice publishes a price fetched from coingecko.
+func publishPrice(feed *models.CoingeckoPriceFeed) {
+	events.Publish(events.PriceTopic(feed.Name, feed.Currency), events.TypePrice, PriceTick{Asset: feed.Name, Currency: feed.Currency, Price: feed.Price})
+}
+
+// publishBalanceChanges publishes the changes found when the wallet of address was saved.
+func publishBalanceChanges(chain, address string, changes []models.AssetChange) {
+	if len(changes) == 0 {
+		return
+	}
+
+	events.Publish(events.WalletTopic(address), events.TypeBalance, BalanceChange{Chain: chain, Address: address, Changes: changes})
+}
+
+// publishRefreshFailure publishes that the wallet of address could not be fetched or saved.
+func publishRefreshFailure(chain, address string, err error) {
+	events.Publish(events.WalletTopic(address), events.TypeRefreshFailed, RefreshFailure{Chain: chain, Address: address, Error: err.Error()})
+}
@@ -0,0 +1,206 @@
+package services
+
+import (
+	er "errors"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type (
+	// Holding is an amount of a coin or token held by a wallet, whatever the provider.
+	Holding struct {
+		Asset    string // coingecko id of coins, mint of solana tokens, debank id of evm tokens
+		Symbol   string
+		Name     string
+		Network  string // the evm chain of evm tokens, the chain of the wallet otherwise
+		Amount   decimal.Decimal
+		PriceUsd *decimal.Decimal
+		ValueUsd *decimal.Decimal
+		LogoURL  string
+	}
+
+	// NFT is an NFT held by a wallet, whatever the provider.
+	NFT struct {
+		Contract   string // mint of solana NFTs
+		TokenID    string
+		Network    string
+		Name       string
+		Symbol     string
+		Collection string
+		Amount     decimal.Decimal
+		ImageURL   string
+		PriceUsd   *decimal.Decimal
+	}
+)
+
+// coins are the coingecko ids of the native coins of bitcoin and solana wallets, which are also their
+// blockchain types.
+var coins = map[string]struct{ symbol, name string }{
+	utils.Bitcoin: {"BTC", "Bitcoin"},
+	utils.Solana:  {"SOL", "Solana"},
+}
+
+// HasCoin reports whether the wallets of chain hold a native coin priced by the coingecko feed of the chain.
+func HasCoin(chain string) bool {
+	_, ok := coins[chain]
+	return ok
+}
+
+// CoinBalance returns the native coin balance of a bitcoin or solana wallet loaded with its chain info, false
+// for evm wallets and wallets not fetched yet.
+func CoinBalance(wallet *models.GlobalWallet) (decimal.Decimal, bool) {
+	if btc := wallet.BitcoinBtcComV1; btc != nil && btc.BitcoinAddressInfo != nil {
+		return btc.BitcoinAddressInfo.BalanceBTC(), true
+	}
+
+	if sol := wallet.SolanaAssetsMoralisV1; sol != nil {
+		return sol.Solana, true
+	}
+
+	return decimal.Zero, false
+}
+
+// WithCoinPrice returns a copy of a wallet loaded with its chain info carrying feed as the price of its coin, so
+// that wallets shared by concurrent readers are not modified.
+func WithCoinPrice(wallet *models.GlobalWallet, feed *models.CoingeckoPriceFeed) *models.GlobalWallet {
+	priced := *wallet
+
+	if btc := wallet.BitcoinBtcComV1; btc != nil {
+		btc := *btc
+		btc.CoingeckoPriceFeed = feed
+		priced.BitcoinBtcComV1 = &btc
+	}
+	if sol := wallet.SolanaAssetsMoralisV1; sol != nil {
+		sol := *sol
+		sol.CoingeckoPriceFeed = feed
+		priced.SolanaAssetsMoralisV1 = &sol
+	}
+
+	return &priced
+}
+
+// coinPrice returns the price feed set on the coin of a wallet, nil when it is not priced.
+func coinPrice(wallet *models.GlobalWallet) *models.CoingeckoPriceFeed {
+	if btc := wallet.BitcoinBtcComV1; btc != nil {
+		return btc.CoingeckoPriceFeed
+	}
+
+	if sol := wallet.SolanaAssetsMoralisV1; sol != nil {
+		return sol.CoingeckoPriceFeed
+	}
+
+	return nil
+}
+
+// Holdings returns the coin balance and the tokens of a wallet loaded with its chain info, coin first. The coin
+// is priced at the feed set on the wallet, as LoadWallet and WithCoinPrice set it; solana
+// tokens are not priced.
+func Holdings(wallet *models.GlobalWallet) []*Holding {
+	list := make([]*Holding, 0)
+
+	if balance, ok := CoinBalance(wallet); ok {
+		coin := coins[wallet.BlockchainType]
+		h := &Holding{Asset: wallet.BlockchainType, Symbol: coin.symbol, Name: coin.name, Network: wallet.BlockchainType, Amount: balance}
+
+		if feed := coinPrice(wallet); feed != nil {
+			value := balance.Mul(feed.Price)
+			h.PriceUsd, h.ValueUsd = &feed.Price, &value
+		}
+
+		list = append(list, h)
+	}
+
+	if sol := wallet.SolanaAssetsMoralisV1; sol != nil && sol.Tokens != nil {
+		for _, token := range *sol.Tokens {
+			list = append(list, &Holding{Asset: token.Mint, Symbol: token.Symbol, Name: token.Name, Network: utils.Solana, Amount: token.Amount})
+		}
+	}
+
+	if evm := wallet.EvmAssetsDebankV1; evm != nil && evm.TokenList != nil {
+		for i := range *evm.TokenList {
+			token := &(*evm.TokenList)[i]
+			amount := token.Quantity()
+			value := amount.Mul(token.Price)
+
+			symbol := token.OptimizedSymbol
+			if symbol == "" {
+				symbol = token.Symbol
+			}
+
+			list = append(list, &Holding{
+				Asset:    token.ID,
+				Symbol:   symbol,
+				Name:     token.Name,
+				Network:  token.Chain,
+				Amount:   amount,
+				PriceUsd: &token.Price,
+				ValueUsd: &value,
+				LogoURL:  token.LogoURL,
+			})
+		}
+	}
+
+	return list
+}
+
+// NFTs returns the NFTs of a wallet loaded with its chain info.
+func NFTs(wallet *models.GlobalWallet) []*NFT {
+	list := make([]*NFT, 0)
+
+	if sol := wallet.SolanaAssetsMoralisV1; sol != nil && sol.NFTS != nil {
+		for _, n := range *sol.NFTS {
+			list = append(list, &NFT{Contract: n.Mint, Network: utils.Solana, Name: n.Name, Symbol: n.Symbol, Amount: n.AmountRaw})
+		}
+	}
+
+	if evm := wallet.EvmAssetsDebankV1; evm != nil && evm.NFTList != nil {
+		for i := range *evm.NFTList {
+			n := &(*evm.NFTList)[i]
+			list = append(list, &NFT{
+				Contract:   n.ContractID,
+				TokenID:    n.InnerID,
+				Network:    n.Chain,
+				Name:       n.Name,
+				Collection: n.ContractName,
+				Amount:     decimal.NewFromInt(n.Amount),
+				ImageURL:   n.ThumbnailURL,
+				PriceUsd:   &n.USDPrice,
+			})
+		}
+	}
+
+	return list
+}
+
+// LoadWallet returns the stored wallet of address with its chain info, tokens and NFTs, its coin priced at the
+// stored price when there is one. It returns gorm.ErrRecordNotFound for addresses not stored yet.
+func LoadWallet(db *gorm.DB, address string) (*models.GlobalWallet, error) {
+	wallet, err := models.GetWallet(db, address)
+	if err != nil {
+		return nil, err
+	}
+
+	wallets, err := models.GetGlobalWalletsWithAssets(db, []int{wallet.WalletID})
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	wallet = wallets[0]
+
+	if !HasCoin(wallet.BlockchainType) {
+		return wallet, nil
+	}
+
+	feed, err := models.GetCoingeckoPriceFeedByName(db, wallet.BlockchainType)
+	if err != nil && !er.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	return WithCoinPrice(wallet, feed), nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers/coingecko"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// HandleCoingeckoPrice refreshes the stored price of cryptoID when it is older than COINGECKO_PRICE_MAX_AGE.
+func HandleCoingeckoPrice(ctx context.Context, tx *gorm.DB, cryptoID, currency string) error {
+	fetched, _ := models.GetCoingeckoPriceFeedByName(tx, cryptoID)
+
+	if fetched != nil {
+		now, err := utils.GetDBTime()
+		if err != nil {
+			return err
+		}
+
+		// Calculate the duration between the timestamps
+		duration := now.Sub(fetched.UpdatedAt)
+
+		// keep the stored price while coingecko is unavailable or its daily budget is nearly spent
+		if duration > configs.EnvConfigVars.CoingeckoPriceMaxAge && !UseStoredData(utils.ProviderCoingecko) {
+			metrics.PriceCacheMiss()
+			if err := fetchAndSaveCoingeckoPriceForCrypto(ctx, tx, cryptoID, currency); err != nil {
+				return err
+			}
+		} else {
+			metrics.PriceCacheHit()
+		}
+	} else {
+		metrics.PriceCacheMiss()
+		if err := fetchAndSaveCoingeckoPriceForCrypto(ctx, tx, cryptoID, currency); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func fetchAndSaveCoingeckoPriceForCrypto(ctx context.Context, db *gorm.DB, cryptoID, currency string) error {
+	priceFeedClient := &coingecko.CoingeckoAPI{}
+
+	body, err := priceFeedClient.FetchData(ctx, cryptoID, currency)
+	if err != nil {
+		return err
+	}
+
+	resp := coingecko.CryptoResponse{}
+
+	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
+		return err
+	}
+
+	priceFeed := &models.CoingeckoPriceFeed{
+		Name:     cryptoID,
+		Price:    resp[cryptoID][currency],
+		Currency: currency,
+	}
+
+	if err := models.UpdateOrCreateCoingeckoPriceFeed(db, priceFeed); err != nil {
+		return err
+	}
+
+	// published even when the wallet save around it rolls back, the price was fetched all the same
+	publishPrice(priceFeed)
+
+	return nil
+}
+
+// RefreshPrices fetches the current price of every stored price feed, whatever its age, and returns the
+// updated feeds.
+func RefreshPrices(ctx context.Context, db *gorm.DB) ([]models.CoingeckoPriceFeed, error) {
+	feeds, err := models.GetCoingeckoPriceFeeds(db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, feed := range feeds {
+		if err := fetchAndSaveCoingeckoPriceForCrypto(ctx, db, feed.Name, feed.Currency); err != nil {
+			return nil, err
+		}
+	}
+
+	return models.GetCoingeckoPriceFeeds(db)
+}
+
+// BackfillPriceHistory stores the daily prices of cryptoID in currency over the last days and returns how
+// many were stored. Prices already stored for a day are overwritten.
+func BackfillPriceHistory(ctx context.Context, db *gorm.DB, cryptoID, currency string, days int) (int, error) {
+	priceFeedClient := &coingecko.CoingeckoAPI{}
+
+	body, err := priceFeedClient.FetchHistory(ctx, cryptoID, currency, days)
+	if err != nil {
+		return 0, err
+	}
+
+	resp := coingecko.MarketChartResponse{}
+	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
+		return 0, err
+	}
+
+	prices := make([]models.CoingeckoPriceHistory, 0, len(resp.Prices))
+	for _, point := range resp.Prices {
+		prices = append(prices, models.CoingeckoPriceHistory{
+			Name:     cryptoID,
+			Currency: currency,
+			Price:    point[1],
+			PricedAt: time.UnixMilli(point[0].IntPart()).UTC(),
+		})
+	}
+
+	if err := models.SaveCoingeckoPriceHistory(db, prices); err != nil {
+		return 0, err
+	}
+
+	return len(prices), nil
+}
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"github.com/0xbase-Corp/portfolio_svc/shared/breaker"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+)
+
+// UseStoredData reports whether stored data should be served instead of calling the provider,
+// either because its circuit breaker is open or because its daily budget is nearly spent.
+func UseStoredData(provider string) bool {
+	return breaker.IsOpen(provider) || ratelimit.NearBudget(provider)
+}
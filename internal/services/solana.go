@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers"
+	"github.com/0xbase-Corp/portfolio_svc/providers/solana"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// RefreshSolana fetches a solana address from moralis and stores it.
+func RefreshSolana(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string) (*models.GlobalWallet, error) {
+	wallet, err := refreshSolana(ctx, db, apiClient, address)
+	if err != nil {
+		publishRefreshFailure(utils.Solana, address, err)
+	}
+
+	return wallet, err
+}
+
+func refreshSolana(ctx context.Context, db *gorm.DB, apiClient providers.APIClient, address string) (*models.GlobalWallet, error) {
+	body, err := fetchCached(ctx, utils.Solana, apiClient, address)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := solana.SolanaApiResponse{}
+	if err := utils.DecodeJSONResponse(body, &resp); err != nil {
+		return nil, err
+	}
+
+	return SaveSolana(ctx, db, address, resp)
+}
+
+// SaveSolana stores the moralis data of an address with the current solana price and returns the wallet.
+func SaveSolana(ctx context.Context, db *gorm.DB, solanaAddress string, apiResponse solana.SolanaApiResponse) (*models.GlobalWallet, error) {
+	ctx, span := tracing.Start(ctx, "save "+utils.Solana+" wallet", trace.WithAttributes(attribute.String("wallet.address", solanaAddress)))
+
+	start := time.Now()
+	walletResponse, changes, err := saveSolana(ctx, db.WithContext(ctx), solanaAddress, apiResponse)
+	metrics.ObserveWalletSave(utils.Solana, time.Since(start), err)
+	tracing.End(span, err)
+
+	if err == nil {
+		publishBalanceChanges(utils.Solana, solanaAddress, changes)
+	}
+
+	return walletResponse, err
+}
+
+func saveSolana(ctx context.Context, db *gorm.DB, solanaAddress string, apiResponse solana.SolanaApiResponse) (*models.GlobalWallet, []models.AssetChange, error) {
+	// Begin a new transaction
+	tx := db.Begin()
+
+	wallet, created, err := models.GetOrCreateWallet(tx, solanaAddress, utils.Solana)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Assuming wallet is the GlobalWallet record found or created
+	walletID := wallet.WalletID
+
+	// Initialize solanaAsset and set the WalletID
+	solanaAsset := models.SolanaAssetsMoralisV1{}
+	solanaAsset.WalletID = walletID
+	solanaAsset.Lamports = apiResponse.NativeBalance.Lamports
+	solanaAsset.Solana = apiResponse.NativeBalance.Solana
+
+	// Attempt to save the Solana asset data along with the associated tokens and NFTs.
+	changes, err := models.SaveSolanaData(tx, &solanaAsset, apiResponse.Tokens, apiResponse.NFTs)
+	if err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// save the solana price feed
+	// for now hard code the USD -> TODO: change
+	if err := HandleCoingeckoPrice(ctx, tx, utils.Solana, "usd"); err != nil {
+		tx.Rollback()
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// Commit the transaction
+	if err := tx.Commit().Error; err != nil {
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// get the data
+	walletResponse, err := models.GetGlobalWalletWithSolanaInfo(db, solanaAddress)
+	if err != nil {
+		return &models.GlobalWallet{}, nil, err
+	}
+
+	// a wallet saved for the first time did not change, it is new
+	if created {
+		changes = nil
+	}
+
+	return walletResponse, changes, nil
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/providers/bitcoin"
+	"github.com/0xbase-Corp/portfolio_svc/providers/debank"
+	"github.com/0xbase-Corp/portfolio_svc/providers/names"
+	"github.com/0xbase-Corp/portfolio_svc/providers/solana"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+	"github.com/0xbase-Corp/portfolio_svc/shared/validation"
+)
+
+// RefreshWallet fetches an address of chain from its provider and stores it, even when the stored data is
+// recent.
+func RefreshWallet(ctx context.Context, db *gorm.DB, chain, address string) (*models.GlobalWallet, error) {
+	if err := validation.Validate(chain, strings.TrimSpace(address)); err != nil {
+		return nil, fmt.Errorf("%s address %s: %w", chain, address, err)
+	}
+	address = validation.Normalize(chain, address)
+
+	switch chain {
+	case utils.Bitcoin:
+		return RefreshBitcoin(ctx, db, &bitcoin.BitcoinAPI{}, address)
+	case utils.Solana:
+		return RefreshSolana(ctx, db, &solana.SolanaAPI{}, address)
+	case utils.Debank:
+		return RefreshDebank(ctx, db, &debank.DebankAPI{}, address)
+	}
+
+	return nil, validation.ErrUnsupportedChain
+}
+
+// WalletMaxAge returns how long a stored wallet of chain is fresh enough to be served without fetching it
+// again: DEBANK_WALLET_MAX_AGE for evm wallets, as long as their responses are cached for the other chains.
+func WalletMaxAge(chain string) time.Duration {
+	if chain == utils.Debank {
+		return configs.EnvConfigVars.DebankWalletMaxAge
+	}
+
+	return CacheTTL(chain)
+}
+
+// WalletFresh reports whether a stored wallet was updated within the max age of its chain.
+func WalletFresh(wallet *models.GlobalWallet) bool {
+	return time.Since(wallet.LastUpdatedAt) <= WalletMaxAge(wallet.BlockchainType)
+}
+
+// ResolveAddress returns the chain and normalized address of an address of any chain, or of an ENS or SNS
+// name. Addresses of no supported chain and unknown names are bad request APIErrors.
+func ResolveAddress(ctx context.Context, input string) (chain, address string, err error) {
+	input = strings.TrimSpace(input)
+
+	if names.IsName(input) {
+		chain, err := names.Chain(input)
+		if err != nil {
+			return "", "", errors.NewBadRequestError(input + ": " + err.Error())
+		}
+
+		address, err := names.Resolve(ctx, input)
+		if er.Is(err, names.ErrNotFound) {
+			return "", "", errors.NewBadRequestError(input + ": " + err.Error())
+		}
+		if err != nil {
+			return "", "", err
+		}
+
+		return chain, validation.Normalize(chain, address), nil
+	}
+
+	chain, err = validation.Detect(input)
+	if err != nil {
+		return "", "", errors.NewBadRequestError(input + ": " + err.Error())
+	}
+
+	return chain, validation.Normalize(chain, input), nil
+}
+
+// RefreshChain refreshes every stored wallet of chain, least recently updated first. Failures do not stop the
+// refresh; they are returned keyed by address along with the number of wallets refreshed.
+func RefreshChain(ctx context.Context, db *gorm.DB, chain string) (int, map[string]error, error) {
+	wallets, err := models.GetWalletsByBlockchainType(db, chain)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	refreshed := 0
+	failed := make(map[string]error)
+	for _, wallet := range wallets {
+		if _, err := RefreshWallet(ctx, db, chain, wallet.WalletAddress); err != nil {
+			failed[wallet.WalletAddress] = err
+			continue
+		}
+		refreshed++
+	}
+
+	return refreshed, failed, nil
+}
+
+// portfolioValue returns the usd value of the stored wallets, given as chain:address, in total and by chain.
+// Wallets not stored yet, or whose coin is not priced yet, are left out.
+func portfolioValue(db *gorm.DB, wallets []string) (decimal.Decimal, map[string]decimal.Decimal, error) {
+	total := decimal.Zero
+	byChain := make(map[string]decimal.Decimal)
+
+	for _, wallet := range wallets {
+		chain, address, _ := strings.Cut(wallet, ":")
+
+		stored, err := models.GetGlobalWalletWithChainInfo(db, chain, address)
+		if er.Is(err, gorm.ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return decimal.Zero, nil, err
+		}
+
+		value := stored.ValueUSD()
+		total = total.Add(value)
+		byChain[chain] = byChain[chain].Add(value)
+	}
+
+	return total, byChain, nil
+}
+
+// hasWalletOf reports whether some of wallets, given as chain:address, are on chain.
+func hasWalletOf(wallets []string, chain string) bool {
+	for _, wallet := range wallets {
+		if strings.HasPrefix(wallet, chain+":") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type (
+	watchedWallet struct{ chain, address string }
+	watchedPrice  struct{ asset, currency string }
+)
+
+var (
+	watchMu        sync.Mutex
+	watchedWallets = make(map[watchedWallet]int)
+	watchedPrices  = make(map[watchedPrice]int)
+
+	// chainProviders are the providers the wallets of a chain are refreshed from
+	chainProviders = map[string]string{
+		utils.Bitcoin: utils.ProviderBtcCom,
+		utils.Solana:  utils.ProviderMoralis,
+		utils.Debank:  utils.ProviderDebank,
+	}
+)
+
+// Watch has RunWatcher refresh the wallet of address on chain until the returned func is called. Wallets
+// watched by several callers are refreshed once.
+func Watch(chain, address string) (unwatch func()) {
+	return watch(watchedWallets, watchedWallet{chain, address})
+}
+
+// WatchPrice has RunWatcher refresh the price of asset in currency until the returned func is called.
+func WatchPrice(asset, currency string) (unwatch func()) {
+	return watch(watchedPrices, watchedPrice{asset, currency})
+}
+
+func watch[K comparable](watched map[K]int, key K) func() {
+	watchMu.Lock()
+	watched[key]++
+	watchMu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			watchMu.Lock()
+			defer watchMu.Unlock()
+
+			if watched[key]--; watched[key] <= 0 {
+				delete(watched, key)
+			}
+		})
+	}
+}
+
+// RunWatcher refreshes the watched wallets and prices every interval until ctx is done, publishing the price
+// ticks and balance changes found. Only the wallets older than their max age are refreshed, WATCH_CONCURRENCY
+// at a time, and those whose provider is failing or short of budget are skipped. Prices are only fetched once
+// older than COINGECKO_PRICE_MAX_AGE.
+func RunWatcher(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// a refresh started when ctx ends is let finish, like the saves of requests
+	work := context.WithoutCancel(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		wallets, prices := watchedNow()
+
+		for _, price := range prices {
+			if err := HandleCoingeckoPrice(work, db.WithContext(work), price.asset, price.currency); err != nil {
+				slog.WarnContext(work, "watched price refresh failed", slog.String("asset", price.asset), slog.Any("error", err))
+			}
+		}
+
+		refreshWatched(ctx, work, db, expiredWallets(work, db, wallets))
+	}
+}
+
+// expiredWallets returns the wallets not stored yet or older than their max age.
+func expiredWallets(ctx context.Context, db *gorm.DB, wallets []watchedWallet) []watchedWallet {
+	if len(wallets) == 0 {
+		return nil
+	}
+
+	addresses := make([]string, 0, len(wallets))
+	for _, wallet := range wallets {
+		addresses = append(addresses, wallet.address)
+	}
+
+	stored, err := models.GetWalletsByAddresses(db.WithContext(ctx), addresses)
+	if err != nil {
+		slog.WarnContext(ctx, "loading watched wallets failed", slog.Any("error", err))
+		return nil
+	}
+
+	fresh := make(map[string]bool, len(stored))
+	for _, wallet := range stored {
+		fresh[wallet.WalletAddress] = WalletFresh(wallet)
+	}
+
+	expired := make([]watchedWallet, 0, len(wallets))
+	for _, wallet := range wallets {
+		if !fresh[wallet.address] {
+			expired = append(expired, wallet)
+		}
+	}
+
+	return expired
+}
+
+// refreshWatched refreshes wallets, WATCH_CONCURRENCY at a time, until ctx is done. The refreshes run with work.
+func refreshWatched(ctx, work context.Context, db *gorm.DB, wallets []watchedWallet) {
+	wg := &sync.WaitGroup{}
+	sem := make(chan struct{}, configs.EnvConfigVars.WatchConcurrency)
+	defer wg.Wait()
+
+	for _, wallet := range wallets {
+		if UseStoredData(chainProviders[wallet.chain]) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(wallet watchedWallet) {
+			defer func() { <-sem; wg.Done() }()
+
+			if _, err := RefreshWallet(work, db, wallet.chain, wallet.address); err != nil {
+				slog.WarnContext(work, "watched wallet refresh failed", slog.String("chain", wallet.chain), slog.String("address", wallet.address), slog.Any("error", err))
+			}
+		}(wallet)
+	}
+}
+
+// keepWatching watches the wallets, as chain:address, and the usd prices, by coingecko id, returned by load
+// until ctx is done, calling it again every interval to follow what it returns.
+func keepWatching(ctx context.Context, interval time.Duration, load func() (wallets, prices map[string]struct{}, err error)) {
+	watchedWallets := make(map[string]func())
+	watchedPrices := make(map[string]func())
+	defer func() {
+		for _, unwatch := range watchedWallets {
+			unwatch()
+		}
+		for _, unwatch := range watchedPrices {
+			unwatch()
+		}
+	}()
+
+	resync := func() {
+		wallets, prices, err := load()
+		if err != nil {
+			slog.WarnContext(ctx, "loading what to watch failed", slog.Any("error", err))
+			return
+		}
+
+		for wallet := range wallets {
+			if _, ok := watchedWallets[wallet]; !ok {
+				chain, address, _ := strings.Cut(wallet, ":")
+				watchedWallets[wallet] = Watch(chain, address)
+			}
+		}
+		for asset := range prices {
+			if _, ok := watchedPrices[asset]; !ok {
+				watchedPrices[asset] = WatchPrice(asset, "usd")
+			}
+		}
+
+		unwatchUnwanted(watchedWallets, wallets)
+		unwatchUnwanted(watchedPrices, prices)
+	}
+
+	resync()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			resync()
+		}
+	}
+}
+
+func unwatchUnwanted(watched map[string]func(), wanted map[string]struct{}) {
+	for key, unwatch := range watched {
+		if _, ok := wanted[key]; !ok {
+			unwatch()
+			delete(watched, key)
+		}
+	}
+}
+
+// chainCoins are the coingecko ids of the native coins of the chains.
+var chainCoins = map[string]string{
+	utils.Bitcoin: "bitcoin",
+	utils.Solana:  "solana",
+	utils.Debank:  "ethereum",
+}
+
+// addWalletPrices adds to prices the coins wallets are valued at, see portfolioValue: the native coins of
+// their chains, but for evm wallets, which debank values.
+func addWalletPrices(prices map[string]struct{}, wallets []string) {
+	for _, wallet := range wallets {
+		if chain, _, _ := strings.Cut(wallet, ":"); chain != utils.Debank && chainCoins[chain] != "" {
+			prices[chainCoins[chain]] = struct{}{}
+		}
+	}
+}
+
+func watchedNow() ([]watchedWallet, []watchedPrice) {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+
+	wallets := make([]watchedWallet, 0, len(watchedWallets))
+	for wallet := range watchedWallets {
+		wallets = append(wallets, wallet)
+	}
+
+	prices := make([]watchedPrice, 0, len(watchedPrices))
+	for price := range watchedPrices {
+		prices = append(prices, price)
+	}
+
+	return wallets, prices
+}
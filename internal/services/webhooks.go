@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	er "errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+
+	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/events"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// Events webhooks are notified of.
+const (
+	WebhookBalanceChanged = "wallet.balance_changed"    // some assets of a wallet changed
+	WebhookTokenReceived  = "token.received"            // a wallet holds more of some tokens
+	WebhookNFTReceived    = "nft.received"              // a wallet holds more of some nfts
+	WebhookValueThreshold = "portfolio.value_threshold" // the usd value of the wallets crossed the threshold
+	WebhookRefreshFailed  = "refresh.failed"            // a wallet could not be refreshed from its provider
+	// sent by FireTestWebhook and by alert rules delivering to a webhook, webhooks cannot subscribe to them
+	WebhookTest           = "webhook.test"
+	WebhookAlertTriggered = "alert.triggered"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to.
+var WebhookEvents = []string{WebhookBalanceChanged, WebhookTokenReceived, WebhookNFTReceived, WebhookValueThreshold, WebhookRefreshFailed}
+
+// Headers of a webhook delivery.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature" // see utils.SignWebhook
+)
+
+const (
+	webhookPollInterval = time.Second // how often due deliveries are looked for
+	webhookClaimSize    = 20          // deliveries sent at once per poll
+	webhookMaxBackoff   = 6 * time.Hour
+	webhookBodyLimit    = 64 << 10 // bytes of a response read before the connection is closed
+)
+
+type (
+	// WebhookPayload is the body of a webhook delivery.
+	WebhookPayload struct {
+		Event     string      `json:"event"`
+		EventID   uint64      `json:"event_id,omitempty"` // id of the change it reports, shared by the webhooks notified of it
+		CreatedAt time.Time   `json:"created_at"`
+		Data      interface{} `json:"data"`
+	}
+
+	// ValueCrossing is the data of a portfolio.value_threshold delivery.
+	ValueCrossing struct {
+		Wallets   []string        `json:"wallets"` // chain:address
+		Threshold decimal.Decimal `json:"threshold"`
+		Previous  decimal.Decimal `json:"previous"`
+		Value     decimal.Decimal `json:"value"`
+		Direction string          `json:"direction"` // above or below
+	}
+
+	// WebhookTestData is the data of a webhook.test delivery.
+	WebhookTestData struct {
+		WebhookID int    `json:"webhook_id"`
+		Message   string `json:"message"`
+	}
+)
+
+// ErrPrivateAddress rejects webhook urls reaching a loopback, private or otherwise non-public address.
+var ErrPrivateAddress = er.New("webhooks cannot be sent to private addresses")
+
+// nonPublicPrefixes are the special-purpose ranges PublicIP rejects that the net.IP methods do not cover.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // this network
+	netip.MustParsePrefix("100.64.0.0/10"),   // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // documentation
+	netip.MustParsePrefix("198.18.0.0/15"),   // benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // documentation
+	netip.MustParsePrefix("203.0.113.0/24"),  // documentation
+	netip.MustParsePrefix("240.0.0.0/4"),     // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, may map to private IPv4 addresses
+	netip.MustParsePrefix("2001:db8::/32"),   // documentation
+}
+
+// webhookClient sends the deliveries; redirects are not followed, they fail the attempt. It checks the address
+// of every connection once resolved, so that names resolving to private addresses are not reached either, and
+// goes through no proxy, which would be dialed instead.
+var webhookClient = &http.Client{
+	CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   webhookDialControl,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	},
+}
+
+// PublicIP reports whether ip is a public unicast address, one webhooks may be sent to.
+func PublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// webhookDialControl refuses connections to non-public addresses unless WEBHOOK_ALLOW_PRIVATE is set.
+func webhookDialControl(_, address string, _ syscall.RawConn) error {
+	if configs.EnvConfigVars.WebhookAllowPrivate {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	if ip := net.ParseIP(host); ip == nil || !PublicIP(ip) {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
+
+// RunWebhooks turns the events of the bus into webhook deliveries and sends them until ctx is done, retrying
+// failed ones with backoff. The wallets of the active webhooks are watched, so that they are refreshed every
+// watchInterval even when no one requests them.
+func RunWebhooks(ctx context.Context, db *gorm.DB, watchInterval time.Duration) {
+	var wg sync.WaitGroup
+	wg.Add(3)
+
+	go func() {
+		defer wg.Done()
+		dispatchWebhooks(ctx, db)
+	}()
+	go func() {
+		defer wg.Done()
+		sendWebhooks(ctx, db)
+	}()
+	go func() {
+		defer wg.Done()
+		watchWebhookWallets(ctx, db, watchInterval)
+	}()
+
+	wg.Wait()
+}
+
+// dispatchWebhooks records the deliveries of every event published until ctx is done.
+func dispatchWebhooks(ctx context.Context, db *gorm.DB) {
+	followEvents(ctx, "webhooks", func(work context.Context, event events.Event) {
+		if err := enqueueWebhooks(db.WithContext(work), event); err != nil {
+			slog.ErrorContext(work, "webhook deliveries not recorded", slog.String("type", event.Type), slog.String("topic", event.Topic), slog.Any("error", err))
+		}
+	})
+}
+
+// enqueueWebhooks records a delivery of event to every webhook notified of it.
+func enqueueWebhooks(db *gorm.DB, event events.Event) error {
+	switch data := event.Data.(type) {
+	case BalanceChange:
+		webhooks, err := models.GetActiveWebhooksByWallet(db, data.Chain, data.Address)
+		if err != nil {
+			return err
+		}
+
+		tokens := receivedChanges(data, models.AssetToken)
+		nfts := receivedChanges(data, models.AssetNFT)
+
+		for _, webhook := range webhooks {
+			if err := enqueueWebhook(db, webhook, WebhookBalanceChanged, event.ID, data); err != nil {
+				return err
+			}
+			if len(tokens.Changes) > 0 {
+				if err := enqueueWebhook(db, webhook, WebhookTokenReceived, event.ID, tokens); err != nil {
+					return err
+				}
+			}
+			if len(nfts.Changes) > 0 {
+				if err := enqueueWebhook(db, webhook, WebhookNFTReceived, event.ID, nfts); err != nil {
+					return err
+				}
+			}
+			if err := checkValueThreshold(db, webhook, event.ID); err != nil {
+				return err
+			}
+		}
+
+	case RefreshFailure:
+		webhooks, err := models.GetActiveWebhooksByWallet(db, data.Chain, data.Address)
+		if err != nil {
+			return err
+		}
+
+		for _, webhook := range webhooks {
+			if err := enqueueWebhook(db, webhook, WebhookRefreshFailed, event.ID, data); err != nil {
+				return err
+			}
+		}
+
+	case PriceTick:
+		if data.Currency != "usd" {
+			return nil
+		}
+
+		webhooks, err := models.GetActiveWebhooks(db)
+		if err != nil {
+			return err
+		}
+
+		for _, webhook := range webhooks {
+			// the coingecko ids of the coins priced are the chain names
+			if !hasWalletOf(webhook.WalletList(), data.Asset) {
+				continue
+			}
+			if err := checkValueThreshold(db, webhook, event.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// enqueueWebhook records a delivery of data as event to webhook, unless it did not subscribe to event.
+func enqueueWebhook(db *gorm.DB, webhook *models.Webhook, event string, eventID uint64, data interface{}) error {
+	if !webhook.HasEvent(event) {
+		return nil
+	}
+
+	_, err := createDelivery(db, webhook, event, eventID, data)
+
+	return err
+}
+
+func createDelivery(db *gorm.DB, webhook *models.Webhook, event string, eventID uint64, data interface{}) (*models.WebhookDelivery, error) {
+	now := time.Now().UTC()
+
+	payload, err := json.Marshal(WebhookPayload{Event: event, EventID: eventID, CreatedAt: now, Data: data})
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:     webhook.WebhookID,
+		Event:         event,
+		EventID:       eventID,
+		Payload:       string(payload),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: now,
+	}
+
+	if err := models.CreateWebhookDelivery(db, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// receivedChanges returns the changes of kind whose amount went up.
+func receivedChanges(change BalanceChange, kind string) BalanceChange {
+	received := BalanceChange{Chain: change.Chain, Address: change.Address, Changes: make([]models.AssetChange, 0)}
+	for _, assetChange := range change.Changes {
+		if assetChange.Kind == kind && assetChange.Received() {
+			received.Changes = append(received.Changes, assetChange)
+		}
+	}
+
+	return received
+}
+
+// checkValueThreshold values the stored wallets of webhook and records a portfolio.value_threshold delivery
+// when the value crossed the threshold since it was last checked. The first check only records the value.
+func checkValueThreshold(db *gorm.DB, webhook *models.Webhook, eventID uint64) error {
+	if webhook.ValueThreshold == nil || !webhook.HasEvent(WebhookValueThreshold) {
+		return nil
+	}
+
+	value, _, err := portfolioValue(db, webhook.WalletList())
+	if err != nil {
+		return err
+	}
+
+	previous := webhook.LastValue
+	if err := models.UpdateWebhookLastValue(db, webhook, value); err != nil {
+		return err
+	}
+
+	threshold := *webhook.ValueThreshold
+	if previous == nil || previous.GreaterThanOrEqual(threshold) == value.GreaterThanOrEqual(threshold) {
+		return nil
+	}
+
+	direction := "below"
+	if value.GreaterThanOrEqual(threshold) {
+		direction = "above"
+	}
+
+	_, err = createDelivery(db, webhook, WebhookValueThreshold, eventID, ValueCrossing{
+		Wallets:   webhook.WalletList(),
+		Threshold: threshold,
+		Previous:  *previous,
+		Value:     value,
+		Direction: direction,
+	})
+
+	return err
+}
+
+// sendWebhooks sends the deliveries due until ctx is done.
+func sendWebhooks(ctx context.Context, db *gorm.DB) {
+	ticker := time.NewTicker(webhookPollInterval)
+	defer ticker.Stop()
+
+	// an attempt started when ctx ends is let finish and recorded
+	work := context.WithoutCancel(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		// claimed deliveries are due again if this instance stops before recording their attempt. They are
+		// posted at once, so that the batch takes a single WEBHOOK_TIMEOUT and is recorded within the lease
+		lease := 2 * configs.EnvConfigVars.WebhookTimeout
+
+		deliveries, err := models.ClaimWebhookDeliveries(db.WithContext(work), webhookClaimSize, lease)
+		if err != nil {
+			slog.ErrorContext(work, "claiming webhook deliveries failed", slog.Any("error", err))
+			continue
+		}
+
+		wg := &sync.WaitGroup{}
+		for _, delivery := range deliveries {
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				sendDelivery(work, db.WithContext(work), delivery)
+			}(delivery)
+		}
+		wg.Wait()
+	}
+}
+
+// sendDelivery attempts a claimed delivery and records the outcome.
+func sendDelivery(ctx context.Context, db *gorm.DB, delivery *models.WebhookDelivery) {
+	webhook, err := models.GetWebhookByID(db, delivery.WebhookID)
+	if err != nil {
+		slog.ErrorContext(ctx, "loading webhook failed", slog.Int("webhook_id", delivery.WebhookID), slog.Any("error", err))
+		return
+	}
+
+	if err := attemptDelivery(ctx, db, webhook, delivery, configs.EnvConfigVars.WebhookMaxAttempts); err != nil {
+		slog.ErrorContext(ctx, "recording webhook delivery failed", slog.Int("delivery_id", delivery.DeliveryID), slog.Any("error", err))
+	}
+}
+
+// FireTestWebhook sends a webhook.test delivery to webhook now and returns it with the outcome. It is not retried.
+func FireTestWebhook(ctx context.Context, db *gorm.DB, webhook *models.Webhook) (*models.WebhookDelivery, error) {
+	delivery, err := createDelivery(db, webhook, WebhookTest, 0, WebhookTestData{
+		WebhookID: webhook.WebhookID,
+		Message:   "test delivery, verify its signature with the secret of the webhook",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := attemptDelivery(ctx, db, webhook, delivery, 1); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// attemptDelivery posts delivery to webhook and records the outcome: succeeded, pending again after a backoff,
+// or failed after maxAttempts attempts or when the webhook was disabled.
+func attemptDelivery(ctx context.Context, db *gorm.DB, webhook *models.Webhook, delivery *models.WebhookDelivery, maxAttempts int) error {
+	if webhook.IsDisabled() {
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = "webhook disabled"
+
+		return models.SaveWebhookDeliveryAttempt(db, delivery)
+	}
+
+	delivery.Attempts++
+	status, err := postWebhook(ctx, webhook, delivery)
+	delivery.ResponseStatus = status
+
+	result := models.DeliverySucceeded
+	switch {
+	case err == nil:
+		now := time.Now().UTC()
+		delivery.Status = models.DeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.Error = ""
+	case delivery.Attempts >= maxAttempts:
+		delivery.Status = models.DeliveryFailed
+		delivery.Error = err.Error()
+		result = models.DeliveryFailed
+	default:
+		delivery.Status = models.DeliveryPending
+		delivery.Error = err.Error()
+		delivery.NextAttemptAt = time.Now().UTC().Add(webhookBackoff(delivery.Attempts))
+		result = "retried"
+	}
+	metrics.WebhookAttempted(delivery.Event, result)
+
+	return models.SaveWebhookDeliveryAttempt(db, delivery)
+}
+
+// postWebhook sends the payload of delivery to the url of webhook, signed with its secret, and returns the
+// response status. Any status but 2xx is an error.
+func postWebhook(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, configs.EnvConfigVars.WebhookTimeout)
+	defer cancel()
+
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "portfolio_svc-webhooks")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, strconv.Itoa(delivery.DeliveryID))
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, utils.SignWebhook(webhook.Secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// read some of the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, webhookBodyLimit))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook answered %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// webhookBackoff returns the wait before the attempt after attempt: exponential from WEBHOOK_RETRY_BASE, up to
+// webhookMaxBackoff, with jitter.
+func webhookBackoff(attempt int) time.Duration {
+	wait := configs.EnvConfigVars.WebhookRetryBase
+	for i := 1; i < attempt && wait < webhookMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > webhookMaxBackoff {
+		wait = webhookMaxBackoff
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
+
+// watchWebhookWallets watches the wallets of the active webhooks, and the prices of those valued against a
+// threshold, until ctx is done.
+func watchWebhookWallets(ctx context.Context, db *gorm.DB, interval time.Duration) {
+	keepWatching(ctx, interval, func() (map[string]struct{}, map[string]struct{}, error) {
+		webhooks, err := models.GetActiveWebhooks(db.WithContext(ctx))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		wallets := make(map[string]struct{})
+		prices := make(map[string]struct{})
+		for _, webhook := range webhooks {
+			for _, wallet := range webhook.WalletList() {
+				wallets[wallet] = struct{}{}
+			}
+			if webhook.ValueThreshold != nil {
+				addWalletPrices(prices, webhook.WalletList())
+			}
+		}
+
+		return wallets, prices, nil
+	})
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"net"
+	"testing"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"255.255.255.255", false},
+		{"::1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:10.0.0.1", false},
+		{"64:ff9b::a00:1", false},
+	}
+
+	for _, tt := range tests {
+		if got := PublicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("PublicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookDialControl(t *testing.T) {
+	tests := []struct {
+		name         string
+		address      string
+		allowPrivate bool
+		wantErr      bool
+	}{
+		{"public", "93.184.216.34:443", false, false},
+		{"private", "10.0.0.5:443", false, true},
+		{"metadata", "169.254.169.254:80", false, true},
+		{"loopback v6", "[::1]:8080", false, true},
+		{"private allowed", "127.0.0.1:8080", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := configs.EnvConfigVars
+			configs.EnvConfigVars = &configs.EnvConfigs{WebhookAllowPrivate: tt.allowPrivate}
+			t.Cleanup(func() { configs.EnvConfigVars = previous })
+
+			err := webhookDialControl("tcp", tt.address, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("webhookDialControl(%s) = %v, want error %v", tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
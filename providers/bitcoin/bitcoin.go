@@ -1,7 +1,9 @@
 package bitcoin
 
 import (
+	"context"
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
@@ -17,11 +19,11 @@ type (
 	BitcoinAPI struct{}
 )
 
-func (b *BitcoinAPI) FetchData(address string) ([]byte, error) {
-	url := "https://chain.api.btc.com/v3/address/" + address
+func (b *BitcoinAPI) FetchData(ctx context.Context, address string) ([]byte, error) {
+	url := configs.EnvConfigVars.BtcComAPIURL + "/address/" + address
 	headers := map[string]string{}
 
-	body, err := utils.CallAPI(url, headers)
+	body, err := utils.CallAPI(ctx, utils.ProviderBtcCom, url, headers)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,20 @@
+package providers
+
+import (
+	"github.com/0xbase-Corp/portfolio_svc/shared/breaker"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// ConfigureBreakers wraps every provider in a circuit breaker configured from the env config.
+func ConfigureBreakers(env *configs.EnvConfigs) {
+	settings := breaker.Settings{
+		FailureThreshold: env.BreakerFailureThreshold,
+		Cooldown:         env.BreakerCooldown,
+		HalfOpenMaxCalls: env.BreakerHalfOpenMaxCalls,
+	}
+
+	for _, provider := range []string{utils.ProviderBtcCom, utils.ProviderMoralis, utils.ProviderDebank, utils.ProviderCoingecko, utils.ProviderENS, utils.ProviderSNS} {
+		breaker.Configure(provider, settings)
+	}
+}
@@ -1,23 +1,47 @@
 package coingecko
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/shopspring/decimal"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
 )
 
 type (
-	CryptoResponse map[string]map[string]float64
+	CryptoResponse map[string]map[string]decimal.Decimal
+
+	// MarketChartResponse is the price history of a crypto, as [unix milliseconds, price] pairs.
+	MarketChartResponse struct {
+		Prices [][2]decimal.Decimal `json:"prices"`
+	}
 
 	CoingeckoAPI struct{}
 )
 
-func (c *CoingeckoAPI) FetchData(cryptoID, currency string) ([]byte, error) {
-	url := fmt.Sprintf("https://api.coingecko.com/api/v3/simple/price?ids=%s&vs_currencies=%s", cryptoID, currency)
+func (c *CoingeckoAPI) FetchData(ctx context.Context, cryptoID, currency string) ([]byte, error) {
+	url := fmt.Sprintf("%s/simple/price?ids=%s&vs_currencies=%s", configs.EnvConfigVars.CoingeckoAPIURL, cryptoID, currency)
+
+	headers := map[string]string{}
+
+	body, err := utils.CallAPI(ctx, utils.ProviderCoingecko, url, headers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// FetchHistory returns the daily prices of cryptoID in currency over the last days.
+func (c *CoingeckoAPI) FetchHistory(ctx context.Context, cryptoID, currency string, days int) ([]byte, error) {
+	url := fmt.Sprintf("%s/coins/%s/market_chart?vs_currency=%s&days=%d&interval=daily", configs.EnvConfigVars.CoingeckoAPIURL, cryptoID, currency, days)
 
 	headers := map[string]string{}
 
-	body, err := utils.CallAPI(url, headers)
+	body, err := utils.CallAPI(ctx, utils.ProviderCoingecko, url, headers)
 
 	if err != nil {
 		return nil, err
@@ -1,7 +1,12 @@
 package debank
 
 import (
+	"context"
 	"encoding/json"
+	"math/big"
+	"strings"
+
+	"github.com/shopspring/decimal"
 
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
@@ -10,7 +15,7 @@ import (
 
 type (
 	EvmDebankTotalBalanceApiResponse struct {
-		TotalUsdValue float64                `json:"total_usd_value"`
+		TotalUsdValue decimal.Decimal        `json:"total_usd_value"`
 		ChainList     []*models.ChainDetails `json:"chain_list"`
 		TokensList    []*models.TokenList    `json:"token_list"`
 		NFTList       []*models.NFTList      `json:"nft_list"`
@@ -19,22 +24,29 @@ type (
 	DebankAPI struct{}
 )
 
-func (d *DebankAPI) FetchData(address string) ([]byte, error) {
+func (d *DebankAPI) FetchData(ctx context.Context, address string) ([]byte, error) {
 	headers := map[string]string{
 		"Accept":    "application/json",
 		"AccessKey": configs.EnvConfigVars.GetDebankAccessKeyHeader(),
 	}
 
 	resp := EvmDebankTotalBalanceApiResponse{}
-	if err := d.fetch("https://pro-openapi.debank.com/v1/user/total_balance?id="+address, headers, &resp); err != nil {
+	if err := d.fetch(ctx, configs.EnvConfigVars.DebankAPIURL+"/user/total_balance?id="+address, headers, &resp); err != nil {
 		return nil, err
 	}
 
-	if err := d.fetch("https://pro-openapi.debank.com/v1/user/all_token_list?id="+address, headers, &resp.TokensList); err != nil {
+	if err := d.fetch(ctx, configs.EnvConfigVars.DebankAPIURL+"/user/all_token_list?id="+address, headers, &resp.TokensList); err != nil {
 		return nil, err
 	}
 
-	if err := d.fetch("https://pro-openapi.debank.com/v1/user/all_nft_list?id="+address, headers, &resp.NFTList); err != nil {
+	// raw_amount is rounded through a float by debank, the hex string carries the exact integer
+	for _, token := range resp.TokensList {
+		if raw, ok := new(big.Int).SetString(strings.TrimPrefix(token.RawAmountHexStr, "0x"), 16); ok {
+			token.RawAmount = decimal.NewFromBigInt(raw, 0)
+		}
+	}
+
+	if err := d.fetch(ctx, configs.EnvConfigVars.DebankAPIURL+"/user/all_nft_list?id="+address, headers, &resp.NFTList); err != nil {
 		return nil, err
 	}
 
@@ -46,8 +58,8 @@ func (d *DebankAPI) FetchData(address string) ([]byte, error) {
 	return body, nil
 }
 
-func (d *DebankAPI) fetch(url string, headers map[string]string, data interface{}) error {
-	body, err := utils.CallAPI(url, headers)
+func (d *DebankAPI) fetch(ctx context.Context, url string, headers map[string]string, data interface{}) error {
+	body, err := utils.CallAPI(ctx, utils.ProviderDebank, url, headers)
 	if err != nil {
 		return err
 	}
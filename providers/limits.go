@@ -0,0 +1,36 @@
+package providers
+
+import (
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// ConfigureLimits registers the client-side rate limits and daily quotas of every provider from the env config.
+func ConfigureLimits(env *configs.EnvConfigs) {
+	ratelimit.Configure(utils.ProviderBtcCom, ratelimit.Limits{
+		RatePerSecond: env.BtcComRateLimit,
+		Burst:         env.BtcComBurst,
+		DailyQuota:    env.BtcComDailyQuota,
+	})
+
+	ratelimit.Configure(utils.ProviderMoralis, ratelimit.Limits{
+		RatePerSecond: env.MoralisRateLimit,
+		Burst:         env.MoralisBurst,
+		DailyQuota:    env.MoralisDailyQuota,
+	})
+
+	ratelimit.Configure(utils.ProviderDebank, ratelimit.Limits{
+		RatePerSecond: env.DebankRateLimit,
+		Burst:         env.DebankBurst,
+		DailyQuota:    env.DebankDailyQuota,
+	})
+
+	ratelimit.Configure(utils.ProviderCoingecko, ratelimit.Limits{
+		RatePerSecond: env.CoingeckoRateLimit,
+		Burst:         env.CoingeckoBurst,
+		DailyQuota:    env.CoingeckoDailyQuota,
+	})
+
+	ratelimit.SetNearBudgetRatio(env.QuotaStaleThreshold)
+}
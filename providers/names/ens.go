@@ -0,0 +1,172 @@
+package names
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+// ENS registry, deployed at the same address on mainnet since 2020
+const ensRegistry = "0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e"
+
+// function selectors
+const (
+	selectorResolver = "0178b8bf" // resolver(bytes32)
+	selectorAddr     = "3b3b57de" // addr(bytes32)
+	selectorName     = "691f3431" // name(bytes32)
+)
+
+type rpcResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// namehash implements the ENS namehash algorithm (EIP-137).
+func namehash(name string) []byte {
+	node := make([]byte, 32)
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = keccak256(node, keccak256([]byte(labels[i])))
+	}
+
+	return node
+}
+
+func keccak256(data ...[]byte) []byte {
+	hash := sha3.NewLegacyKeccak256()
+	for _, d := range data {
+		hash.Write(d)
+	}
+
+	return hash.Sum(nil)
+}
+
+func resolveENS(ctx context.Context, name string) (string, error) {
+	node := namehash(name)
+
+	resolver, err := ensResolver(ctx, node)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := ethCall(ctx, resolver, selectorAddr, node)
+	if err != nil {
+		return "", err
+	}
+
+	return wordAddress(result)
+}
+
+// reverseENS returns the primary name of address, checked against its forward resolution.
+func reverseENS(ctx context.Context, address string) (string, error) {
+	node := namehash(strings.TrimPrefix(address, "0x") + ".addr.reverse")
+
+	resolver, err := ensResolver(ctx, node)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := ethCall(ctx, resolver, selectorName, node)
+	if err != nil {
+		return "", err
+	}
+
+	name, err := abiString(result)
+	if err != nil || name == "" {
+		return "", ErrNotFound
+	}
+
+	// anyone can claim any name in their reverse record, only trust it when it points back
+	if resolved, err := Resolve(ctx, name); err != nil || resolved != address {
+		return "", ErrNotFound
+	}
+
+	return name, nil
+}
+
+func ensResolver(ctx context.Context, node []byte) (string, error) {
+	result, err := ethCall(ctx, ensRegistry, selectorResolver, node)
+	if err != nil {
+		return "", err
+	}
+
+	return wordAddress(result)
+}
+
+// ethCall calls a view function taking a single bytes32 argument and returns the raw result.
+func ethCall(ctx context.Context, to, selector string, node []byte) ([]byte, error) {
+	payload := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "eth_call",
+		"params": []interface{}{
+			map[string]string{"to": to, "data": "0x" + selector + hex.EncodeToString(node)},
+			"latest",
+		},
+	}
+
+	body, err := utils.PostJSON(ctx, utils.ProviderENS, configs.EnvConfigVars.EvmRpcURL, nil, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := rpcResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("eth_call failed: %d %s", resp.Error.Code, resp.Error.Message)
+	}
+
+	return hex.DecodeString(strings.TrimPrefix(resp.Result, "0x"))
+}
+
+// wordAddress reads an address from a 32-byte ABI word, the zero address meaning not found.
+func wordAddress(result []byte) (string, error) {
+	if len(result) < 32 {
+		return "", ErrNotFound
+	}
+
+	address := result[12:32]
+	if new(big.Int).SetBytes(address).Sign() == 0 {
+		return "", ErrNotFound
+	}
+
+	return "0x" + hex.EncodeToString(address), nil
+}
+
+// abiString decodes an ABI encoded dynamic string return value.
+func abiString(result []byte) (string, error) {
+	if len(result) < 64 {
+		return "", ErrNotFound
+	}
+
+	offset := new(big.Int).SetBytes(result[:32])
+	if !offset.IsInt64() || offset.Int64()+32 > int64(len(result)) {
+		return "", ErrNotFound
+	}
+
+	start := offset.Int64() + 32
+	length := new(big.Int).SetBytes(result[offset.Int64():start])
+	if !length.IsInt64() || start+length.Int64() > int64(len(result)) {
+		return "", ErrNotFound
+	}
+
+	return string(result[start : start+length.Int64()]), nil
+}
@@ -0,0 +1,129 @@
+// Package names resolves ENS (.eth) and Solana Name Service (.sol) names to wallet addresses and back.
+package names
+
+import (
+	"context"
+	er "errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type cacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+var (
+	ErrNotFound    = er.New("name does not resolve to an address")
+	ErrUnsupported = er.New("only .eth and .sol names are supported")
+
+	mu    sync.Mutex
+	cache = make(map[string]cacheEntry)
+)
+
+// sweepEvery is how many cache writes happen between removals of expired entries.
+const sweepEvery = 256
+
+// IsName reports whether input looks like a name rather than an address.
+func IsName(input string) bool {
+	return strings.Contains(input, ".")
+}
+
+// Chain returns the wallet blockchain type a name belongs to.
+func Chain(name string) (string, error) {
+	switch {
+	case strings.HasSuffix(strings.ToLower(name), ".eth"):
+		return utils.Debank, nil
+	case strings.HasSuffix(strings.ToLower(name), ".sol"):
+		return utils.Solana, nil
+	}
+
+	return "", ErrUnsupported
+}
+
+// Resolve returns the address name points to. Results, including names without an address, are cached.
+func Resolve(ctx context.Context, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+
+	chain, err := Chain(name)
+	if err != nil {
+		return "", err
+	}
+
+	return cached("resolve:"+name, func() (string, error) {
+		if chain == utils.Debank {
+			return resolveENS(ctx, name)
+		}
+		return resolveSNS(ctx, name)
+	})
+}
+
+// Reverse returns the primary name of a normalized address, or ErrNotFound when it has none.
+func Reverse(ctx context.Context, chain, address string) (string, error) {
+	return cached("reverse:"+chain+":"+address, func() (string, error) {
+		switch chain {
+		case utils.Debank:
+			return reverseENS(ctx, address)
+		case utils.Solana:
+			return reverseSNS(ctx, address)
+		}
+		return "", nil
+	})
+}
+
+// cached returns the cached value of key, calling lookup on a miss. ErrNotFound is cached as an empty
+// value, other errors are not cached.
+func cached(key string, lookup func() (string, error)) (string, error) {
+	mu.Lock()
+	entry, ok := cache[key]
+	mu.Unlock()
+
+	if !ok || time.Now().After(entry.expires) {
+		value, err := lookup()
+		if err != nil && !er.Is(err, ErrNotFound) {
+			return "", err
+		}
+
+		entry = cacheEntry{value: value, expires: time.Now().Add(configs.EnvConfigVars.NameCacheTTL)}
+		store(key, entry)
+	}
+
+	if entry.value == "" {
+		return "", ErrNotFound
+	}
+
+	return entry.value, nil
+}
+
+func store(key string, entry cacheEntry) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	cache[key] = entry
+
+	if len(cache)%sweepEvery == 0 {
+		now := time.Now()
+		for k, e := range cache {
+			if now.After(e.expires) {
+				delete(cache, k)
+			}
+		}
+	}
+}
+
+// rejectedAsNotFound turns a lookup the upstream rejected (4xx other than 429) into ErrNotFound.
+func rejectedAsNotFound(err error) error {
+	var upstreamErr *errors.UpstreamError
+	if er.As(err, &upstreamErr) && upstreamErr.StatusCode >= http.StatusBadRequest &&
+		upstreamErr.StatusCode < http.StatusInternalServerError && upstreamErr.StatusCode != http.StatusTooManyRequests {
+		return ErrNotFound
+	}
+
+	return err
+}
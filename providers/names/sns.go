@@ -0,0 +1,63 @@
+package names
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type (
+	snsResolveResponse struct {
+		Status string `json:"s"`
+		Result string `json:"result"`
+	}
+
+	snsFavoriteResponse struct {
+		Status string `json:"s"`
+		Result struct {
+			Domain  string `json:"domain"`
+			Reverse string `json:"reverse"`
+		} `json:"result"`
+	}
+)
+
+func resolveSNS(ctx context.Context, name string) (string, error) {
+	body, err := utils.CallAPI(ctx, utils.ProviderSNS, configs.EnvConfigVars.SnsProxyURL+"/resolve/"+url.PathEscape(strings.TrimSuffix(name, ".sol")), nil)
+	if err != nil {
+		return "", rejectedAsNotFound(err)
+	}
+
+	resp := snsResolveResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Status != "ok" || resp.Result == "" {
+		return "", ErrNotFound
+	}
+
+	return resp.Result, nil
+}
+
+// reverseSNS returns the favorite domain the owner of address picked as primary name.
+func reverseSNS(ctx context.Context, address string) (string, error) {
+	body, err := utils.CallAPI(ctx, utils.ProviderSNS, configs.EnvConfigVars.SnsProxyURL+"/favorite-domain/"+url.PathEscape(address), nil)
+	if err != nil {
+		return "", rejectedAsNotFound(err)
+	}
+
+	resp := snsFavoriteResponse{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+
+	if resp.Status != "ok" || resp.Result.Reverse == "" {
+		return "", ErrNotFound
+	}
+
+	return resp.Result.Reverse + ".sol", nil
+}
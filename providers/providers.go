@@ -1,11 +1,13 @@
 package providers
 
+import "context"
+
 type (
 	APIClient interface {
-		FetchData(address string) ([]byte, error)
+		FetchData(ctx context.Context, address string) ([]byte, error)
 	}
 
 	PriceFeedClient interface {
-		FetchData(cryptoID, currency string) ([]byte, error)
+		FetchData(ctx context.Context, cryptoID, currency string) ([]byte, error)
 	}
 )
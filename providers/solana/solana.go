@@ -1,6 +1,9 @@
 package solana
 
 import (
+	"context"
+	"github.com/shopspring/decimal"
+
 	"github.com/0xbase-Corp/portfolio_svc/internal/models"
 	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
 	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
@@ -11,22 +14,22 @@ type (
 		Tokens        []models.Token `json:"tokens"`
 		NFTs          []models.NFT   `json:"nfts"`
 		NativeBalance struct {
-			Lamports string `json:"lamports"`
-			Solana   string `json:"solana"`
+			Lamports decimal.Decimal `json:"lamports"`
+			Solana   decimal.Decimal `json:"solana"`
 		} `json:"nativeBalance"`
 	}
 
 	SolanaAPI struct{}
 )
 
-func (s *SolanaAPI) FetchData(address string) ([]byte, error) {
-	url := "https://solana-gateway.moralis.io/account/mainnet/" + address + "/portfolio"
+func (s *SolanaAPI) FetchData(ctx context.Context, address string) ([]byte, error) {
+	url := configs.EnvConfigVars.MoralisAPIURL + "/account/mainnet/" + address + "/portfolio"
 	headers := map[string]string{
 		"Accept":    "application/json",
 		"x-api-key": configs.EnvConfigVars.GetMoralisAccessKeyHeader(),
 	}
 
-	body, err := utils.CallAPI(url, headers)
+	body, err := utils.CallAPI(ctx, utils.ProviderMoralis, url, headers)
 	if err != nil {
 		return nil, err
 	}
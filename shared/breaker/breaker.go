@@ -0,0 +1,231 @@
+package breaker
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half-open"
+)
+
+type (
+	State string
+
+	// Settings configures when a breaker opens and how it recovers.
+	Settings struct {
+		FailureThreshold int           // consecutive failures that open the circuit
+		Cooldown         time.Duration // how long the circuit stays open before probing
+		HalfOpenMaxCalls int           // concurrent probe calls allowed while half-open
+	}
+
+	// Breaker guards calls to a single upstream provider.
+	Breaker struct {
+		name     string
+		settings Settings
+
+		mu            sync.Mutex
+		state         State
+		failures      int
+		openedAt      time.Time
+		probes        int
+		lastError     string
+		lastFailureAt time.Time
+	}
+
+	// Status is a point-in-time view of a breaker, used by the health endpoint.
+	Status struct {
+		Provider      string     `json:"provider"`
+		State         State      `json:"state"`
+		Failures      int        `json:"consecutive_failures"`
+		OpenedAt      *time.Time `json:"opened_at,omitempty"`
+		LastError     string     `json:"last_error,omitempty"`
+		LastFailureAt *time.Time `json:"last_failure_at,omitempty"`
+	}
+)
+
+// ErrOpen is returned while the circuit is open and calls are short-circuited.
+var ErrOpen = errors.New("circuit breaker is open")
+
+var (
+	mu       sync.RWMutex
+	breakers = make(map[string]*Breaker)
+)
+
+// Configure registers a breaker for the provider, replacing any previous one.
+func Configure(provider string, settings Settings) {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenMaxCalls < 1 {
+		settings.HalfOpenMaxCalls = 1
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	breakers[provider] = &Breaker{
+		name:     provider,
+		settings: settings,
+		state:    StateClosed,
+	}
+}
+
+// Allow reports whether a call to the provider may go ahead. Every allowed call must be
+// followed by exactly one of Success, Failure or Release.
+func Allow(provider string) error {
+	if b := get(provider); b != nil {
+		return b.Allow()
+	}
+
+	return nil
+}
+
+// Success records a call that reached a healthy provider.
+func Success(provider string) {
+	if b := get(provider); b != nil {
+		b.Success()
+	}
+}
+
+// Failure records a call that failed because the provider is unhealthy.
+func Failure(provider string, err error) {
+	if b := get(provider); b != nil {
+		b.Failure(err)
+	}
+}
+
+// Release ends an allowed call that never reached the provider, without affecting the breaker state.
+func Release(provider string) {
+	if b := get(provider); b != nil {
+		b.Release()
+	}
+}
+
+// IsOpen reports whether calls to the provider are currently short-circuited.
+func IsOpen(provider string) bool {
+	if b := get(provider); b != nil {
+		return b.IsOpen()
+	}
+
+	return false
+}
+
+// Snapshot returns the status of every configured breaker, sorted by provider.
+func Snapshot() []Status {
+	mu.RLock()
+	list := make([]*Breaker, 0, len(breakers))
+	for _, b := range breakers {
+		list = append(list, b)
+	}
+	mu.RUnlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].name < list[j].name })
+
+	statuses := make([]Status, 0, len(list))
+	for _, b := range list {
+		statuses = append(statuses, b.Status())
+	}
+
+	return statuses
+}
+
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.settings.Cooldown {
+			return ErrOpen
+		}
+		// cool-down elapsed, let a probe through
+		b.state = StateHalfOpen
+		b.probes = 0
+		fallthrough
+	case StateHalfOpen:
+		if b.probes >= b.settings.HalfOpenMaxCalls {
+			return ErrOpen
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probes = 0
+}
+
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.lastFailureAt = time.Now()
+	if err != nil {
+		b.lastError = err.Error()
+	}
+
+	if b.state == StateHalfOpen || b.failures >= b.settings.FailureThreshold {
+		b.state = StateOpen
+		b.openedAt = time.Now()
+		b.probes = 0
+	}
+}
+
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+func (b *Breaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state == StateOpen && time.Since(b.openedAt) < b.settings.Cooldown
+}
+
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		Provider:  b.name,
+		State:     b.state,
+		Failures:  b.failures,
+		LastError: b.lastError,
+	}
+
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+
+	if !b.lastFailureAt.IsZero() {
+		lastFailureAt := b.lastFailureAt
+		status.LastFailureAt = &lastFailureAt
+	}
+
+	return status
+}
+
+func get(provider string) *Breaker {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return breakers[provider]
+}
@@ -0,0 +1,102 @@
+// Package cache is a read-through cache of encoded values, used for provider responses and computed
+// portfolios. Concurrent loads of the same key are de-duplicated, and the storage backend is pluggable.
+package cache
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+)
+
+type (
+	// Store keeps values until their TTL expires. Implementations must be safe for concurrent use.
+	Store interface {
+		// Get returns the value of key, ok is false when it is missing or expired.
+		Get(ctx context.Context, key string) (value []byte, ok bool, err error)
+		Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	}
+)
+
+var (
+	mu    sync.RWMutex
+	store Store = NewMemoryStore(defaultMaxEntries)
+
+	group singleflight.Group
+)
+
+// SetStore replaces the in-memory store, e.g. by one shared between instances.
+func SetStore(s Store) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	store = s
+}
+
+func current() Store {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return store
+}
+
+// Load returns the cached value of key, calling load on a miss and caching its result for ttl. Concurrent
+// calls for a missing key share a single load. Errors are returned but not cached, and a failing store only
+// costs the cache: the value is loaded as if it were missing. hit reports whether load was not called.
+// A ttl of zero or less disables caching.
+func Load(ctx context.Context, key string, ttl time.Duration, load func() ([]byte, error)) (value []byte, hit bool, err error) {
+	if ttl <= 0 {
+		value, err = load()
+		return value, false, err
+	}
+
+	s := current()
+
+	value, ok, err := s.Get(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "cache read failed", slog.String("key", key), slog.Any("error", err))
+	}
+	if ok {
+		metrics.CacheHit(kind(key))
+		return value, true, nil
+	}
+	metrics.CacheMiss(kind(key))
+
+	// group.Do runs the load in the goroutine of the first caller, the others wait on it
+	loaded := false
+	result, err, _ := group.Do(key, func() (interface{}, error) {
+		loaded = true
+
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.Set(ctx, key, value, ttl); err != nil {
+			slog.WarnContext(ctx, "cache write failed", slog.String("key", key), slog.Any("error", err))
+		}
+
+		return value, nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	// a caller that waited on another one's load did not call load either
+	return result.([]byte), !loaded, nil
+}
+
+// kind is the prefix of key up to its first colon, which keeps the metrics labels bounded.
+func kind(key string) string {
+	for i := 0; i < len(key); i++ {
+		if key[i] == ':' {
+			return key[:i]
+		}
+	}
+
+	return key
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"context"
+	er "errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeStore is a MemoryStore that counts its calls and can fail them.
+type fakeStore struct {
+	*MemoryStore
+	gets, sets atomic.Int32
+	fail       error
+	arrived    *sync.WaitGroup
+}
+
+func (s *fakeStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.gets.Add(1)
+	if s.arrived != nil {
+		s.arrived.Done()
+	}
+	if s.fail != nil {
+		return nil, false, s.fail
+	}
+
+	return s.MemoryStore.Get(ctx, key)
+}
+
+func (s *fakeStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.sets.Add(1)
+	if s.fail != nil {
+		return s.fail
+	}
+
+	return s.MemoryStore.Set(ctx, key, value, ttl)
+}
+
+func setStore(t *testing.T, s Store) {
+	t.Helper()
+
+	previous := current()
+	SetStore(s)
+	t.Cleanup(func() { SetStore(previous) })
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeStore{MemoryStore: NewMemoryStore(10)}
+	setStore(t, store)
+
+	loads := 0
+	load := func() ([]byte, error) {
+		loads++
+		return []byte("value"), nil
+	}
+
+	value, hit, err := Load(ctx, "test:a", time.Minute, load)
+	if err != nil || hit || string(value) != "value" {
+		t.Fatalf("Load() = %q, %v, %v, want a miss loading value", value, hit, err)
+	}
+	value, hit, err = Load(ctx, "test:a", time.Minute, load)
+	if err != nil || !hit || string(value) != "value" {
+		t.Fatalf("Load() = %q, %v, %v, want a hit", value, hit, err)
+	}
+	if loads != 1 {
+		t.Errorf("load called %d times, want once", loads)
+	}
+
+	failure := er.New("provider down")
+	for i := 0; i < 2; i++ {
+		if _, _, err := Load(ctx, "test:b", time.Minute, func() ([]byte, error) { loads++; return nil, failure }); !er.Is(err, failure) {
+			t.Fatalf("Load() = %v, want %v", err, failure)
+		}
+	}
+	if loads != 3 {
+		t.Errorf("load called %d times, want errors not cached", loads-1)
+	}
+
+	gets, sets := store.gets.Load(), store.sets.Load()
+	for i := 0; i < 2; i++ {
+		if _, hit, _ := Load(ctx, "test:c", 0, load); hit {
+			t.Error("Load() with no ttl was a hit")
+		}
+	}
+	if loads != 5 || store.gets.Load() != gets || store.sets.Load() != sets {
+		t.Error("Load() with no ttl used the store")
+	}
+}
+
+func TestLoadFailingStore(t *testing.T) {
+	setStore(t, &fakeStore{MemoryStore: NewMemoryStore(10), fail: er.New("store down")})
+
+	value, hit, err := Load(context.Background(), "test:a", time.Minute, func() ([]byte, error) { return []byte("value"), nil })
+	if err != nil || hit || string(value) != "value" {
+		t.Errorf("Load() = %q, %v, %v, want the loaded value", value, hit, err)
+	}
+}
+
+func TestLoadSingleFlight(t *testing.T) {
+	const callers = 20
+
+	arrived := &sync.WaitGroup{}
+	arrived.Add(callers)
+	setStore(t, &fakeStore{MemoryStore: NewMemoryStore(10), arrived: arrived})
+
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func() ([]byte, error) {
+		loads.Add(1)
+		<-release
+		return []byte("value"), nil
+	}
+
+	var (
+		wg   sync.WaitGroup
+		hits atomic.Int32
+	)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			value, hit, err := Load(context.Background(), "test:a", time.Minute, load)
+			if err != nil || string(value) != "value" {
+				t.Errorf("Load() = %q, %v", value, err)
+			}
+			if hit {
+				hits.Add(1)
+			}
+		}()
+	}
+
+	// every caller missed the store, give them the time to join the load before it completes
+	arrived.Wait()
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if loads.Load() != 1 {
+		t.Errorf("load called %d times, want once", loads.Load())
+	}
+	if hits.Load() != callers-1 {
+		t.Errorf("%d callers reported a hit, want every one but the loader", hits.Load())
+	}
+}
+
+func TestMemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+
+	_ = s.Set(ctx, "expired", []byte("x"), -time.Second)
+	_ = s.Set(ctx, "soon", []byte("x"), time.Minute)
+	_ = s.Set(ctx, "later", []byte("x"), time.Hour)
+	_ = s.Set(ctx, "latest", []byte("x"), 2*time.Hour)
+
+	for key, want := range map[string]bool{"expired": false, "soon": false, "later": true, "latest": true} {
+		if _, ok, _ := s.Get(ctx, key); ok != want {
+			t.Errorf("Get(%q) = %v, want %v", key, ok, want)
+		}
+	}
+}
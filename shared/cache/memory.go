@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// defaultMaxEntries bounds the default memory store.
+const defaultMaxEntries = 10000
+
+type (
+	// MemoryStore is the default in-process Store. When full, expired entries are removed first, then the
+	// entries closest to expiring.
+	MemoryStore struct {
+		mu         sync.Mutex
+		entries    map[string]memoryEntry
+		maxEntries int
+	}
+
+	memoryEntry struct {
+		value   []byte
+		expires time.Time
+	}
+)
+
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry), maxEntries: maxEntries}
+}
+
+func (s *MemoryStore) Get(_ context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false, nil
+	}
+
+	return entry.value, true, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entries[key]; !ok && s.maxEntries > 0 && len(s.entries) >= s.maxEntries {
+		s.evict()
+	}
+
+	s.entries[key] = memoryEntry{value: value, expires: time.Now().Add(ttl)}
+
+	return nil
+}
+
+// evict makes room for one entry, it must be called with the lock held.
+func (s *MemoryStore) evict() {
+	now := time.Now()
+
+	var (
+		oldestKey string
+		oldest    time.Time
+	)
+	for key, entry := range s.entries {
+		if now.After(entry.expires) {
+			delete(s.entries, key)
+			continue
+		}
+		if oldestKey == "" || entry.expires.Before(oldest) {
+			oldestKey, oldest = key, entry.expires
+		}
+	}
+
+	if len(s.entries) >= s.maxEntries {
+		delete(s.entries, oldestKey)
+	}
+}
@@ -0,0 +1,124 @@
+// Package callstats keeps the latency and outcome of the recent calls to every upstream provider, for the
+// health details endpoint.
+package callstats
+
+import (
+	er "errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+)
+
+// window is the number of recent calls latencies are computed over.
+const window = 100
+
+type (
+	// Stats is a point-in-time view of the calls made to a provider since start up.
+	Stats struct {
+		Provider      string     `json:"provider"`
+		Calls         int64      `json:"calls"`
+		Errors        int64      `json:"errors"`
+		LastLatencyMs int64      `json:"last_latency_ms"`
+		AvgLatencyMs  int64      `json:"avg_latency_ms"` // over the last 100 calls
+		P95LatencyMs  int64      `json:"p95_latency_ms"` // over the last 100 calls
+		LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+		LastError     string     `json:"last_error,omitempty"`
+		LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	}
+
+	provider struct {
+		calls         int64
+		errors        int64
+		latencies     [window]time.Duration
+		next          int
+		lastLatency   time.Duration
+		lastSuccessAt time.Time
+		lastError     string
+		lastErrorAt   time.Time
+	}
+)
+
+var (
+	mu        sync.Mutex
+	providers = make(map[string]*provider)
+)
+
+// Record counts one request to provider that took latency and failed with err, if not nil.
+func Record(name string, latency time.Duration, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	p, ok := providers[name]
+	if !ok {
+		p = &provider{}
+		providers[name] = p
+	}
+
+	p.latencies[p.next%window] = latency
+	p.next++
+	p.calls++
+	p.lastLatency = latency
+
+	if err != nil {
+		p.errors++
+		p.lastError = err.Error()
+
+		// upstream response bodies are logged, not shown
+		var upstreamErr *errors.UpstreamError
+		if er.As(err, &upstreamErr) {
+			p.lastError = upstreamErr.APIError().Message
+		}
+		p.lastErrorAt = time.Now()
+		return
+	}
+
+	p.lastSuccessAt = time.Now()
+}
+
+// Get returns the stats of a provider, which are empty before its first call.
+func Get(name string) Stats {
+	mu.Lock()
+	defer mu.Unlock()
+
+	stats := Stats{Provider: name}
+
+	p, ok := providers[name]
+	if !ok {
+		return stats
+	}
+
+	stats.Calls = p.calls
+	stats.Errors = p.errors
+	stats.LastLatencyMs = p.lastLatency.Milliseconds()
+	stats.LastError = p.lastError
+
+	if !p.lastSuccessAt.IsZero() {
+		lastSuccessAt := p.lastSuccessAt
+		stats.LastSuccessAt = &lastSuccessAt
+	}
+	if !p.lastErrorAt.IsZero() {
+		lastErrorAt := p.lastErrorAt
+		stats.LastErrorAt = &lastErrorAt
+	}
+
+	n := p.next
+	if n > window {
+		n = window
+	}
+
+	recent := make([]time.Duration, n)
+	copy(recent, p.latencies[:n])
+	sort.Slice(recent, func(i, j int) bool { return recent[i] < recent[j] })
+
+	var total time.Duration
+	for _, latency := range recent {
+		total += latency
+	}
+
+	stats.AvgLatencyMs = (total / time.Duration(n)).Milliseconds()
+	stats.P95LatencyMs = recent[(n*95-1)/100].Milliseconds()
+
+	return stats
+}
@@ -1,52 +1,282 @@
 package configs
 
 import (
-	"log"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
+// EnvConfigs holds every tunable of the service. Each field is read from the variable named by its
+// mapstructure tag, or from the matching lower-case, dash separated flag; fields tagged secret are redacted
+// when the configuration is printed.
 type EnvConfigs struct {
 	Port             string `mapstructure:"PORT"`
-	DbPassword       string `mapstructure:"DB_PASSWORD"`
-	DatabaseUrl      string `mapstructure:"DATABASE_URL"`
-	Secret           string `mapstructure:"SECRET"`
-	DebankAccessKey  string `mapstructure:"DEBANK_ACCESS_KEY"`
-	MoralisAccessKey string `mapstructure:"MORALIS_ACCESS_KEY"`
+	DbPassword       string `mapstructure:"DB_PASSWORD" secret:"true"`
+	DatabaseUrl      string `mapstructure:"DATABASE_URL" secret:"true"`
+	Secret           string `mapstructure:"SECRET" secret:"true"`
+	DebankAccessKey  string `mapstructure:"DEBANK_ACCESS_KEY" secret:"true"`
+	MoralisAccessKey string `mapstructure:"MORALIS_ACCESS_KEY" secret:"true"`
+	// operators read /health/details with this bearer token; the endpoint is not served when it is empty
+	HealthToken string `mapstructure:"HEALTH_TOKEN" secret:"true"`
+	// the server applies pending migrations on start up unless they are run separately with 0xbasectl
+	AutoMigrate bool `mapstructure:"AUTO_MIGRATE"`
+
+	// http server timeouts, and how long a shutdown waits for in-flight requests and background work
+	HTTPReadTimeout       time.Duration `mapstructure:"HTTP_READ_TIMEOUT"`
+	HTTPReadHeaderTimeout time.Duration `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	HTTPWriteTimeout      time.Duration `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	HTTPIdleTimeout       time.Duration `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout       time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
+
+	// the gRPC API for internal consumers listens on GRPC_PORT, [host]:port, and is off when it is empty; its
+	// reflection service, listing the methods to anyone, is only registered when GRPC_REFLECTION is set
+	GrpcPort       string `mapstructure:"GRPC_PORT"`
+	GrpcReflection bool   `mapstructure:"GRPC_REFLECTION"`
+
+	// client-side rate limits (requests per second, burst) and daily call quotas per provider, 0 quota = unlimited
+	BtcComRateLimit     float64 `mapstructure:"BTC_COM_RATE_LIMIT"`
+	BtcComBurst         int     `mapstructure:"BTC_COM_BURST"`
+	BtcComDailyQuota    int64   `mapstructure:"BTC_COM_DAILY_QUOTA"`
+	MoralisRateLimit    float64 `mapstructure:"MORALIS_RATE_LIMIT"`
+	MoralisBurst        int     `mapstructure:"MORALIS_BURST"`
+	MoralisDailyQuota   int64   `mapstructure:"MORALIS_DAILY_QUOTA"`
+	DebankRateLimit     float64 `mapstructure:"DEBANK_RATE_LIMIT"`
+	DebankBurst         int     `mapstructure:"DEBANK_BURST"`
+	DebankDailyQuota    int64   `mapstructure:"DEBANK_DAILY_QUOTA"`
+	CoingeckoRateLimit  float64 `mapstructure:"COINGECKO_RATE_LIMIT"`
+	CoingeckoBurst      int     `mapstructure:"COINGECKO_BURST"`
+	CoingeckoDailyQuota int64   `mapstructure:"COINGECKO_DAILY_QUOTA"`
+	// share of a daily quota after which stored data is served instead of calling the provider
+	QuotaStaleThreshold float64 `mapstructure:"QUOTA_STALE_THRESHOLD"`
+
+	// circuit breaker around every provider
+	BreakerFailureThreshold int           `mapstructure:"BREAKER_FAILURE_THRESHOLD"`
+	BreakerCooldown         time.Duration `mapstructure:"BREAKER_COOLDOWN"`
+	BreakerHalfOpenMaxCalls int           `mapstructure:"BREAKER_HALF_OPEN_MAX_CALLS"`
+
+	// inbound rate limits as "<requests>/<window>", with ";" separated "METHOD /path=<requests>/<window>" overrides
+	RateLimitDefault       string `mapstructure:"RATE_LIMIT_DEFAULT"`
+	RateLimitRoutes        string `mapstructure:"RATE_LIMIT_ROUTES"`
+	MaxAddressesPerRequest int    `mapstructure:"MAX_ADDRESSES_PER_REQUEST"`
+
+	// ENS names are resolved through an ethereum json-rpc endpoint, .sol names through an SNS proxy
+	EvmRpcURL    string        `mapstructure:"EVM_RPC_URL"`
+	SnsProxyURL  string        `mapstructure:"SNS_PROXY_URL"`
+	NameCacheTTL time.Duration `mapstructure:"NAME_CACHE_TTL"`
+	// how long the reverse lookups naming the wallets of a response may take altogether
+	NameLookupTimeout time.Duration `mapstructure:"NAME_LOOKUP_TIMEOUT"`
+
+	// base urls of the data providers
+	BtcComAPIURL    string `mapstructure:"BTC_COM_API_URL"`
+	MoralisAPIURL   string `mapstructure:"MORALIS_API_URL"`
+	DebankAPIURL    string `mapstructure:"DEBANK_API_URL"`
+	CoingeckoAPIURL string `mapstructure:"COINGECKO_API_URL"`
+
+	// how long stored prices and debank wallets are served before they are fetched again
+	CoingeckoPriceMaxAge time.Duration `mapstructure:"COINGECKO_PRICE_MAX_AGE"`
+	DebankWalletMaxAge   time.Duration `mapstructure:"DEBANK_WALLET_MAX_AGE"`
+
+	// how long provider responses and computed portfolios are cached per chain, 0 disables caching, and the
+	// size of the in-memory cache
+	CacheBitcoinTTL time.Duration `mapstructure:"CACHE_BITCOIN_TTL"`
+	CacheSolanaTTL  time.Duration `mapstructure:"CACHE_SOLANA_TTL"`
+	CacheDebankTTL  time.Duration `mapstructure:"CACHE_DEBANK_TTL"`
+	CacheMaxEntries int           `mapstructure:"CACHE_MAX_ENTRIES"`
+
+	// websocket clients are pinged every WS_PING_INTERVAL and dropped when they fall WS_SEND_BUFFER events
+	// behind, the last EVENTS_REPLAY_SIZE events are kept for clients resuming, and the wallets and prices they
+	// watch are refreshed every WATCH_INTERVAL
+	WsPingInterval   time.Duration `mapstructure:"WS_PING_INTERVAL"`
+	WsSendBuffer     int           `mapstructure:"WS_SEND_BUFFER"`
+	EventsReplaySize int           `mapstructure:"EVENTS_REPLAY_SIZE"`
+	WatchInterval    time.Duration `mapstructure:"WATCH_INTERVAL"`
+	// at most WATCH_CONCURRENCY watched wallets are refreshed at once, and only once older than their max age
+	WatchConcurrency int `mapstructure:"WATCH_CONCURRENCY"`
+	// a user opens at most WS_MAX_CONNECTIONS_PER_USER websockets watching WS_MAX_WATCHES_PER_USER wallets in all;
+	// browsers connect from the comma separated WS_ALLOWED_ORIGINS, "*" for any, or from the host of the API
+	WsMaxConnectionsPerUser int    `mapstructure:"WS_MAX_CONNECTIONS_PER_USER"`
+	WsMaxWatchesPerUser     int    `mapstructure:"WS_MAX_WATCHES_PER_USER"`
+	WsAllowedOrigins        string `mapstructure:"WS_ALLOWED_ORIGINS"`
+
+	// webhook deliveries time out after WEBHOOK_TIMEOUT and are retried with exponential backoff from
+	// WEBHOOK_RETRY_BASE until WEBHOOK_MAX_ATTEMPTS attempts failed. They are only sent to public addresses
+	// unless WEBHOOK_ALLOW_PRIVATE is set, for development
+	WebhookTimeout      time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookRetryBase    time.Duration `mapstructure:"WEBHOOK_RETRY_BASE"`
+	WebhookMaxAttempts  int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookAllowPrivate bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE"`
+
+	// alerts are emailed through SMTP_HOST:SMTP_PORT when SMTP_HOST is set, authenticating when SMTP_USERNAME
+	// is set, and rules stay quiet for ALERT_COOLDOWN after they triggered unless they set their own cooldown
+	SmtpHost      string        `mapstructure:"SMTP_HOST"`
+	SmtpPort      int           `mapstructure:"SMTP_PORT"`
+	SmtpUsername  string        `mapstructure:"SMTP_USERNAME"`
+	SmtpPassword  string        `mapstructure:"SMTP_PASSWORD" secret:"true"`
+	SmtpFrom      string        `mapstructure:"SMTP_FROM"`
+	SmtpTimeout   time.Duration `mapstructure:"SMTP_TIMEOUT"`
+	AlertCooldown time.Duration `mapstructure:"ALERT_COOLDOWN"`
+
+	// structured logs of level debug, info, warn or error and above, as json or text
+	LogLevel  string `mapstructure:"LOG_LEVEL"`
+	LogFormat string `mapstructure:"LOG_FORMAT"`
+
+	// OpenTelemetry traces are exported over OTLP/HTTP when enabled, sampling a share of the new traces
+	TracingEnabled     bool    `mapstructure:"TRACING_ENABLED"`
+	OtlpEndpoint       string  `mapstructure:"OTLP_ENDPOINT"`
+	OtelServiceName    string  `mapstructure:"OTEL_SERVICE_NAME"`
+	TracingSampleRatio float64 `mapstructure:"TRACING_SAMPLE_RATIO"`
+	// comma separated hosts of internal services that receive the trace context of upstream calls; third-party
+	// providers never do
+	TracePropagateHosts string `mapstructure:"TRACE_PROPAGATE_HOSTS"`
+
+	// lifetimes of the issued JWTs
+	AccessTokenTTL  time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	RefreshTokenTTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
 }
 
 var EnvConfigVars *EnvConfigs
 
-func InitEnvConfigs() {
-	var err error
-	EnvConfigVars, err = loadEnvVariables()
+// defaultConfigFile is read when it exists and no other file is given with -config or CONFIG_FILE.
+const defaultConfigFile = "app.env"
+
+// InitEnvConfigs loads and validates the configuration into EnvConfigVars and returns the command line
+// arguments left after the configuration flags. It returns flag.ErrHelp when -h was given.
+func InitEnvConfigs(args []string) ([]string, error) {
+	config, rest, err := Load(args)
 	if err != nil {
-		log.Fatalf("Failed to load environment variables: %v", err)
+		return nil, err
 	}
+
+	EnvConfigVars = config
+
+	return rest, nil
 }
 
-func loadEnvVariables() (config *EnvConfigs, err error) {
-	// Tell viper the path/location of your env file. If it is root just add "."
-	viper.AddConfigPath(".")
+// Load reads the configuration from, in increasing precedence, the defaults, the config file, the
+// environment and the command line flags in args, then validates it. The config file is app.env in the
+// working directory when present, or the file given by -config or CONFIG_FILE in any format viper reads.
+// On validation errors the loaded configuration is returned along with the error.
+func Load(args []string) (*EnvConfigs, []string, error) {
+	v := viper.New()
+	setDefaults(v)
 
-	// Tell viper the name of your file
-	viper.SetConfigName("app")
+	// bind every key explicitly so variables only set in the environment are unmarshalled too
+	for _, key := range keys() {
+		if err := v.BindEnv(key); err != nil {
+			return nil, nil, err
+		}
+	}
 
-	// Tell viper the type of your file
-	viper.SetConfigType("env")
+	flags, configFile := newFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return nil, nil, err
+	}
 
-	viper.AutomaticEnv()
+	if *configFile == "" {
+		*configFile = os.Getenv("CONFIG_FILE")
+	}
 
-	// Viper reads all the variables from env file and log error if any found
-	if err := viper.ReadInConfig(); err != nil {
-		log.Fatal("Error reading env file", err)
+	if *configFile != "" {
+		v.SetConfigFile(*configFile)
+		if strings.HasSuffix(*configFile, ".env") {
+			v.SetConfigType("env")
+		}
+		if err := v.ReadInConfig(); err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", *configFile, err)
+		}
+	} else if _, err := os.Stat(defaultConfigFile); err == nil {
+		v.SetConfigFile(defaultConfigFile)
+		v.SetConfigType("env")
+		if err := v.ReadInConfig(); err != nil {
+			return nil, nil, fmt.Errorf("reading %s: %w", defaultConfigFile, err)
+		}
 	}
 
-	// Viper unmarshals the loaded env variables into the struct
-	if err := viper.Unmarshal(&config); err != nil {
-		log.Fatal(err)
+	// flags take precedence over everything else
+	flags.Visit(func(f *flag.Flag) {
+		if key, ok := flagKeys[f.Name]; ok {
+			v.Set(key, f.Value.String())
+		}
+	})
+
+	config := &EnvConfigs{}
+	if err := v.Unmarshal(config); err != nil {
+		return nil, nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return config, flags.Args(), err
 	}
-	return config, nil
+
+	return config, flags.Args(), nil
+}
+
+// setDefaults registers defaults for optional variables.
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("AUTO_MIGRATE", true)
+	v.SetDefault("HTTP_READ_TIMEOUT", "15s")
+	v.SetDefault("HTTP_READ_HEADER_TIMEOUT", "5s")
+	v.SetDefault("HTTP_WRITE_TIMEOUT", "120s")
+	v.SetDefault("HTTP_IDLE_TIMEOUT", "120s")
+	v.SetDefault("SHUTDOWN_TIMEOUT", "30s")
+	v.SetDefault("BTC_COM_RATE_LIMIT", 5)
+	v.SetDefault("BTC_COM_BURST", 10)
+	v.SetDefault("BTC_COM_DAILY_QUOTA", 0)
+	v.SetDefault("MORALIS_RATE_LIMIT", 20)
+	v.SetDefault("MORALIS_BURST", 40)
+	v.SetDefault("MORALIS_DAILY_QUOTA", 0)
+	v.SetDefault("DEBANK_RATE_LIMIT", 10)
+	v.SetDefault("DEBANK_BURST", 20)
+	v.SetDefault("DEBANK_DAILY_QUOTA", 0)
+	v.SetDefault("COINGECKO_RATE_LIMIT", 0.2)
+	v.SetDefault("COINGECKO_BURST", 5)
+	v.SetDefault("COINGECKO_DAILY_QUOTA", 10000)
+	v.SetDefault("QUOTA_STALE_THRESHOLD", 0.9)
+	v.SetDefault("BREAKER_FAILURE_THRESHOLD", 5)
+	v.SetDefault("BREAKER_COOLDOWN", "30s")
+	v.SetDefault("BREAKER_HALF_OPEN_MAX_CALLS", 1)
+	v.SetDefault("RATE_LIMIT_DEFAULT", "120/1m")
+	v.SetDefault("RATE_LIMIT_ROUTES", "POST /api/v1/all-portfolio=10/1m;GET /api/v1/portfolio/debank=30/1m")
+	v.SetDefault("MAX_ADDRESSES_PER_REQUEST", 20)
+	v.SetDefault("EVM_RPC_URL", "https://cloudflare-eth.com")
+	v.SetDefault("SNS_PROXY_URL", "https://sns-sdk-proxy.bonfida.workers.dev")
+	v.SetDefault("NAME_CACHE_TTL", "15m")
+	v.SetDefault("NAME_LOOKUP_TIMEOUT", "2s")
+	v.SetDefault("BTC_COM_API_URL", "https://chain.api.btc.com/v3")
+	v.SetDefault("MORALIS_API_URL", "https://solana-gateway.moralis.io")
+	v.SetDefault("DEBANK_API_URL", "https://pro-openapi.debank.com/v1")
+	v.SetDefault("COINGECKO_API_URL", "https://api.coingecko.com/api/v3")
+	v.SetDefault("COINGECKO_PRICE_MAX_AGE", "2m")
+	v.SetDefault("DEBANK_WALLET_MAX_AGE", "24h")
+	v.SetDefault("CACHE_BITCOIN_TTL", "30s")
+	v.SetDefault("CACHE_SOLANA_TTL", "30s")
+	v.SetDefault("CACHE_DEBANK_TTL", "30s")
+	v.SetDefault("CACHE_MAX_ENTRIES", 10000)
+	v.SetDefault("WS_PING_INTERVAL", "30s")
+	v.SetDefault("WS_SEND_BUFFER", 256)
+	v.SetDefault("EVENTS_REPLAY_SIZE", 1000)
+	v.SetDefault("WATCH_INTERVAL", "1m")
+	v.SetDefault("WATCH_CONCURRENCY", 4)
+	v.SetDefault("WS_MAX_CONNECTIONS_PER_USER", 5)
+	v.SetDefault("WS_MAX_WATCHES_PER_USER", 100)
+	v.SetDefault("WEBHOOK_TIMEOUT", "10s")
+	v.SetDefault("WEBHOOK_RETRY_BASE", "30s")
+	v.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	v.SetDefault("SMTP_PORT", 587)
+	v.SetDefault("SMTP_TIMEOUT", "10s")
+	v.SetDefault("ALERT_COOLDOWN", "1h")
+	v.SetDefault("LOG_LEVEL", "info")
+	v.SetDefault("LOG_FORMAT", "json")
+	v.SetDefault("TRACING_ENABLED", false)
+	v.SetDefault("OTLP_ENDPOINT", "http://localhost:4318")
+	v.SetDefault("OTEL_SERVICE_NAME", "portfolio_svc")
+	v.SetDefault("TRACING_SAMPLE_RATIO", 1)
+	v.SetDefault("ACCESS_TOKEN_TTL", "438000h")
+	v.SetDefault("REFRESH_TOKEN_TTL", "24h")
 }
 
 // GetSecret returns the value of JWT_SECRET
@@ -0,0 +1,182 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requiredFlags sets the variables without a default, so that the rest of a configuration is valid.
+var requiredFlags = []string{
+	"-port", ":5050",
+	"-database-url", "postgres://portfolio:pw@db:5432/portfolio",
+	"-secret", "s3cret",
+	"-debank-access-key", "debank",
+	"-moralis-access-key", "moralis",
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "test.env")
+	if err := os.WriteFile(configFile, []byte("RATE_LIMIT_DEFAULT=10/1m\nNAME_CACHE_TTL=1m\nAUTO_MIGRATE=false\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		file      bool
+		env       map[string]string
+		flags     []string
+		wantLimit string
+		wantTTL   time.Duration
+		wantAuto  bool
+	}{
+		{name: "defaults", wantLimit: "120/1m", wantTTL: 15 * time.Minute, wantAuto: true},
+		{name: "file over defaults", file: true, wantLimit: "10/1m", wantTTL: time.Minute, wantAuto: false},
+		{
+			name:      "environment over file",
+			file:      true,
+			env:       map[string]string{"RATE_LIMIT_DEFAULT": "20/1m", "AUTO_MIGRATE": "true"},
+			wantLimit: "20/1m", wantTTL: time.Minute, wantAuto: true,
+		},
+		{
+			name:      "flags over environment",
+			file:      true,
+			env:       map[string]string{"RATE_LIMIT_DEFAULT": "20/1m", "NAME_CACHE_TTL": "2m"},
+			flags:     []string{"-rate-limit-default", "30/1m", "-auto-migrate"},
+			wantLimit: "30/1m", wantTTL: 2 * time.Minute, wantAuto: true,
+		},
+		{name: "bool flags take a value", flags: []string{"-auto-migrate=false"}, wantLimit: "120/1m", wantTTL: 15 * time.Minute, wantAuto: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("CONFIG_FILE", "")
+			for _, key := range []string{"RATE_LIMIT_DEFAULT", "NAME_CACHE_TTL", "AUTO_MIGRATE"} {
+				t.Setenv(key, tt.env[key])
+				if tt.env[key] == "" {
+					os.Unsetenv(key)
+				}
+			}
+
+			args := append([]string{}, requiredFlags...)
+			if tt.file {
+				args = append(args, "-config", configFile)
+			}
+			args = append(args, tt.flags...)
+			args = append(args, "migrate", "up")
+
+			config, rest, err := Load(args)
+			if err != nil {
+				t.Fatalf("Load() = %v", err)
+			}
+
+			if config.RateLimitDefault != tt.wantLimit || config.NameCacheTTL != tt.wantTTL || config.AutoMigrate != tt.wantAuto {
+				t.Errorf("Load() = %s, %s, auto migrate %v, want %s, %s, %v",
+					config.RateLimitDefault, config.NameCacheTTL, config.AutoMigrate, tt.wantLimit, tt.wantTTL, tt.wantAuto)
+			}
+			if strings.Join(rest, " ") != "migrate up" {
+				t.Errorf("Load() left %v, want the arguments after the flags", rest)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid, _, err := Load(requiredFlags)
+	if err != nil {
+		t.Fatalf("Load() = %v, want the defaults valid", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(env *EnvConfigs)
+		want   []string
+	}{
+		{"valid", func(env *EnvConfigs) {}, nil},
+		{"no database", func(env *EnvConfigs) { env.DatabaseUrl = "" }, []string{"DATABASE_URL is required, set it in the environment, the config file or with -database-url"}},
+		{"no secret", func(env *EnvConfigs) { env.Secret = "" }, []string{"SECRET is required"}},
+		{"port without colon", func(env *EnvConfigs) { env.Port = "5050" }, []string{"PORT must be [host]:port"}},
+		{"negative rate", func(env *EnvConfigs) { env.BtcComRateLimit = -1 }, []string{"BTC_COM_RATE_LIMIT must not be negative"}},
+		{"rate without burst", func(env *EnvConfigs) { env.MoralisBurst = 0 }, []string{"MORALIS_BURST must be at least 1"}},
+		{"negative quota", func(env *EnvConfigs) { env.DebankDailyQuota = -1 }, []string{"DEBANK_DAILY_QUOTA must not be negative"}},
+		{"stale threshold above 1", func(env *EnvConfigs) { env.QuotaStaleThreshold = 1.5 }, []string{"QUOTA_STALE_THRESHOLD must be in (0, 1]"}},
+		{"no breaker failures", func(env *EnvConfigs) { env.BreakerFailureThreshold = 0 }, []string{"BREAKER_FAILURE_THRESHOLD"}},
+		{"no half open calls", func(env *EnvConfigs) { env.BreakerHalfOpenMaxCalls = 0 }, []string{"BREAKER_HALF_OPEN_MAX_CALLS"}},
+		{"no addresses", func(env *EnvConfigs) { env.MaxAddressesPerRequest = 0 }, []string{"MAX_ADDRESSES_PER_REQUEST"}},
+		{"zero duration", func(env *EnvConfigs) { env.BreakerCooldown = 0 }, []string{"BREAKER_COOLDOWN must be a positive duration"}},
+		{"negative duration", func(env *EnvConfigs) { env.AccessTokenTTL = -time.Hour }, []string{"ACCESS_TOKEN_TTL must be a positive duration"}},
+		{"url without scheme", func(env *EnvConfigs) { env.EvmRpcURL = "cloudflare-eth.com" }, []string{"EVM_RPC_URL must be an http(s) url"}},
+		{"url of another scheme", func(env *EnvConfigs) { env.CoingeckoAPIURL = "ftp://api.coingecko.com" }, []string{"COINGECKO_API_URL must be an http(s) url"}},
+		{
+			name:   "every problem at once",
+			change: func(env *EnvConfigs) { env.Secret, env.DebankAccessKey, env.NameCacheTTL = "", "", 0 },
+			want:   []string{"SECRET", "DEBANK_ACCESS_KEY", "NAME_CACHE_TTL"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := *valid
+			tt.change(&env)
+
+			err := env.Validate()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %v", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %v, want it to report %q", err, want)
+				}
+			}
+			if problems := strings.Count(err.Error(), "\n") + 1; problems != len(tt.want) {
+				t.Errorf("Validate() reported %d problems, want %d: %v", problems, len(tt.want), err)
+			}
+		})
+	}
+}
+
+func TestSettingsRedaction(t *testing.T) {
+	env := &EnvConfigs{
+		Port:             ":5050",
+		DbPassword:       "",
+		DatabaseUrl:      "postgres://portfolio:pw@db:5432/portfolio?sslmode=disable",
+		Secret:           "s3cret",
+		DebankAccessKey:  "host=db password=pw",
+		RateLimitDefault: "120/1m",
+	}
+
+	want := map[string]string{
+		"PORT":               ":5050",
+		"DB_PASSWORD":        "",
+		"DATABASE_URL":       "postgres://portfolio:xxxxx@db:5432/portfolio?sslmode=disable",
+		"SECRET":             redacted,
+		"DEBANK_ACCESS_KEY":  redacted,
+		"MORALIS_ACCESS_KEY": "",
+		"RATE_LIMIT_DEFAULT": "120/1m",
+	}
+
+	settings := env.Settings()
+	if len(settings) != len(keys()) {
+		t.Errorf("Settings() listed %d variables, want every one of the %d", len(settings), len(keys()))
+	}
+
+	for _, setting := range settings {
+		if value, ok := want[setting.Key]; ok && setting.Value != value {
+			t.Errorf("%s = %q, want %q", setting.Key, setting.Value, value)
+		}
+		for _, secret := range []string{"s3cret", ":pw@", "password=pw"} {
+			if strings.Contains(setting.Value, secret) {
+				t.Errorf("%s = %q leaks a secret", setting.Key, setting.Value)
+			}
+		}
+	}
+}
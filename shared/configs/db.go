@@ -2,36 +2,34 @@ package configs
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-)
-
-func GetDB() *gorm.DB {
-	// Database connection string
 
-	dsn := EnvConfigVars.DatabaseUrl
+	"github.com/0xbase-Corp/portfolio_svc/shared/logging"
+)
 
-	// Open the connection to the database
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+// GetDB connects to DATABASE_URL and checks the connection.
+func GetDB() (*gorm.DB, error) {
+	// Open the connection to the database, unique violations are reported as gorm.ErrDuplicatedKey
+	db, err := gorm.Open(postgres.Open(EnvConfigVars.DatabaseUrl), &gorm.Config{Logger: logging.GormLogger{}, TranslateError: true})
 	if err != nil {
-		log.Fatalf("Error connecting to database: %v", err)
+		return nil, fmt.Errorf("connecting to database: %w", err)
 	}
+
 	// Get generic database object sql.DB to use its functions
 	sqlDB, err := db.DB()
 	if err != nil {
-		log.Fatalf("Error getting generic database object: %v", err)
+		return nil, fmt.Errorf("getting generic database object: %w", err)
 	}
-	// defer sqlDB.Close()
+
 	// Ping the database to check for connection
-	err = sqlDB.Ping()
-	if err != nil {
-		log.Fatalf("Error pinging database: %v", err)
+	if err := sqlDB.Ping(); err != nil {
+		return nil, fmt.Errorf("pinging database: %w", err)
 	}
 
-	// Your database operations go here
-	fmt.Println("Successfully connected to the database")
+	slog.Info("Connected to the database")
 
-	return db
+	return db, nil
 }
@@ -0,0 +1,62 @@
+package configs
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+type (
+	// flagValue records a flag as the string viper decodes into the field, like an environment variable.
+	flagValue struct {
+		value  string
+		isBool bool
+	}
+)
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(s string) error { f.value = s; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
+
+// flagKeys maps flag names to configuration keys, PORT is set with -port and RATE_LIMIT_DEFAULT with
+// -rate-limit-default.
+var flagKeys = func() map[string]string {
+	flagKeys := make(map[string]string)
+	for _, key := range keys() {
+		flagKeys[flagName(key)] = key
+	}
+
+	return flagKeys
+}()
+
+// keys returns the configuration keys in declaration order.
+func keys() []string {
+	fields := reflect.TypeOf(EnvConfigs{})
+
+	keys := make([]string, 0, fields.NumField())
+	for i := 0; i < fields.NumField(); i++ {
+		keys = append(keys, fields.Field(i).Tag.Get("mapstructure"))
+	}
+
+	return keys
+}
+
+// newFlagSet declares a flag for every configuration key, plus -config for the config file.
+func newFlagSet() (*flag.FlagSet, *string) {
+	flags := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configFile := flags.String("config", "", "configuration file, overrides $CONFIG_FILE")
+
+	fields := reflect.TypeOf(EnvConfigs{})
+	for i := 0; i < fields.NumField(); i++ {
+		key := fields.Field(i).Tag.Get("mapstructure")
+		flags.Var(&flagValue{isBool: fields.Field(i).Type.Kind() == reflect.Bool}, flagName(key), "overrides $"+key)
+	}
+
+	return flags, configFile
+}
+
+func flagName(key string) string {
+	return strings.ReplaceAll(strings.ToLower(key), "_", "-")
+}
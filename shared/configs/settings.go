@@ -0,0 +1,50 @@
+package configs
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+)
+
+type (
+	// Setting is a configuration variable and its value, as printed for operators.
+	Setting struct {
+		Key   string
+		Value string
+	}
+)
+
+// redacted replaces the value of secrets that are set.
+const redacted = "[redacted]"
+
+// Settings lists every variable in declaration order with secrets redacted. Database urls keep everything
+// but their password, so operators can still tell which database is used.
+func (env *EnvConfigs) Settings() []Setting {
+	value := reflect.ValueOf(env).Elem()
+	fields := value.Type()
+
+	settings := make([]Setting, 0, fields.NumField())
+	for i := 0; i < fields.NumField(); i++ {
+		field := fields.Field(i)
+		key := field.Tag.Get("mapstructure")
+		setting := fmt.Sprint(value.Field(i).Interface())
+
+		if field.Tag.Get("secret") == "true" && setting != "" {
+			setting = redact(setting)
+		}
+
+		settings = append(settings, Setting{Key: key, Value: setting})
+	}
+
+	return settings
+}
+
+func redact(secret string) string {
+	u, err := url.Parse(secret)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return redacted
+	}
+
+	// url.Redacted masks the password only
+	return u.Redacted()
+}
@@ -0,0 +1,127 @@
+package configs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Validate checks that every required variable is set and every value is usable, and reports all the
+// problems found at once.
+func (env *EnvConfigs) Validate() error {
+	problems := make([]error, 0)
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	required := func(key, value string) {
+		check(value != "", "%s is required, set it in the environment, the config file or with -%s", key, flagName(key))
+	}
+	required("DATABASE_URL", env.DatabaseUrl)
+	required("SECRET", env.Secret)
+	required("DEBANK_ACCESS_KEY", env.DebankAccessKey)
+	required("MORALIS_ACCESS_KEY", env.MoralisAccessKey)
+
+	if _, _, err := net.SplitHostPort(env.Port); err != nil {
+		problems = append(problems, fmt.Errorf("PORT must be [host]:port, got %q", env.Port))
+	}
+	if env.GrpcPort != "" {
+		_, _, err := net.SplitHostPort(env.GrpcPort)
+		check(err == nil, "GRPC_PORT must be [host]:port, got %q", env.GrpcPort)
+		check(env.GrpcPort != env.Port, "GRPC_PORT must differ from PORT")
+	}
+
+	providerLimits := []struct {
+		name  string
+		rate  float64
+		burst int
+		quota int64
+	}{
+		{"BTC_COM", env.BtcComRateLimit, env.BtcComBurst, env.BtcComDailyQuota},
+		{"MORALIS", env.MoralisRateLimit, env.MoralisBurst, env.MoralisDailyQuota},
+		{"DEBANK", env.DebankRateLimit, env.DebankBurst, env.DebankDailyQuota},
+		{"COINGECKO", env.CoingeckoRateLimit, env.CoingeckoBurst, env.CoingeckoDailyQuota},
+	}
+	for _, limits := range providerLimits {
+		check(limits.rate >= 0, "%s_RATE_LIMIT must not be negative", limits.name)
+		check(limits.rate == 0 || limits.burst >= 1, "%s_BURST must be at least 1 when %s_RATE_LIMIT is set", limits.name, limits.name)
+		check(limits.quota >= 0, "%s_DAILY_QUOTA must not be negative", limits.name)
+	}
+	check(env.QuotaStaleThreshold > 0 && env.QuotaStaleThreshold <= 1, "QUOTA_STALE_THRESHOLD must be in (0, 1], got %v", env.QuotaStaleThreshold)
+
+	check(env.BreakerFailureThreshold >= 1, "BREAKER_FAILURE_THRESHOLD must be at least 1")
+	check(env.BreakerHalfOpenMaxCalls >= 1, "BREAKER_HALF_OPEN_MAX_CALLS must be at least 1")
+	check(env.MaxAddressesPerRequest >= 1, "MAX_ADDRESSES_PER_REQUEST must be at least 1")
+	check(env.CacheBitcoinTTL >= 0 && env.CacheSolanaTTL >= 0 && env.CacheDebankTTL >= 0, "CACHE_*_TTL must not be negative")
+	check(env.CacheMaxEntries >= 1, "CACHE_MAX_ENTRIES must be at least 1")
+	check(env.WsSendBuffer >= 1, "WS_SEND_BUFFER must be at least 1")
+	check(env.WatchConcurrency >= 1, "WATCH_CONCURRENCY must be at least 1")
+	check(env.WsMaxConnectionsPerUser >= 1, "WS_MAX_CONNECTIONS_PER_USER must be at least 1")
+	check(env.WsMaxWatchesPerUser >= 1, "WS_MAX_WATCHES_PER_USER must be at least 1")
+	check(env.EventsReplaySize >= 0, "EVENTS_REPLAY_SIZE must not be negative")
+	check(env.WebhookMaxAttempts >= 1, "WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	if env.SmtpHost != "" {
+		check(env.SmtpPort >= 1 && env.SmtpPort <= 65535, "SMTP_PORT must be a port number, got %d", env.SmtpPort)
+		required("SMTP_FROM", env.SmtpFrom)
+	}
+
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"HTTP_READ_TIMEOUT", env.HTTPReadTimeout},
+		{"HTTP_READ_HEADER_TIMEOUT", env.HTTPReadHeaderTimeout},
+		{"HTTP_WRITE_TIMEOUT", env.HTTPWriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", env.HTTPIdleTimeout},
+		{"SHUTDOWN_TIMEOUT", env.ShutdownTimeout},
+		{"BREAKER_COOLDOWN", env.BreakerCooldown},
+		{"NAME_CACHE_TTL", env.NameCacheTTL},
+		{"NAME_LOOKUP_TIMEOUT", env.NameLookupTimeout},
+		{"COINGECKO_PRICE_MAX_AGE", env.CoingeckoPriceMaxAge},
+		{"DEBANK_WALLET_MAX_AGE", env.DebankWalletMaxAge},
+		{"ACCESS_TOKEN_TTL", env.AccessTokenTTL},
+		{"REFRESH_TOKEN_TTL", env.RefreshTokenTTL},
+		{"WS_PING_INTERVAL", env.WsPingInterval},
+		{"WATCH_INTERVAL", env.WatchInterval},
+		{"WEBHOOK_TIMEOUT", env.WebhookTimeout},
+		{"WEBHOOK_RETRY_BASE", env.WebhookRetryBase},
+		{"SMTP_TIMEOUT", env.SmtpTimeout},
+		{"ALERT_COOLDOWN", env.AlertCooldown},
+	}
+	for _, duration := range durations {
+		check(duration.value > 0, "%s must be a positive duration such as 30s or 24h", duration.key)
+	}
+
+	urls := []struct {
+		key   string
+		value string
+	}{
+		{"EVM_RPC_URL", env.EvmRpcURL},
+		{"SNS_PROXY_URL", env.SnsProxyURL},
+		{"BTC_COM_API_URL", env.BtcComAPIURL},
+		{"MORALIS_API_URL", env.MoralisAPIURL},
+		{"DEBANK_API_URL", env.DebankAPIURL},
+		{"COINGECKO_API_URL", env.CoingeckoAPIURL},
+	}
+	for _, u := range urls {
+		parsed, err := url.Parse(u.value)
+		check(err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != "", "%s must be an http(s) url, got %q", u.key, u.value)
+	}
+
+	var level slog.Level
+	check(level.UnmarshalText([]byte(env.LogLevel)) == nil, "LOG_LEVEL must be debug, info, warn or error, got %q", env.LogLevel)
+	check(env.LogFormat == "json" || env.LogFormat == "text", "LOG_FORMAT must be json or text, got %q", env.LogFormat)
+
+	if env.TracingEnabled {
+		endpoint, err := url.Parse(env.OtlpEndpoint)
+		check(err == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "", "OTLP_ENDPOINT must be an http(s) url, got %q", env.OtlpEndpoint)
+		check(env.TracingSampleRatio > 0 && env.TracingSampleRatio <= 1, "TRACING_SAMPLE_RATIO must be in (0, 1], got %v", env.TracingSampleRatio)
+	}
+
+	return errors.Join(problems...)
+}
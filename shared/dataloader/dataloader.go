@@ -0,0 +1,139 @@
+// Package dataloader batches the loads of keys made concurrently, such as those of the resolvers of the items
+// of a GraphQL list, into one fetch, and caches the results for the lifetime of the loader.
+package dataloader
+
+import (
+	"context"
+	er "errors"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned by Load for keys missing from the result of the fetch.
+var ErrNotFound = er.New("not found")
+
+// FetchFunc loads the values of keys. Keys that do not exist are left out of the result.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader loads values by key in batches. A loader is meant to live as long as one request: values are
+// cached, errors included, and never refreshed.
+type Loader[K comparable, V any] struct {
+	fetch    FetchFunc[K, V]
+	wait     time.Duration
+	maxBatch int
+
+	mu    sync.Mutex
+	cache map[K]*result[V]
+	batch *batch[K, V]
+}
+
+type (
+	result[V any] struct {
+		done  chan struct{}
+		value V
+		err   error
+	}
+
+	batch[K comparable, V any] struct {
+		keys    []K
+		results []*result[V]
+		full    chan struct{}
+	}
+)
+
+// New returns a loader that waits up to wait after the first load of a batch for more keys, and fetches at
+// most maxBatch keys at once, 0 for no bound.
+func New[K comparable, V any](fetch FetchFunc[K, V], wait time.Duration, maxBatch int) *Loader[K, V] {
+	return &Loader[K, V]{fetch: fetch, wait: wait, maxBatch: maxBatch, cache: make(map[K]*result[V])}
+}
+
+// Load returns the value of key, joining the pending batch or starting one.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result[V]{done: make(chan struct{})}
+		l.cache[key] = r
+
+		if l.batch == nil {
+			l.batch = &batch[K, V]{full: make(chan struct{})}
+			go l.run(ctx, l.batch)
+		}
+
+		b := l.batch
+		b.keys = append(b.keys, key)
+		b.results = append(b.results, r)
+
+		if l.maxBatch > 0 && len(b.keys) >= l.maxBatch {
+			l.batch = nil
+			close(b.full)
+		}
+	}
+
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// LoadMany returns the values of keys, in order, loading them in one batch. errs is nil when every key loaded,
+// otherwise it holds the error of each key, nil for those that loaded.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) (values []V, errs []error) {
+	values = make([]V, len(keys))
+	loadErrs := make([]error, len(keys))
+
+	var wg sync.WaitGroup
+	wg.Add(len(keys))
+	for i, key := range keys {
+		go func(i int, key K) {
+			defer wg.Done()
+			values[i], loadErrs[i] = l.Load(ctx, key)
+		}(i, key)
+	}
+	wg.Wait()
+
+	for _, err := range loadErrs {
+		if err != nil {
+			return values, loadErrs
+		}
+	}
+
+	return values, nil
+}
+
+// run fetches the keys of b once it is full or the wait is over.
+func (l *Loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case <-b.full:
+	case <-timer.C:
+		l.mu.Lock()
+		if l.batch == b {
+			l.batch = nil
+		}
+		l.mu.Unlock()
+	}
+
+	// the keys come from several loads, the batch is fetched even if the one that started it gives up
+	values, err := l.fetch(context.WithoutCancel(ctx), b.keys)
+
+	for i, key := range b.keys {
+		r := b.results[i]
+		if err != nil {
+			r.err = err
+		} else if value, ok := values[key]; ok {
+			r.value = value
+		} else {
+			r.err = ErrNotFound
+		}
+		close(r.done)
+	}
+}
@@ -0,0 +1,170 @@
+package dataloader
+
+import (
+	"context"
+	er "errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder fetches the squares of the positive keys and records the batches it was called with.
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (r *recorder) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	batch := append([]int{}, keys...)
+	sort.Ints(batch)
+	r.batches = append(r.batches, batch)
+
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	values := make(map[int]int)
+	for _, key := range keys {
+		if key > 0 {
+			values[key] = key * key
+		}
+	}
+
+	return values, nil
+}
+
+func (r *recorder) calls() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.batches)
+}
+
+func TestLoadBatches(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, 20*time.Millisecond, 0)
+
+	values, errs := loader.LoadMany(context.Background(), []int{3, 1, 2, 3})
+	if errs != nil {
+		t.Fatalf("LoadMany() errs = %v", errs)
+	}
+	if want := []int{9, 1, 4, 9}; !equal(values, want) {
+		t.Errorf("LoadMany() = %v, want %v", values, want)
+	}
+	if len(r.batches) != 1 || !equal(r.batches[0], []int{1, 2, 3}) {
+		t.Errorf("batches = %v, want one of the unique keys", r.batches)
+	}
+}
+
+func TestLoadMaxBatch(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, time.Hour, 2)
+
+	// full batches are fetched without waiting
+	values, errs := loader.LoadMany(context.Background(), []int{1, 2, 3, 4})
+	if errs != nil || !equal(values, []int{1, 4, 9, 16}) {
+		t.Fatalf("LoadMany() = %v, %v", values, errs)
+	}
+
+	for _, batch := range r.batches {
+		if len(batch) != 2 {
+			t.Errorf("batches = %v, want batches of 2 keys", r.batches)
+			break
+		}
+	}
+}
+
+func TestLoadCaches(t *testing.T) {
+	r := &recorder{}
+	loader := New(r.fetch, time.Millisecond, 0)
+
+	for i := 0; i < 3; i++ {
+		if value, err := loader.Load(context.Background(), 5); err != nil || value != 25 {
+			t.Fatalf("Load(5) = %d, %v, want 25", value, err)
+		}
+	}
+
+	if _, err := loader.Load(context.Background(), -1); !er.Is(err, ErrNotFound) {
+		t.Errorf("Load(-1) = %v, want ErrNotFound", err)
+	}
+	if _, err := loader.Load(context.Background(), -1); !er.Is(err, ErrNotFound) {
+		t.Errorf("Load(-1) again = %v, want ErrNotFound", err)
+	}
+
+	if calls := r.calls(); calls != 2 {
+		t.Errorf("fetches = %d, want 2", calls)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	failure := er.New("database down")
+	r := &recorder{err: failure}
+	loader := New(r.fetch, time.Millisecond, 0)
+
+	_, errs := loader.LoadMany(context.Background(), []int{1, 2})
+	if len(errs) != 2 || !er.Is(errs[0], failure) || !er.Is(errs[1], failure) {
+		t.Errorf("LoadMany() errs = %v, want the fetch error for every key", errs)
+	}
+
+	// errors are cached like values
+	if _, err := loader.Load(context.Background(), 1); !er.Is(err, failure) || r.calls() != 1 {
+		t.Errorf("Load(1) = %v after %d fetches, want the cached error", err, r.calls())
+	}
+}
+
+func TestLoadManyPartial(t *testing.T) {
+	loader := New((&recorder{}).fetch, time.Millisecond, 0)
+
+	values, errs := loader.LoadMany(context.Background(), []int{2, -2})
+	if values[0] != 4 || len(errs) != 2 || errs[0] != nil || !er.Is(errs[1], ErrNotFound) {
+		t.Errorf("LoadMany() = %v, %v, want 4 and ErrNotFound for -2", values, errs)
+	}
+}
+
+func TestLoadCanceled(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	loader := New(func(ctx context.Context, keys []int) (map[int]int, error) {
+		close(started)
+		<-release
+		return map[int]int{1: 1}, nil
+	}, time.Millisecond, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := loader.Load(ctx, 1)
+		done <- err
+	}()
+
+	<-started
+	cancel()
+	if err := <-done; !er.Is(err, context.Canceled) {
+		t.Errorf("Load() = %v, want context.Canceled", err)
+	}
+
+	// the batch is still fetched for the other loads of the key
+	close(release)
+	if value, err := loader.Load(context.Background(), 1); err != nil || value != 1 {
+		t.Errorf("Load(1) = %d, %v, want 1", value, err)
+	}
+}
+
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
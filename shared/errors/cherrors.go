@@ -1,24 +1,55 @@
 package errors
 
 import (
-	"github.com/gin-gonic/gin"
+	er "errors"
+	"net/http"
+	"slices"
+	"strings"
 )
 
-func HandleChannelErrors(errorCh <-chan error, c *gin.Context) []string {
-	var errs []error
+// upstreamPriority decides which mapped upstream status wins when several addresses fail differently.
+var upstreamPriority = map[int]int{
+	http.StatusBadGateway:         1,
+	http.StatusServiceUnavailable: 2,
+	http.StatusTooManyRequests:    3,
+}
+
+// HandleChannelErrors drains errorCh and merges every error into a single APIError, or returns nil when
+// there were none. Upstream failures keep their mapped status and generic message, anything else, like a
+// failing database, is an internal error. The errors themselves, upstream bodies included, are kept as the
+// cause that is logged rather than sent.
+func HandleChannelErrors(errorCh <-chan error) *APIError {
+	var apiErr *APIError
+	errs := make([]error, 0)
+	messages := make([]string, 0)
 
 	for err := range errorCh {
 		errs = append(errs, err)
-	}
 
-	errorMessages := make([]string, 0, len(errs))
-	if len(errs) > 0 {
-		for _, err := range errs {
-			errorMessages = append(errorMessages, err.Error())
+		var upstreamErr *UpstreamError
+		if !er.As(err, &upstreamErr) {
+			continue
+		}
+
+		mapped := upstreamErr.APIError()
+		if !slices.Contains(messages, mapped.Message) {
+			messages = append(messages, mapped.Message)
 		}
+		if apiErr == nil || upstreamPriority[mapped.Code] > upstreamPriority[apiErr.Code] {
+			apiErr = mapped
+		}
+	}
 
-		return errorMessages
+	if len(errs) == 0 {
+		return nil
 	}
 
-	return errorMessages
+	if apiErr == nil {
+		return NewInternalError("failed to load wallets", er.Join(errs...))
+	}
+
+	apiErr.Message = strings.Join(messages, "; ")
+	apiErr.Err = er.Join(errs...)
+
+	return apiErr
 }
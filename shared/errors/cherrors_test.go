@@ -0,0 +1,80 @@
+package errors
+
+import (
+	er "errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestHandleChannelErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		errs        []error
+		wantNil     bool
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "no errors", wantNil: true},
+		{
+			name:        "upstream body is not sent",
+			errs:        []error{NewUpstreamError("debank", http.StatusUnauthorized, 0, []byte(`{"error":"access key abc123 is invalid"}`))},
+			wantCode:    http.StatusBadGateway,
+			wantMessage: "upstream debank failed (status 401)",
+		},
+		{
+			name: "rate limits win over other upstream failures",
+			errs: []error{
+				NewUpstreamError("btc.com", http.StatusInternalServerError, 0, []byte("boom")),
+				NewUpstreamError("btc.com", http.StatusTooManyRequests, 0, []byte("slow down")),
+			},
+			wantCode:    http.StatusTooManyRequests,
+			wantMessage: "upstream btc.com failed (status 500); upstream btc.com failed (status 429)",
+		},
+		{
+			name:        "failures reported in a successful response are upstream failures",
+			errs:        []error{NewUpstreamFailure("btc.com", http.StatusOK, "address abc123 is invalid")},
+			wantCode:    http.StatusBadGateway,
+			wantMessage: "upstream btc.com reported a failure",
+		},
+		{
+			name:        "other failures are internal",
+			errs:        []error{er.New("pq: connection refused")},
+			wantCode:    http.StatusInternalServerError,
+			wantMessage: "failed to load wallets",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errorCh := make(chan error, len(tt.errs))
+			for _, err := range tt.errs {
+				errorCh <- err
+			}
+			close(errorCh)
+
+			apiErr := HandleChannelErrors(errorCh)
+			if tt.wantNil {
+				if apiErr != nil {
+					t.Fatalf("HandleChannelErrors() = %v, want nil", apiErr)
+				}
+				return
+			}
+
+			if apiErr == nil {
+				t.Fatal("HandleChannelErrors() = nil, want an error")
+			}
+			if apiErr.Code != tt.wantCode || apiErr.Message != tt.wantMessage {
+				t.Errorf("HandleChannelErrors() = %d %q, want %d %q", apiErr.Code, apiErr.Message, tt.wantCode, tt.wantMessage)
+			}
+			for _, err := range tt.errs {
+				if !er.Is(apiErr.Err, err) {
+					t.Errorf("cause does not keep %v", err)
+				}
+			}
+			if strings.Contains(apiErr.Message, "abc123") {
+				t.Errorf("message %q leaks the upstream body", apiErr.Message)
+			}
+		})
+	}
+}
@@ -1,26 +1,63 @@
 package errors
 
 import (
+	er "errors"
 	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type (
+	// Kind is the stable, machine-readable class of an error, sent as the code of the problem.
+	Kind string
+
 	APIError struct {
-		Code    int    `json:"code"`
-		Message string `json:"message,omitempty"`
+		Code       int           `json:"code"`
+		Kind       Kind          `json:"kind"`
+		Message    string        `json:"message,omitempty"`
+		RetryAfter int           `json:"retry_after,omitempty"` // seconds, sent as the Retry-After header
+		Details    []ErrorDetail `json:"details,omitempty"`
+		Err        error         `json:"-"` // cause, logged but never sent to clients
+	}
+
+	// ErrorDetail explains why one input value of a request was rejected.
+	ErrorDetail struct {
+		Field  string `json:"field"`
+		Value  string `json:"value,omitempty"`
+		Reason string `json:"reason"`
 	}
 )
 
+const (
+	KindValidation          Kind = "validation"
+	KindUnauthorized        Kind = "unauthorized"
+	KindForbidden           Kind = "forbidden"
+	KindNotFound            Kind = "not_found"
+	KindConflict            Kind = "conflict"
+	KindRateLimited         Kind = "rate_limited"
+	KindUpstreamUnavailable Kind = "upstream_unavailable"
+	KindInternal            Kind = "internal"
+)
+
 func (e *APIError) Error() string {
-	return fmt.Sprintf("%d: %s", e.Code, e.Message)
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %s: %v", e.Code, e.Kind, e.Message, e.Err)
+	}
+
+	return fmt.Sprintf("%d %s: %s", e.Code, e.Kind, e.Message)
 }
 
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// NewHttpError returns APIError with the given status code, of the kind that status stands for.
 func NewHttpError(code int, message string) *APIError {
 	return &APIError{
 		Code:    code,
+		Kind:    kindOf(code),
 		Message: message,
 	}
 }
@@ -30,6 +67,14 @@ func NewBadRequestError(message string) *APIError {
 	return NewHttpError(http.StatusBadRequest, message)
 }
 
+// NewValidationError returns APIError with status code 400 and the reason of every rejected value.
+func NewValidationError(message string, details []ErrorDetail) *APIError {
+	apiErr := NewHttpError(http.StatusBadRequest, message)
+	apiErr.Details = details
+
+	return apiErr
+}
+
 // NewUnauthorizedError returns APIError with status code 401.
 func NewUnauthorizedError(message string) *APIError {
 	return NewHttpError(http.StatusUnauthorized, message)
@@ -45,11 +90,65 @@ func NewForbiddenError(message string) *APIError {
 	return NewHttpError(http.StatusForbidden, message)
 }
 
+// NewConflictError returns APIError with status code 409.
+func NewConflictError(message string) *APIError {
+	return NewHttpError(http.StatusConflict, message)
+}
+
+// NewRateLimitedError returns APIError with status code 429, to be retried after retryAfter seconds.
+func NewRateLimitedError(message string, retryAfter int) *APIError {
+	apiErr := NewHttpError(http.StatusTooManyRequests, message)
+	apiErr.RetryAfter = retryAfter
+
+	return apiErr
+}
+
 // NewInternalServerError returns APIError with status code 500.
 func NewInternalServerError(message string) *APIError {
 	return NewHttpError(http.StatusInternalServerError, message)
 }
 
+// NewInternalError returns APIError with status code 500 caused by err, which is logged but not sent.
+func NewInternalError(message string, err error) *APIError {
+	apiErr := NewInternalServerError(message)
+	apiErr.Err = err
+
+	return apiErr
+}
+
+// NewDatabaseError returns a 404 with message notFound when err is gorm.ErrRecordNotFound, and a 500
+// otherwise: a failing database is not the client's fault.
+func NewDatabaseError(err error, notFound string) *APIError {
+	if er.Is(err, gorm.ErrRecordNotFound) {
+		return NewNotFoundError(notFound)
+	}
+
+	return NewInternalError("database error", err)
+}
+
+func kindOf(code int) Kind {
+	switch code {
+	case http.StatusUnauthorized:
+		return KindUnauthorized
+	case http.StatusForbidden:
+		return KindForbidden
+	case http.StatusNotFound:
+		return KindNotFound
+	case http.StatusConflict:
+		return KindConflict
+	case http.StatusTooManyRequests:
+		return KindRateLimited
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return KindUpstreamUnavailable
+	}
+
+	if code >= http.StatusInternalServerError {
+		return KindInternal
+	}
+
+	return KindValidation
+}
+
 func HandleHttpError(c *gin.Context, err error) {
 	c.Error(err)
 }
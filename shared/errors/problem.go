@@ -0,0 +1,42 @@
+package errors
+
+import "net/http"
+
+// ProblemContentType is the media type of error responses, see RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+type (
+	// Problem is the RFC 7807 problem details document every error response carries. Code is the stable kind
+	// of the error clients should switch on, Errors the reason of every rejected input value.
+	Problem struct {
+		Type       string        `json:"type"`
+		Title      string        `json:"title"`
+		Status     int           `json:"status"`
+		Detail     string        `json:"detail,omitempty"`
+		Instance   string        `json:"instance,omitempty"`
+		Code       Kind          `json:"code"`
+		RequestID  string        `json:"request_id,omitempty"`
+		RetryAfter int           `json:"retry_after,omitempty"`
+		Errors     []ErrorDetail `json:"errors,omitempty"`
+	}
+)
+
+// Problem returns the problem document of e for the request of path instance with ID requestID.
+func (e *APIError) Problem(instance, requestID string) *Problem {
+	kind := e.Kind
+	if kind == "" {
+		kind = kindOf(e.Code)
+	}
+
+	return &Problem{
+		Type:       "urn:0xbase:problem:" + string(kind),
+		Title:      http.StatusText(e.Code),
+		Status:     e.Code,
+		Detail:     e.Message,
+		Instance:   instance,
+		Code:       kind,
+		RequestID:  requestID,
+		RetryAfter: e.RetryAfter,
+		Errors:     e.Details,
+	}
+}
@@ -0,0 +1,99 @@
+package errors
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+)
+
+// maxBodyExcerpt caps how much of an upstream response body is kept on the error.
+const maxBodyExcerpt = 512
+
+type (
+	// UpstreamError describes a failed call to an external data provider, either a transport
+	// failure (Err is set), a non-2xx response or a 2xx response reporting a failure in its body.
+	UpstreamError struct {
+		Provider   string
+		StatusCode int
+		RetryAfter time.Duration
+		Body       string
+		Err        error
+	}
+)
+
+// NewUpstreamError returns an UpstreamError for a non-2xx response, keeping an excerpt of the body.
+func NewUpstreamError(provider string, statusCode int, retryAfter time.Duration, body []byte) *UpstreamError {
+	if len(body) > maxBodyExcerpt {
+		body = body[:maxBodyExcerpt]
+	}
+
+	return &UpstreamError{
+		Provider:   provider,
+		StatusCode: statusCode,
+		RetryAfter: retryAfter,
+		Body:       string(body),
+	}
+}
+
+// NewUpstreamTransportError returns an UpstreamError for a request that never got a response.
+func NewUpstreamTransportError(provider string, err error) *UpstreamError {
+	return &UpstreamError{
+		Provider: provider,
+		Err:      err,
+	}
+}
+
+// NewUpstreamFailure returns an UpstreamError for a response the provider answered successfully but whose
+// body reports a failure, such as a btc.com status "fail". message is the failure reported by the provider.
+func NewUpstreamFailure(provider string, statusCode int, message string) *UpstreamError {
+	return NewUpstreamError(provider, statusCode, 0, []byte(message))
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Provider, e.Err)
+	}
+
+	return fmt.Sprintf("%s: upstream returned %d: %s", e.Provider, e.StatusCode, e.Body)
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the same request may succeed when retried.
+func (e *UpstreamError) Temporary() bool {
+	return e.Err != nil || e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// APIError maps the upstream failure to the error returned to our own clients:
+// 429 stays 429, unavailable upstreams become 503 and everything else, failures reported in a successful
+// response included, is a 502. The message only names the provider and its status; the response body and
+// transport error stay on the wrapped cause, which is logged.
+func (e *UpstreamError) APIError() *APIError {
+	code := http.StatusBadGateway
+
+	switch {
+	case e.StatusCode == http.StatusTooManyRequests:
+		code = http.StatusTooManyRequests
+	case e.Err != nil, e.StatusCode == http.StatusServiceUnavailable, e.StatusCode == http.StatusGatewayTimeout:
+		code = http.StatusServiceUnavailable
+	}
+
+	message := fmt.Sprintf("upstream %s failed (status %d)", e.Provider, e.StatusCode)
+	switch {
+	case e.Err != nil:
+		message = fmt.Sprintf("upstream %s is unreachable", e.Provider)
+	case e.StatusCode < http.StatusMultipleChoices:
+		message = fmt.Sprintf("upstream %s reported a failure", e.Provider)
+	}
+
+	return &APIError{
+		Code:       code,
+		Kind:       kindOf(code),
+		Message:    message,
+		Err:        e,
+		RetryAfter: int(math.Ceil(e.RetryAfter.Seconds())),
+	}
+}
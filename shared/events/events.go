@@ -0,0 +1,331 @@
+// Package events is an in-process publish/subscribe bus for what changes in the service, such as prices and
+// wallet balances. Events are numbered, and the latest ones are kept so that a subscriber reconnecting after a
+// short outage can resume where it left off.
+package events
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+)
+
+// Types of the events published.
+const (
+	TypePrice   = "price"   // a price was fetched, see PriceTopic
+	TypeBalance = "balance" // the balances of a wallet changed, see WalletTopic
+	// a wallet could not be refreshed from its provider, see WalletTopic
+	TypeRefreshFailed = "refresh_failed"
+)
+
+// defaultReplaySize is the number of events kept for resuming subscribers, see SetBus.
+const defaultReplaySize = 1000
+
+// ErrLagged closes a subscription whose subscriber did not keep up with the events.
+var ErrLagged = errors.New("subscriber lagged behind the events")
+
+type (
+	// Event is something that changed. IDs increase by one for every event published on a bus, from a
+	// number taken from the clock so that the IDs of a restarted process are above those it gave before.
+	Event struct {
+		ID    uint64      `json:"id"`
+		Type  string      `json:"type"`
+		Topic string      `json:"topic"`
+		Time  time.Time   `json:"time"`
+		Data  interface{} `json:"data"`
+	}
+
+	// Bus delivers the events published to the subscribers of their topic. Publishing never blocks: a
+	// subscriber whose buffer is full is dropped with ErrLagged.
+	Bus struct {
+		mu          sync.Mutex
+		firstID     uint64
+		nextID      uint64
+		replay      []Event // ring of the latest events
+		replaySize  int
+		subscribers map[*Subscription]struct{}
+	}
+
+	// Subscription receives the events of its topics, or every event, on C until it is closed.
+	Subscription struct {
+		bus    *Bus
+		ch     chan Event
+		topics map[string]struct{}
+		all    bool
+		err    error
+		closed bool
+	}
+)
+
+var (
+	mu  sync.RWMutex
+	bus = NewBus(defaultReplaySize)
+)
+
+// NewBus returns a bus keeping the last replaySize events for Since.
+func NewBus(replaySize int) *Bus {
+	first := uint64(time.Now().UnixMicro())
+
+	return &Bus{
+		firstID:     first,
+		nextID:      first,
+		replay:      make([]Event, 0, replaySize),
+		replaySize:  replaySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// SetBus replaces the bus of Publish, Subscribe and Since.
+func SetBus(b *Bus) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	bus = b
+}
+
+func current() *Bus {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return bus
+}
+
+// Publish publishes an event on the bus set with SetBus.
+func Publish(topic, typ string, data interface{}) Event {
+	return current().Publish(topic, typ, data)
+}
+
+// Subscribe subscribes to the bus set with SetBus.
+func Subscribe(buffer int, topics ...string) *Subscription {
+	return current().Subscribe(buffer, topics...)
+}
+
+// SubscribeAll subscribes to every topic of the bus set with SetBus.
+func SubscribeAll(buffer int) *Subscription {
+	return current().SubscribeAll(buffer)
+}
+
+// Since returns the events of the bus set with SetBus published after id.
+func Since(id uint64, topics ...string) ([]Event, bool) {
+	return current().Since(id, topics...)
+}
+
+// SinceAll returns every event of the bus set with SetBus published after id.
+func SinceAll(id uint64) ([]Event, bool) {
+	return current().SinceAll(id)
+}
+
+// LastID returns the ID of the last event published on the bus set with SetBus.
+func LastID() uint64 {
+	return current().LastID()
+}
+
+// Watched reports whether a subscriber of the bus set with SetBus listens to topic.
+func Watched(topic string) bool {
+	return current().Watched(topic)
+}
+
+// PriceTopic is the topic of the prices of asset, a coingecko id, in currency.
+func PriceTopic(asset, currency string) string {
+	return "price:" + asset + ":" + currency
+}
+
+// WalletTopic is the topic of the balances of the wallet of address.
+func WalletTopic(address string) string {
+	return "wallet:" + address
+}
+
+// Kind returns the kind of topic, such as price or wallet.
+func Kind(topic string) string {
+	kind, _, _ := strings.Cut(topic, ":")
+	return kind
+}
+
+// Publish sends an event of type typ with data to the subscribers of topic and returns it.
+func (b *Bus) Publish(topic, typ string, data interface{}) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	event := Event{ID: b.nextID, Type: typ, Topic: topic, Time: time.Now().UTC(), Data: data}
+	b.nextID++
+
+	if b.replaySize > 0 {
+		if len(b.replay) < b.replaySize {
+			b.replay = append(b.replay, event)
+		} else {
+			b.replay[int((event.ID-b.firstID)%uint64(b.replaySize))] = event
+		}
+	}
+
+	for s := range b.subscribers {
+		if _, ok := s.topics[topic]; !ok && !s.all {
+			continue
+		}
+
+		select {
+		case s.ch <- event:
+		default:
+			b.drop(s, ErrLagged)
+			metrics.EventSubscriberLagged()
+		}
+	}
+	metrics.EventPublished(typ)
+
+	return event
+}
+
+// Subscribe returns a subscription to topics, buffering up to buffer events.
+func (b *Bus) Subscribe(buffer int, topics ...string) *Subscription {
+	s := &Subscription{bus: b, ch: make(chan Event, buffer), topics: make(map[string]struct{})}
+	for _, topic := range topics {
+		s.topics[topic] = struct{}{}
+	}
+
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	return s
+}
+
+// SubscribeAll returns a subscription to every topic, buffering up to buffer events.
+func (b *Bus) SubscribeAll(buffer int) *Subscription {
+	s := b.Subscribe(buffer)
+
+	b.mu.Lock()
+	s.all = true
+	b.mu.Unlock()
+
+	return s
+}
+
+// Since returns the events of topics published after the event id, oldest first. ok is false when some of
+// them are no longer kept, the subscriber has then missed events and should reload what it shows.
+func (b *Bus) Since(id uint64, topics ...string) (events []Event, ok bool) {
+	wanted := make(map[string]struct{}, len(topics))
+	for _, topic := range topics {
+		wanted[topic] = struct{}{}
+	}
+
+	return b.since(id, func(topic string) bool { _, ok := wanted[topic]; return ok })
+}
+
+// SinceAll returns every event published after the event id, like Since.
+func (b *Bus) SinceAll(id uint64) ([]Event, bool) {
+	return b.since(id, func(string) bool { return true })
+}
+
+func (b *Bus) since(id uint64, wanted func(topic string) bool) (events []Event, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// an ID this bus did not give, e.g. one of the process before a restart
+	if id >= b.nextID || id+1 < b.firstID {
+		return nil, false
+	}
+
+	oldest := b.nextID - uint64(len(b.replay))
+	if id+1 < oldest {
+		return nil, false
+	}
+
+	for next := id + 1; next < b.nextID; next++ {
+		event := b.replay[int((next-b.firstID)%uint64(b.replaySize))]
+		if wanted(event.Topic) {
+			events = append(events, event)
+		}
+	}
+
+	return events, true
+}
+
+// Watched reports whether a subscriber listens to topic, publishers use it to skip the work of events no one
+// would receive.
+func (b *Bus) Watched(topic string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for s := range b.subscribers {
+		if _, ok := s.topics[topic]; ok || s.all {
+			return true
+		}
+	}
+
+	return false
+}
+
+// LastID returns the ID of the last event published, or the one before the first event when none was.
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.nextID - 1
+}
+
+// drop closes s with err, the caller holds b.mu.
+func (b *Bus) drop(s *Subscription, err error) {
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	s.err = err
+	delete(b.subscribers, s)
+	close(s.ch)
+}
+
+// C receives the events of the subscription, it is closed when the subscription is.
+func (s *Subscription) C() <-chan Event {
+	return s.ch
+}
+
+// Add subscribes to more topics.
+func (s *Subscription) Add(topics ...string) {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	for _, topic := range topics {
+		s.topics[topic] = struct{}{}
+	}
+}
+
+// Remove unsubscribes from topics.
+func (s *Subscription) Remove(topics ...string) {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	for _, topic := range topics {
+		delete(s.topics, topic)
+	}
+}
+
+// Topics returns the topics of the subscription.
+func (s *Subscription) Topics() []string {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+
+	return topics
+}
+
+// Err returns why the subscription was closed by the bus, nil while it is open or when it was closed with Close.
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	return s.err
+}
+
+// Close ends the subscription.
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	s.bus.drop(s, nil)
+}
@@ -0,0 +1,129 @@
+// Package lifecycle tracks the background work of the process so that shutdown can wait for it and release
+// resources in order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type (
+	// Workers reports the background goroutines still running.
+	Workers struct {
+		Running  int   `json:"running"`
+		OldestMs int64 `json:"oldest_ms"` // how long the oldest one has been running
+	}
+
+	closer struct {
+		name string
+		fn   func(ctx context.Context) error
+	}
+)
+
+var (
+	workers sync.WaitGroup
+
+	runningMu sync.Mutex
+	running   = make(map[uint64]time.Time)
+	nextID    uint64
+
+	mu      sync.Mutex
+	closers []closer
+
+	ctx, cancel = context.WithCancel(context.Background())
+)
+
+// Go runs fn in a goroutine that Shutdown waits for, such as the provider calls and saves fanned out by a
+// request, which must not be cut off half way by a deploy.
+func Go(fn func()) {
+	runningMu.Lock()
+	nextID++
+	id := nextID
+	running[id] = time.Now()
+	runningMu.Unlock()
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		defer func() {
+			runningMu.Lock()
+			delete(running, id)
+			runningMu.Unlock()
+		}()
+
+		fn()
+	}()
+}
+
+// Running reports how many goroutines started with Go are still running and for how long the oldest has
+// been, which shows background work lagging behind.
+func Running() Workers {
+	runningMu.Lock()
+	defer runningMu.Unlock()
+
+	status := Workers{Running: len(running)}
+	for _, startedAt := range running {
+		if age := time.Since(startedAt).Milliseconds(); age > status.OldestMs {
+			status.OldestMs = age
+		}
+	}
+
+	return status
+}
+
+// ShuttingDown reports whether shutdown has started.
+func ShuttingDown() bool {
+	return ctx.Err() != nil
+}
+
+// Context is cancelled when shutdown starts. Long running workers watch it to stop taking new work.
+func Context() context.Context {
+	return ctx
+}
+
+// OnShutdown registers fn to run on shutdown, after the tracked goroutines finished. Closers run in the
+// reverse order of registration, so resources opened first, like the database pool, are closed last.
+func OnShutdown(name string, fn func(ctx context.Context) error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	closers = append(closers, closer{name: name, fn: fn})
+}
+
+// Shutdown cancels Context, waits for the tracked goroutines and runs the registered closers. When ctx ends
+// before the goroutines finished, the closers still run and the returned error says so.
+func Shutdown(ctx context.Context) error {
+	cancel()
+
+	problems := make([]error, 0)
+
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		problems = append(problems, fmt.Errorf("background work still running: %w", ctx.Err()))
+	}
+
+	mu.Lock()
+	registered := closers
+	closers = nil
+	mu.Unlock()
+
+	for i := len(registered) - 1; i >= 0; i-- {
+		slog.Info("Closing " + registered[i].name)
+		if err := registered[i].fn(ctx); err != nil {
+			problems = append(problems, fmt.Errorf("closing %s: %w", registered[i].name, err))
+		}
+	}
+
+	return errors.Join(problems...)
+}
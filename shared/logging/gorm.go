@@ -0,0 +1,66 @@
+package logging
+
+import (
+	"context"
+	er "errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowQuery is the duration above which queries are logged as warnings.
+const slowQuery = 200 * time.Millisecond
+
+type (
+	// GormLogger writes the gorm logs through slog, with the request ID of the query context. Failed and slow
+	// queries are logged as warnings or errors, every other query at debug level.
+	GormLogger struct{}
+)
+
+func (l GormLogger) LogMode(logger.LogLevel) logger.Interface {
+	// the level is the one of the slog handler
+	return l
+}
+
+func (GormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (GormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (GormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+func (GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+
+	level := slog.LevelDebug
+	switch {
+	case err != nil && !er.Is(err, gorm.ErrRecordNotFound):
+		level = slog.LevelError
+	case elapsed > slowQuery:
+		level = slog.LevelWarn
+	}
+
+	if !slog.Default().Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Duration("duration", elapsed),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+
+	slog.LogAttrs(ctx, level, "query", attrs...)
+}
@@ -0,0 +1,140 @@
+// Package logging sets up structured JSON logging with log/slog. Records logged with a request context carry
+// its request ID and trace ID, and credentials are redacted wherever they appear.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type (
+	requestIDKey struct{}
+
+	// contextHandler adds the request and trace IDs of the record context to every record.
+	contextHandler struct {
+		slog.Handler
+	}
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	// sensitiveKeys are attribute keys, compared in lower case, whose values are never logged
+	sensitiveKeys = map[string]bool{
+		"authorization": true,
+		"x-api-key":     true,
+		"accesskey":     true,
+		"api_key":       true,
+		"apikey":        true,
+		"token":         true,
+		"access_token":  true,
+		"refresh_token": true,
+		"password":      true,
+		"secret":        true,
+	}
+
+	// jwtPattern matches JWTs in free text, like error messages quoting a header
+	jwtPattern = regexp.MustCompile(`eyJ[\w-]+\.[\w-]+\.[\w-]*`)
+
+	// apiKeyPattern matches our api keys, see utils.GenerateAPIKey
+	apiKeyPattern = regexp.MustCompile(`0xb_[0-9a-f]{16,}`)
+
+	// bearerPattern matches credentials following an authorization scheme
+	bearerPattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[^\s"',]+`)
+)
+
+// Setup makes a handler writing to stderr at level in format, LOG_LEVEL and LOG_FORMAT, the default of slog
+// and of the log package.
+func Setup(level, format string) error {
+	handler, err := NewHandler(os.Stderr, level, format)
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(slog.New(handler))
+
+	return nil
+}
+
+// NewHandler returns a handler writing records of level and above to w, as JSON or as text.
+func NewHandler(w io.Writer, level, format string) (slog.Handler, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+
+	options := &slog.HandlerOptions{Level: l, ReplaceAttr: redact}
+
+	switch format {
+	case "json":
+		return &contextHandler{slog.NewJSONHandler(w, options)}, nil
+	case "text":
+		return &contextHandler{slog.NewTextHandler(w, options)}, nil
+	}
+
+	return nil, fmt.Errorf("LOG_FORMAT must be json or text, got %q", format)
+}
+
+// WithRequestID returns a context carrying the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of ctx, or an empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			record.AddAttrs(slog.String("request_id", id))
+		}
+		if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+			record.AddAttrs(slog.String("trace_id", span.TraceID().String()))
+		}
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{h.Handler.WithGroup(name)}
+}
+
+// redact replaces the values of sensitive attributes, and credentials found in messages and other strings.
+func redact(_ []string, attr slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(attr.Key)] {
+		return slog.String(attr.Key, redacted)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, Redact(attr.Value.String()))
+	case slog.KindAny:
+		if err, ok := attr.Value.Any().(error); ok {
+			return slog.String(attr.Key, Redact(err.Error()))
+		}
+	}
+
+	return attr
+}
+
+// Redact removes the JWTs, api keys and authorization credentials from s.
+func Redact(s string) string {
+	s = jwtPattern.ReplaceAllString(s, redacted)
+	s = apiKeyPattern.ReplaceAllString(s, redacted)
+	return bearerPattern.ReplaceAllString(s, "$1 "+redacted)
+}
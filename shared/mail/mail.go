@@ -0,0 +1,132 @@
+// Package mail sends plain text emails through an SMTP server. STARTTLS is used when the server offers it and
+// credentials are only sent over TLS or to localhost, so a local test server such as MailHog works without
+// either.
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	er "errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Sender sends messages, see SMTP.
+	Sender interface {
+		Send(ctx context.Context, msg Message) error
+	}
+
+	// Message is a plain text email.
+	Message struct {
+		To      []string
+		Subject string
+		Body    string
+	}
+
+	// SMTP sends messages through one server.
+	SMTP struct {
+		host     string
+		port     int
+		username string
+		password string
+		from     string
+		timeout  time.Duration
+	}
+)
+
+// NewSMTP returns a sender through host:port, authenticating when username is set, from the address from.
+// Each message is given timeout to be sent.
+func NewSMTP(host string, port int, username, password, from string, timeout time.Duration) *SMTP {
+	return &SMTP{host: host, port: port, username: username, password: password, from: from, timeout: timeout}
+}
+
+// Send sends msg, failing when ctx is done or the timeout of the sender elapsed.
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if len(msg.To) == 0 {
+		return er.New("mail: no recipients")
+	}
+	for _, header := range append([]string{msg.Subject}, msg.To...) {
+		if strings.ContainsAny(header, "\r\n") {
+			return er.New("mail: line break in a header")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(s.host, strconv.Itoa(s.port)))
+	if err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return err
+		}
+	}
+
+	if s.username != "" {
+		// PlainAuth refuses to send the password unencrypted to anything but localhost
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(s.from); err != nil {
+		return err
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(s.compose(msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// compose returns the headers and body of msg, with CRLF line endings.
+func (s *SMTP) compose(msg Message) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().UTC().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	b.WriteString("\r\n")
+
+	return []byte(b.String())
+}
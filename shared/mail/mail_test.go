@@ -0,0 +1,223 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpServer is an SMTP server of one connection at a time, offering neither STARTTLS nor, unless auth is
+// set, AUTH. It records the commands and the data it was sent.
+type smtpServer struct {
+	listener net.Listener
+	auth     bool
+	greet    bool
+
+	mu       sync.Mutex
+	commands []string
+	data     string
+}
+
+func newSMTPServer(t *testing.T, auth, greet bool) *smtpServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen() = %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	s := &smtpServer{listener: listener, auth: auth, greet: greet}
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.handle(conn)
+	}
+}
+
+func (s *smtpServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	if !s.greet {
+		// a server that never answers, read until the client gives up
+		_, _ = bufio.NewReader(conn).ReadString('\n')
+		return
+	}
+
+	r := bufio.NewReader(conn)
+	reply := func(lines ...string) { _, _ = conn.Write([]byte(strings.Join(lines, "\r\n") + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimRight(line, "\r\n")
+
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+
+		switch verb := strings.ToUpper(strings.Fields(command + " ")[0]); verb {
+		case "EHLO":
+			if s.auth {
+				reply("250-localhost", "250 AUTH PLAIN")
+			} else {
+				reply("250 localhost")
+			}
+		case "AUTH":
+			if !s.auth {
+				reply("502 5.5.1 Unrecognized command")
+				continue
+			}
+			reply("235 2.7.0 Authentication successful")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) received() ([]string, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.commands...), s.data
+}
+
+func TestSend(t *testing.T) {
+	server := newSMTPServer(t, false, true)
+	sender := NewSMTP("127.0.0.1", server.port(), "", "", "alerts@0xbase.io", time.Second)
+
+	err := sender.Send(context.Background(), Message{
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Alert: bitcoin above 100000",
+		Body:    "Bitcoin is at 100001 usd.\n.hidden line\r\nRule: bitcoin",
+	})
+	if err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	commands, data := server.received()
+	want := []string{"EHLO localhost", "MAIL FROM:<alerts@0xbase.io>", "RCPT TO:<a@example.com>", "RCPT TO:<b@example.com>", "DATA", "QUIT"}
+	if strings.Join(commands, "|") != strings.Join(want, "|") {
+		t.Errorf("commands = %q, want %q, without STARTTLS or AUTH", commands, want)
+	}
+
+	headers, body, ok := strings.Cut(data, "\r\n\r\n")
+	if !ok {
+		t.Fatalf("data = %q, want headers and a body", data)
+	}
+	for _, header := range []string{
+		"From: alerts@0xbase.io",
+		"To: a@example.com, b@example.com",
+		"Subject: Alert: bitcoin above 100000",
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=utf-8",
+	} {
+		if !strings.Contains("\r\n"+headers+"\r\n", "\r\n"+header+"\r\n") {
+			t.Errorf("headers = %q, want %q", headers, header)
+		}
+	}
+	if !strings.Contains(headers, "\r\nDate: ") {
+		t.Errorf("headers = %q, want a date", headers)
+	}
+	if want := "Bitcoin is at 100001 usd.\r\n.hidden line\r\nRule: bitcoin\r\n"; body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+}
+
+func TestSendAuthenticates(t *testing.T) {
+	server := newSMTPServer(t, true, true)
+	sender := NewSMTP("127.0.0.1", server.port(), "user", "pass", "alerts@0xbase.io", time.Second)
+
+	if err := sender.Send(context.Background(), Message{To: []string{"a@example.com"}, Subject: "s", Body: "b"}); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+
+	commands, _ := server.received()
+	want := "AUTH PLAIN " + base64.StdEncoding.EncodeToString([]byte("\x00user\x00pass"))
+	if len(commands) < 2 || commands[1] != want {
+		t.Errorf("commands = %q, want %q after EHLO", commands, want)
+	}
+}
+
+func TestSendFails(t *testing.T) {
+	tests := []struct {
+		name     string
+		auth     bool
+		greet    bool
+		username string
+		msg      Message
+		wantErr  string
+	}{
+		{name: "no recipients", greet: true, msg: Message{Subject: "s"}, wantErr: "no recipients"},
+		{name: "line break in the subject", greet: true, msg: Message{To: []string{"a@example.com"}, Subject: "s\r\nBcc: x@example.com"}, wantErr: "line break"},
+		{name: "line break in a recipient", greet: true, msg: Message{To: []string{"a@example.com\nBcc: x@example.com"}}, wantErr: "line break"},
+		{name: "credentials to a server without AUTH", greet: true, username: "user", msg: Message{To: []string{"a@example.com"}}, wantErr: "Unrecognized command"},
+		{name: "server that never answers", msg: Message{To: []string{"a@example.com"}}, wantErr: "timeout"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newSMTPServer(t, tt.auth, tt.greet)
+			sender := NewSMTP("127.0.0.1", server.port(), tt.username, "pass", "alerts@0xbase.io", 100*time.Millisecond)
+
+			err := sender.Send(context.Background(), tt.msg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Send() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompose(t *testing.T) {
+	sender := NewSMTP("localhost", 25, "", "", "alerts@0xbase.io", time.Second)
+	composed := string(sender.compose(Message{To: []string{"a@example.com"}, Subject: "s", Body: "one\ntwo\r\nthree\n"}))
+
+	if strings.Contains(strings.ReplaceAll(composed, "\r\n", ""), "\n") || strings.Contains(composed, "\r\r") {
+		t.Errorf("compose() = %q, want every line to end with CRLF", composed)
+	}
+	if !strings.HasSuffix(composed, "\r\n\r\none\r\ntwo\r\nthree\r\n\r\n") {
+		t.Errorf("compose() = %q, want the body after a blank line", composed)
+	}
+}
@@ -0,0 +1,223 @@
+// Package metrics defines the prometheus metrics of the service, served on /metrics.
+package metrics
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "portfolio"
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests, by method and route.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route"})
+
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "gRPC calls served, by full method and status code.",
+	}, []string{"method", "code"})
+
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Time to serve gRPC calls, by full method; streams until they end.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method"})
+
+	upstreamCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_calls_total",
+		Help:      "Requests sent to upstream providers, by provider and status code; transport failures have status error.",
+	}, []string{"provider", "status"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_call_duration_seconds",
+		Help:      "Time of single requests to upstream providers, retries counted separately.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"provider"})
+
+	priceCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "price_cache_lookups_total",
+		Help:      "Stored price lookups, by result: hit when the stored price was used, miss when it was fetched.",
+	}, []string{"result"})
+
+	responseCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Read-through cache lookups, by kind of cached value and result.",
+	}, []string{"kind", "result"})
+
+	walletSave = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "wallet_save_duration_seconds",
+		Help:      "Time of the transactions saving fetched wallets, by chain and outcome.",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"chain", "outcome"})
+
+	fanout = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "fanout_size",
+		Help:      "Addresses fetched concurrently by one request, by chain.",
+		Buckets:   []float64{1, 2, 5, 10, 20, 50},
+	}, []string{"chain"})
+
+	walletStaleness = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "wallet_staleness_seconds",
+		Help:      "Age of the wallet data served, since it was last refreshed from its provider, by chain.",
+		Buckets:   []float64{1, 60, 300, 900, 3600, 6 * 3600, 24 * 3600, 7 * 24 * 3600},
+	}, []string{"chain"})
+
+	eventsPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_published_total",
+		Help:      "Events published on the event bus, by type.",
+	}, []string{"type"})
+
+	eventsLagged = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "event_subscribers_lagged_total",
+		Help:      "Event subscribers dropped because they did not keep up with the events.",
+	})
+
+	websocketConnections = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "websocket_connections",
+		Help:      "Open websocket connections.",
+	})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Attempts to deliver a webhook, by event and result: succeeded, retried or failed.",
+	}, []string{"event", "result"})
+
+	alertsTriggered = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "alerts_triggered_total",
+		Help:      "Alerts triggered, by kind of rule and result: delivered when every channel succeeded, failed otherwise.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	prometheus.MustRegister(httpRequests, httpDuration, rpcRequests, rpcDuration, upstreamCalls, upstreamDuration, priceCache, responseCache, walletSave, fanout, walletStaleness,
+		eventsPublished, eventsLagged, websocketConnections, webhookDeliveries, alertsTriggered)
+}
+
+// RegisterDB exports the connection pool statistics of db.
+func RegisterDB(db *sql.DB) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// ObserveRequest records an HTTP request served. route is the route pattern, not the path, to bound the labels.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveRPC records a gRPC call served. method is the full method, of a registered service only.
+func ObserveRPC(method, code string, duration time.Duration) {
+	rpcRequests.WithLabelValues(method, code).Inc()
+	rpcDuration.WithLabelValues(method).Observe(duration.Seconds())
+}
+
+// ObserveUpstream records a single request to a provider. status is 0 when no response was received.
+func ObserveUpstream(provider string, status int, duration time.Duration) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+
+	upstreamCalls.WithLabelValues(provider, label).Inc()
+	upstreamDuration.WithLabelValues(provider).Observe(duration.Seconds())
+}
+
+// PriceCacheHit records a stored price used as is.
+func PriceCacheHit() {
+	priceCache.WithLabelValues("hit").Inc()
+}
+
+// PriceCacheMiss records a price fetched because none was stored or it was too old.
+func PriceCacheMiss() {
+	priceCache.WithLabelValues("miss").Inc()
+}
+
+// CacheHit records a value served from the read-through cache.
+func CacheHit(kind string) {
+	responseCache.WithLabelValues(kind, "hit").Inc()
+}
+
+// CacheMiss records a value loaded because it was not cached.
+func CacheMiss(kind string) {
+	responseCache.WithLabelValues(kind, "miss").Inc()
+}
+
+// ObserveWalletSave records the time of a wallet save transaction, committed or not.
+func ObserveWalletSave(chain string, duration time.Duration, err error) {
+	outcome := "committed"
+	if err != nil {
+		outcome = "failed"
+	}
+
+	walletSave.WithLabelValues(chain, outcome).Observe(duration.Seconds())
+}
+
+// ObserveFanout records the number of addresses of chain fetched concurrently for a request.
+func ObserveFanout(chain string, size int) {
+	if size > 0 {
+		fanout.WithLabelValues(chain).Observe(float64(size))
+	}
+}
+
+// ObserveWalletStaleness records the age of wallet data served to a client.
+func ObserveWalletStaleness(chain string, lastUpdatedAt time.Time) {
+	walletStaleness.WithLabelValues(chain).Observe(time.Since(lastUpdatedAt).Seconds())
+}
+
+// EventPublished records an event of type published on the event bus.
+func EventPublished(typ string) {
+	eventsPublished.WithLabelValues(typ).Inc()
+}
+
+// EventSubscriberLagged records a subscriber dropped for not keeping up with the events.
+func EventSubscriberLagged() {
+	eventsLagged.Inc()
+}
+
+// WebsocketOpened records a websocket connection opened, the returned func records it closed.
+func WebsocketOpened() func() {
+	websocketConnections.Inc()
+	return websocketConnections.Dec
+}
+
+// WebhookAttempted records an attempt to deliver a webhook event and its result: succeeded, retried or failed.
+func WebhookAttempted(event, result string) {
+	webhookDeliveries.WithLabelValues(event, result).Inc()
+}
+
+// AlertTriggered records an alert triggered by a rule of kind, and whether all its channels delivered it.
+func AlertTriggered(kind string, delivered bool) {
+	result := "delivered"
+	if !delivered {
+		result = "failed"
+	}
+
+	alertsTriggered.WithLabelValues(kind, result).Inc()
+}
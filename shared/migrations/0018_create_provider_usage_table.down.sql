@@ -0,0 +1,2 @@
+-- Drop provider_usage table
+DROP TABLE IF EXISTS provider_usage;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS provider_usage (
+    provider VARCHAR(50) NOT NULL,
+    usage_date DATE NOT NULL,
+    call_count BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (provider, usage_date)
+);
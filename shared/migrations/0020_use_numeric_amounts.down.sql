@@ -0,0 +1,72 @@
+-- Restore the float and varchar amount columns
+ALTER TABLE bitcoin_btc_com_v1
+    ALTER COLUMN btc_usd_price DROP NOT NULL,
+    ALTER COLUMN btc_usd_price DROP DEFAULT,
+    ALTER COLUMN btc_usd_price TYPE FLOAT;
+
+ALTER TABLE bitcoin_address_info
+    ALTER COLUMN received DROP NOT NULL,
+    ALTER COLUMN received DROP DEFAULT,
+    ALTER COLUMN received TYPE FLOAT,
+    ALTER COLUMN sent DROP NOT NULL,
+    ALTER COLUMN sent DROP DEFAULT,
+    ALTER COLUMN sent TYPE FLOAT,
+    ALTER COLUMN balance DROP NOT NULL,
+    ALTER COLUMN balance DROP DEFAULT,
+    ALTER COLUMN balance TYPE FLOAT,
+    ALTER COLUMN unconfirmed_received DROP NOT NULL,
+    ALTER COLUMN unconfirmed_received DROP DEFAULT,
+    ALTER COLUMN unconfirmed_received TYPE FLOAT,
+    ALTER COLUMN unconfirmed_sent DROP NOT NULL,
+    ALTER COLUMN unconfirmed_sent DROP DEFAULT,
+    ALTER COLUMN unconfirmed_sent TYPE FLOAT;
+
+ALTER TABLE evm_assets_debank_v1
+    ALTER COLUMN total_usd_value DROP NOT NULL,
+    ALTER COLUMN total_usd_value DROP DEFAULT,
+    ALTER COLUMN total_usd_value TYPE FLOAT;
+
+ALTER TABLE chain_details
+    ALTER COLUMN usd_value DROP NOT NULL,
+    ALTER COLUMN usd_value DROP DEFAULT,
+    ALTER COLUMN usd_value TYPE DECIMAL;
+
+ALTER TABLE token_list
+    ALTER COLUMN price DROP NOT NULL,
+    ALTER COLUMN price SET DEFAULT NULL,
+    ALTER COLUMN price TYPE DOUBLE PRECISION,
+    ALTER COLUMN price24h_change DROP NOT NULL,
+    ALTER COLUMN price24h_change SET DEFAULT NULL,
+    ALTER COLUMN price24h_change TYPE DOUBLE PRECISION,
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN amount SET DEFAULT NULL,
+    ALTER COLUMN amount TYPE DOUBLE PRECISION,
+    ALTER COLUMN raw_amount DROP NOT NULL,
+    ALTER COLUMN raw_amount DROP DEFAULT;
+
+ALTER TABLE nft_list
+    ALTER COLUMN usd_price TYPE DOUBLE PRECISION;
+
+ALTER TABLE coingecko_price_feed
+    ALTER COLUMN price TYPE FLOAT;
+
+ALTER TABLE solana_assets_moralis_v1
+    ALTER COLUMN lamports DROP NOT NULL,
+    ALTER COLUMN lamports DROP DEFAULT,
+    ALTER COLUMN lamports TYPE VARCHAR(255),
+    ALTER COLUMN solana DROP NOT NULL,
+    ALTER COLUMN solana DROP DEFAULT,
+    ALTER COLUMN solana TYPE VARCHAR(255);
+
+ALTER TABLE tokens
+    ALTER COLUMN amount_raw DROP NOT NULL,
+    ALTER COLUMN amount_raw DROP DEFAULT,
+    ALTER COLUMN amount_raw TYPE VARCHAR(255),
+    ALTER COLUMN amount DROP NOT NULL,
+    ALTER COLUMN amount DROP DEFAULT,
+    ALTER COLUMN amount TYPE VARCHAR(255);
+
+ALTER TABLE nfts
+    ALTER COLUMN amount_raw DROP NOT NULL,
+    ALTER COLUMN amount_raw DROP DEFAULT,
+    ALTER COLUMN amount_raw TYPE VARCHAR(255);
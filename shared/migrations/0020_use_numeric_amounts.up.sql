@@ -0,0 +1,74 @@
+-- Store balances and prices as exact NUMERIC values; NULLs become 0 so they scan into decimals
+ALTER TABLE bitcoin_btc_com_v1
+    ALTER COLUMN btc_usd_price TYPE NUMERIC USING COALESCE(btc_usd_price, 0)::NUMERIC,
+    ALTER COLUMN btc_usd_price SET DEFAULT 0,
+    ALTER COLUMN btc_usd_price SET NOT NULL;
+
+-- btc.com reports amounts in satoshis
+ALTER TABLE bitcoin_address_info
+    ALTER COLUMN received TYPE NUMERIC USING COALESCE(received, 0)::NUMERIC,
+    ALTER COLUMN received SET DEFAULT 0,
+    ALTER COLUMN received SET NOT NULL,
+    ALTER COLUMN sent TYPE NUMERIC USING COALESCE(sent, 0)::NUMERIC,
+    ALTER COLUMN sent SET DEFAULT 0,
+    ALTER COLUMN sent SET NOT NULL,
+    ALTER COLUMN balance TYPE NUMERIC USING COALESCE(balance, 0)::NUMERIC,
+    ALTER COLUMN balance SET DEFAULT 0,
+    ALTER COLUMN balance SET NOT NULL,
+    ALTER COLUMN unconfirmed_received TYPE NUMERIC USING COALESCE(unconfirmed_received, 0)::NUMERIC,
+    ALTER COLUMN unconfirmed_received SET DEFAULT 0,
+    ALTER COLUMN unconfirmed_received SET NOT NULL,
+    ALTER COLUMN unconfirmed_sent TYPE NUMERIC USING COALESCE(unconfirmed_sent, 0)::NUMERIC,
+    ALTER COLUMN unconfirmed_sent SET DEFAULT 0,
+    ALTER COLUMN unconfirmed_sent SET NOT NULL;
+
+ALTER TABLE evm_assets_debank_v1
+    ALTER COLUMN total_usd_value TYPE NUMERIC USING COALESCE(total_usd_value, 0)::NUMERIC,
+    ALTER COLUMN total_usd_value SET DEFAULT 0,
+    ALTER COLUMN total_usd_value SET NOT NULL;
+
+ALTER TABLE chain_details
+    ALTER COLUMN usd_value TYPE NUMERIC USING COALESCE(usd_value, 0)::NUMERIC,
+    ALTER COLUMN usd_value SET DEFAULT 0,
+    ALTER COLUMN usd_value SET NOT NULL;
+
+ALTER TABLE token_list
+    ALTER COLUMN price TYPE NUMERIC USING COALESCE(price, 0)::NUMERIC,
+    ALTER COLUMN price SET DEFAULT 0,
+    ALTER COLUMN price SET NOT NULL,
+    ALTER COLUMN price24h_change TYPE NUMERIC USING COALESCE(price24h_change, 0)::NUMERIC,
+    ALTER COLUMN price24h_change SET DEFAULT 0,
+    ALTER COLUMN price24h_change SET NOT NULL,
+    ALTER COLUMN amount TYPE NUMERIC USING COALESCE(amount, 0)::NUMERIC,
+    ALTER COLUMN amount SET DEFAULT 0,
+    ALTER COLUMN amount SET NOT NULL,
+    ALTER COLUMN raw_amount TYPE NUMERIC USING COALESCE(raw_amount, 0),
+    ALTER COLUMN raw_amount SET DEFAULT 0,
+    ALTER COLUMN raw_amount SET NOT NULL;
+
+ALTER TABLE nft_list
+    ALTER COLUMN usd_price TYPE NUMERIC USING usd_price::NUMERIC;
+
+ALTER TABLE coingecko_price_feed
+    ALTER COLUMN price TYPE NUMERIC USING price::NUMERIC;
+
+ALTER TABLE solana_assets_moralis_v1
+    ALTER COLUMN lamports TYPE NUMERIC USING COALESCE(NULLIF(lamports, ''), '0')::NUMERIC,
+    ALTER COLUMN lamports SET DEFAULT 0,
+    ALTER COLUMN lamports SET NOT NULL,
+    ALTER COLUMN solana TYPE NUMERIC USING COALESCE(NULLIF(solana, ''), '0')::NUMERIC,
+    ALTER COLUMN solana SET DEFAULT 0,
+    ALTER COLUMN solana SET NOT NULL;
+
+ALTER TABLE tokens
+    ALTER COLUMN amount_raw TYPE NUMERIC USING COALESCE(NULLIF(amount_raw, ''), '0')::NUMERIC,
+    ALTER COLUMN amount_raw SET DEFAULT 0,
+    ALTER COLUMN amount_raw SET NOT NULL,
+    ALTER COLUMN amount TYPE NUMERIC USING COALESCE(NULLIF(amount, ''), '0')::NUMERIC,
+    ALTER COLUMN amount SET DEFAULT 0,
+    ALTER COLUMN amount SET NOT NULL;
+
+ALTER TABLE nfts
+    ALTER COLUMN amount_raw TYPE NUMERIC USING COALESCE(NULLIF(amount_raw, ''), '0')::NUMERIC,
+    ALTER COLUMN amount_raw SET DEFAULT 0,
+    ALTER COLUMN amount_raw SET NOT NULL;
@@ -0,0 +1,7 @@
+ALTER TABLE nft_list
+    ALTER COLUMN usd_price DROP NOT NULL,
+    ALTER COLUMN usd_price DROP DEFAULT;
+
+ALTER TABLE coingecko_price_feed
+    ALTER COLUMN price DROP NOT NULL,
+    ALTER COLUMN price DROP DEFAULT;
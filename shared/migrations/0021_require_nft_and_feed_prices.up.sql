@@ -0,0 +1,12 @@
+-- 0020 left these prices nullable, but they scan into decimals: NULLs become 0 like the other amounts
+UPDATE nft_list SET usd_price = 0 WHERE usd_price IS NULL;
+
+ALTER TABLE nft_list
+    ALTER COLUMN usd_price SET DEFAULT 0,
+    ALTER COLUMN usd_price SET NOT NULL;
+
+UPDATE coingecko_price_feed SET price = 0 WHERE price IS NULL;
+
+ALTER TABLE coingecko_price_feed
+    ALTER COLUMN price SET DEFAULT 0,
+    ALTER COLUMN price SET NOT NULL;
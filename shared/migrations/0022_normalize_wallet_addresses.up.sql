@@ -0,0 +1,14 @@
+-- Trim stored addresses and lowercase EVM ones. Where several stored addresses normalize to the same
+-- value, only the oldest wallet is renamed so the unique constraint holds.
+WITH normalized AS (
+    SELECT wallet_id,
+           CASE WHEN blockchain_type = 'debank' THEN LOWER(TRIM(wallet_address)) ELSE TRIM(wallet_address) END AS address
+    FROM global_wallets
+)
+UPDATE global_wallets g
+SET wallet_address = n.address
+FROM normalized n
+WHERE g.wallet_id = n.wallet_id
+  AND g.wallet_address <> n.address
+  AND NOT EXISTS (SELECT 1 FROM global_wallets o WHERE o.wallet_address = n.address)
+  AND g.wallet_id = (SELECT MIN(d.wallet_id) FROM normalized d WHERE d.address = n.address);
@@ -0,0 +1,2 @@
+-- Normalized wallet addresses cannot be restored to their original form
+SELECT 1;
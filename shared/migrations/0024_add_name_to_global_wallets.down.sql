@@ -0,0 +1,2 @@
+-- Drop name column from global_wallets
+ALTER TABLE global_wallets DROP COLUMN IF EXISTS name;
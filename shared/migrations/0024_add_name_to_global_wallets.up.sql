@@ -0,0 +1,2 @@
+-- Add the ENS or SNS name of the wallet
+ALTER TABLE global_wallets ADD COLUMN IF NOT EXISTS name VARCHAR(255);
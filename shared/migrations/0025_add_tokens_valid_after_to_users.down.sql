@@ -0,0 +1,2 @@
+-- Drop tokens_valid_after column from users
+ALTER TABLE users DROP COLUMN IF EXISTS tokens_valid_after;
@@ -0,0 +1,2 @@
+-- Tokens issued before this time are rejected
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Drop coingecko_price_history table
+DROP TABLE IF EXISTS coingecko_price_history;
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS coingecko_price_history (
+  id SERIAL PRIMARY KEY,
+  name VARCHAR(255) NOT NULL,
+  currency VARCHAR(50) NOT NULL,
+  price NUMERIC NOT NULL DEFAULT 0,
+  priced_at TIMESTAMP NOT NULL,
+  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+  UNIQUE (name, currency, priced_at)
+);
@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    api_key_id INTEGER, -- set when created with an api key, which then owns the webhook
+    url TEXT NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    events TEXT NOT NULL DEFAULT '', -- comma separated
+    wallets TEXT NOT NULL DEFAULT '', -- comma separated chain:address
+    value_threshold NUMERIC, -- usd, for portfolio.value_threshold
+    last_value NUMERIC, -- usd value of the wallets when last evaluated against the threshold
+    disabled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (api_key_id) REFERENCES api_keys(api_key_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_user_id ON webhooks(user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id SERIAL PRIMARY KEY,
+    webhook_id INTEGER NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    event_id BIGINT NOT NULL DEFAULT 0, -- id of the bus event it was made from, 0 for test deliveries
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending', -- pending, succeeded or failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(webhook_id) ON DELETE CASCADE
+);
+
+-- Add an index for the delivery log of a webhook and one for the deliveries due
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,4 @@
+-- Drop alert_history, alert_snapshots and alert_rules tables
+DROP TABLE IF EXISTS alert_history;
+DROP TABLE IF EXISTS alert_snapshots;
+DROP TABLE IF EXISTS alert_rules;
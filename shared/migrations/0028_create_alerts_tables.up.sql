@@ -0,0 +1,46 @@
+CREATE TABLE IF NOT EXISTS alert_rules (
+    rule_id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    kind VARCHAR(32) NOT NULL, -- price, portfolio_change or allocation
+    asset VARCHAR(255) NOT NULL DEFAULT '', -- coingecko id for price, chain for allocation
+    direction VARCHAR(8) NOT NULL, -- above or below
+    threshold NUMERIC NOT NULL, -- usd for price, percent otherwise
+    window_seconds INTEGER NOT NULL DEFAULT 0, -- for portfolio_change
+    wallets TEXT NOT NULL DEFAULT '', -- comma separated chain:address
+    channels TEXT NOT NULL DEFAULT '', -- comma separated
+    webhook_id INTEGER,
+    email VARCHAR(255) NOT NULL DEFAULT '',
+    cooldown_seconds INTEGER NOT NULL DEFAULT 0,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_triggered_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(user_id) ON DELETE CASCADE,
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(webhook_id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_user_id ON alert_rules(user_id);
+
+CREATE TABLE IF NOT EXISTS alert_snapshots (
+    snapshot_id SERIAL PRIMARY KEY,
+    rule_id INTEGER NOT NULL,
+    value NUMERIC NOT NULL,
+    taken_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (rule_id) REFERENCES alert_rules(rule_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_snapshots_rule_id ON alert_snapshots(rule_id, taken_at);
+
+CREATE TABLE IF NOT EXISTS alert_history (
+    history_id SERIAL PRIMARY KEY,
+    rule_id INTEGER NOT NULL,
+    value NUMERIC NOT NULL DEFAULT 0,
+    message TEXT NOT NULL DEFAULT '',
+    delivered TEXT NOT NULL DEFAULT '', -- comma separated channels
+    error TEXT NOT NULL DEFAULT '',
+    triggered_at TIMESTAMP NOT NULL,
+    FOREIGN KEY (rule_id) REFERENCES alert_rules(rule_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_history_rule_id ON alert_history(rule_id, triggered_at);
@@ -0,0 +1,2 @@
+-- Whether the condition of a rule held when it was last evaluated: rules only trigger when it starts holding
+ALTER TABLE alert_rules ADD COLUMN IF NOT EXISTS triggered BOOLEAN NOT NULL DEFAULT FALSE;
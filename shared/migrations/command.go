@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Usage lists the commands understood by RunCommand.
+const Usage = `commands:
+  up          apply every pending migration
+  down [N]    revert the last N applied migrations (default 1)
+  status      list migrations and whether they are applied
+  goto V      migrate up or down to version V, 0 reverts everything`
+
+// RunCommand runs a migrate command line such as "down 2", printing the status to w. Both the server and
+// the admin CLI expose it as their migrate subcommand.
+func RunCommand(db *gorm.DB, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", Usage)
+	}
+
+	switch args[0] {
+	case "up":
+		return Up(db)
+
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n < 1 {
+				return fmt.Errorf("down expects a positive number of migrations, got %q", args[1])
+			}
+		}
+		return Down(db, n)
+
+	case "goto":
+		if len(args) < 2 {
+			return fmt.Errorf("goto expects a version\n%s", Usage)
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil || version < 0 {
+			return fmt.Errorf("goto expects a version, got %q", args[1])
+		}
+		return Goto(db, version)
+
+	case "status":
+		statuses, err := GetStatus(db)
+		if err != nil {
+			return err
+		}
+		return printStatus(w, statuses)
+	}
+
+	return fmt.Errorf("unknown command %q\n%s", args[0], Usage)
+}
+
+func printStatus(out io.Writer, statuses []Status) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tNOTE")
+
+	for _, s := range statuses {
+		appliedAt, note := "pending", ""
+		if s.Applied {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+		if s.Modified {
+			note = "modified after it was applied"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, appliedAt, note)
+	}
+
+	return w.Flush()
+}
@@ -1,89 +1,391 @@
 package migrations
 
 import (
-	"log"
-	"os"
-	"path/filepath"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
-// Migrate applies the migration files to the database in the correct order.
+type (
+	// Migration is one versioned schema change read from a NNNN_name.up.sql / NNNN_name.down.sql pair.
+	Migration struct {
+		Version  int
+		Name     string
+		Up       string
+		Down     string
+		Checksum string // sha256 of the up script
+	}
+
+	// Status describes a migration and whether it has been applied.
+	Status struct {
+		Version   int
+		Name      string
+		Applied   bool
+		AppliedAt *time.Time
+		Modified  bool // the up script changed after it was applied
+	}
+
+	// applied is a row of the schema_migrations table.
+	applied struct {
+		Version   int       `gorm:"primaryKey"`
+		Name      string    `gorm:"type:varchar(255)"`
+		Checksum  string    `gorm:"type:varchar(64)"`
+		AppliedAt time.Time `gorm:"default:CURRENT_TIMESTAMP"`
+	}
+)
+
+func (applied) TableName() string {
+	return "schema_migrations"
+}
+
+//go:embed *.sql
+var files embed.FS
+
+const createTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    checksum VARCHAR(64) NOT NULL,
+    applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`
+
+// lockID is the postgres advisory lock key held while migrating, so replicas starting together migrate once.
+const lockID = 7283940517
+
+// Migrate applies every pending migration. It is kept for the server start up.
 func Migrate(db *gorm.DB) error {
-	migrationsDir := "./"
+	return Up(db)
+}
 
-	// Store all migration files in a slice
-	var migrationFiles []string
+// Load reads the embedded migrations ordered by version.
+func Load() ([]Migration, error) {
+	return load(files)
+}
 
-	// Recursive function to collect all .up.sql files
-	var collectMigrationFiles func(string) error
-	collectMigrationFiles = func(path string) error {
-		entries, err := os.ReadDir(path)
+// load reads the migrations of fsys ordered by version.
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		base, direction, ok := strings.Cut(strings.TrimSuffix(fileName, ".sql"), ".")
+		if !ok || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.up.sql or NNNN_name.down.sql", fileName)
+		}
+
+		prefix, name, _ := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
 		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", fileName, err)
+		}
+
+		content, err := fs.ReadFile(fsys, fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d: conflicting names %s and %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+			sum := sha256.Sum256(content)
+			m.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order.
+func Up(db *gorm.DB) error {
+	return Goto(db, -1)
+}
+
+// Down reverts the last n applied migrations.
+func Down(db *gorm.DB, n int) error {
+	return withLock(db, func(conn *gorm.DB, migrations []Migration, done map[int]applied) error {
+		if err := checkModified(migrations, done); err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && n > 0; i-- {
+			if _, ok := done[migrations[i].Version]; !ok {
+				continue
+			}
+
+			if err := revert(conn, migrations[i]); err != nil {
+				return err
+			}
+			n--
+		}
+
+		return nil
+	})
+}
+
+// Goto migrates up or down so that exactly the migrations up to version are applied. A negative version
+// applies every migration.
+func Goto(db *gorm.DB, version int) error {
+	return withLock(db, func(conn *gorm.DB, migrations []Migration, done map[int]applied) error {
+		if version > 0 && !known(migrations, version) {
+			return fmt.Errorf("unknown migration version %d", version)
+		}
+
+		if err := checkModified(migrations, done); err != nil {
 			return err
 		}
 
-		for _, entry := range entries {
-			fullPath := filepath.Join(path, entry.Name())
-			if entry.IsDir() {
-				err := collectMigrationFiles(fullPath)
-				if err != nil {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; ok && version >= 0 && m.Version > version {
+				if err := revert(conn, m); err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; !ok && (version < 0 || m.Version <= version) {
+				if err := apply(conn, m); err != nil {
 					return err
 				}
-			} else if strings.HasSuffix(entry.Name(), ".up.sql") {
-				migrationFiles = append(migrationFiles, fullPath)
 			}
 		}
+
+		return nil
+	})
+}
+
+// GetStatus lists every migration with whether and when it was applied.
+func GetStatus(db *gorm.DB) ([]Status, error) {
+	var statuses []Status
+
+	err := withLock(db, func(conn *gorm.DB, migrations []Migration, done map[int]applied) error {
+		for _, m := range migrations {
+			status := Status{Version: m.Version, Name: m.Name}
+			if row, ok := done[m.Version]; ok {
+				appliedAt := row.AppliedAt
+				status.Applied = true
+				status.AppliedAt = &appliedAt
+				status.Modified = row.Checksum != m.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+
 		return nil
+	})
+
+	return statuses, err
+}
+
+// CheckVersion returns the latest applied and the latest embedded migration versions, and an error unless
+// every embedded migration is applied unmodified. It does not take the migration lock, so readiness probes
+// can call it while another replica migrates.
+func CheckVersion(db *gorm.DB) (current, expected int, err error) {
+	migrations, err := Load()
+	if err != nil {
+		return 0, 0, err
 	}
+	expected = migrations[len(migrations)-1].Version
 
-	// Start the recursive file collection
-	err := collectMigrationFiles(migrationsDir)
+	if !db.Migrator().HasTable(&applied{}) {
+		return 0, expected, fmt.Errorf("no migrations applied")
+	}
+
+	rows := make([]applied, 0)
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return 0, expected, err
+	}
+
+	done := make(map[int]applied, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+		current = row.Version
+	}
+
+	if err := checkModified(migrations, done); err != nil {
+		return current, expected, err
+	}
+
+	pending := 0
+	for _, m := range migrations {
+		if _, ok := done[m.Version]; !ok {
+			pending++
+		}
+	}
+	if pending > 0 {
+		return current, expected, fmt.Errorf("%d migrations pending", pending)
+	}
+
+	return current, expected, nil
+}
+
+// withLock runs fn on a single connection holding the advisory lock, with the embedded migrations and the
+// applied ones by version.
+func withLock(db *gorm.DB, fn func(conn *gorm.DB, migrations []Migration, done map[int]applied) error) error {
+	migrations, err := Load()
 	if err != nil {
 		return err
 	}
 
-	// Sort the migration files by their sequence number
-	sort.Slice(migrationFiles, func(i, j int) bool {
-		seqNumI, _ := strconv.Atoi(strings.Split(filepath.Base(migrationFiles[i]), "_")[0])
-		seqNumJ, _ := strconv.Atoi(strings.Split(filepath.Base(migrationFiles[j]), "_")[0])
-		return seqNumI < seqNumJ
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("SELECT pg_advisory_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("SELECT pg_advisory_unlock(?)", lockID)
+
+		if err := conn.Exec(createTable).Error; err != nil {
+			return err
+		}
+
+		if err := importLegacy(conn, migrations); err != nil {
+			return err
+		}
+
+		rows := make([]applied, 0)
+		if err := conn.Order("version").Find(&rows).Error; err != nil {
+			return err
+		}
+
+		done := make(map[int]applied, len(rows))
+		for _, row := range rows {
+			done[row.Version] = row
+		}
+
+		return fn(conn, migrations, done)
 	})
+}
 
-	// Create a table to track which migrations have been run
-	err = db.Exec("CREATE TABLE IF NOT EXISTS migrations (name VARCHAR PRIMARY KEY)").Error
-	if err != nil {
+// importLegacy records the migrations applied by the previous runner, which tracked up script file names
+// in the migrations table, so they are not run again.
+func importLegacy(conn *gorm.DB, migrations []Migration) error {
+	if !conn.Migrator().HasTable("migrations") {
+		return nil
+	}
+
+	var count int64
+	if err := conn.Model(&applied{}).Count(&count).Error; err != nil || count > 0 {
 		return err
 	}
 
-	// Execute each migration file in sorted order
-	for _, file := range migrationFiles {
-		fileName := filepath.Base(file)
-		log.Printf("Checking migration file: %s", fileName)
-		var count int64
-		db.Table("migrations").Where("name = ?", fileName).Count(&count)
-		if count > 0 {
-			log.Printf("Migration %s has already been applied, skipping", fileName)
+	legacy := make([]string, 0)
+	if err := conn.Table("migrations").Pluck("name", &legacy).Error; err != nil {
+		return err
+	}
+
+	ran := make(map[string]bool, len(legacy))
+	for _, name := range legacy {
+		ran[name] = true
+	}
+
+	for _, m := range migrations {
+		if !ran[fmt.Sprintf("%04d_%s.up.sql", m.Version, m.Name)] {
 			continue
 		}
-		content, err := os.ReadFile(file)
-		if err != nil {
+
+		if err := conn.Create(&applied{Version: m.Version, Name: m.Name, Checksum: m.Checksum}).Error; err != nil {
 			return err
 		}
-		tx := db.Begin()
-		if err := tx.Exec(string(content)).Error; err != nil {
-			tx.Rollback()
+		slog.Info(fmt.Sprintf("Imported migration %04d_%s from the legacy migrations table", m.Version, m.Name))
+	}
+
+	return nil
+}
+
+func apply(conn *gorm.DB, m Migration) error {
+	slog.Info(fmt.Sprintf("Applying migration %04d_%s", m.Version, m.Name))
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(m.Up).Error; err != nil {
 			return err
 		}
-		if err := tx.Exec("INSERT INTO migrations (name) VALUES (?)", fileName).Error; err != nil {
-			tx.Rollback()
+
+		return tx.Create(&applied{Version: m.Version, Name: m.Name, Checksum: m.Checksum}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
+
+func revert(conn *gorm.DB, m Migration) error {
+	if m.Down == "" {
+		return fmt.Errorf("migration %04d_%s has no down script", m.Version, m.Name)
+	}
+
+	slog.Info(fmt.Sprintf("Reverting migration %04d_%s", m.Version, m.Name))
+
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(m.Down).Error; err != nil {
 			return err
 		}
-		tx.Commit()
+
+		return tx.Delete(&applied{}, m.Version).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+	}
+
+	return nil
+}
+
+// checkModified refuses to migrate when an applied up script was edited, since the schema no longer
+// matches the embedded migrations.
+func checkModified(migrations []Migration, done map[int]applied) error {
+	modified := make([]string, 0)
+	for _, m := range migrations {
+		if row, ok := done[m.Version]; ok && row.Checksum != m.Checksum {
+			modified = append(modified, fmt.Sprintf("%04d_%s", m.Version, m.Name))
+		}
+	}
+
+	if len(modified) > 0 {
+		return fmt.Errorf("applied migrations were modified: %s", strings.Join(modified, ", "))
 	}
 
 	return nil
 }
+
+func known(migrations []Migration, version int) bool {
+	for _, m := range migrations {
+		if m.Version == version {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadEmbedded(t *testing.T) {
+	migrations, err := Load()
+	if err != nil {
+		t.Fatalf("Load() = %v", err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Load() found no migrations")
+	}
+
+	for i, m := range migrations {
+		name := fmt.Sprintf("%04d_%s", m.Version, m.Name)
+
+		// versions follow each other, so two branches adding the same version collide here
+		if m.Version != i+1 {
+			t.Errorf("%s: version %d at position %d, want %d", name, m.Version, i, i+1)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("%s: every migration needs an up and a down script", name)
+		}
+
+		up, err := files.ReadFile(name + ".up.sql")
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if sum := sha256.Sum256(up); m.Checksum != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s: checksum %s is not the sha256 of its up script", name, m.Checksum)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(content string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(content)} }
+
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		versions []int
+		wantErr  string
+	}{
+		{
+			name: "ordered by version, not by name",
+			files: fstest.MapFS{
+				"0010_c.up.sql":   file("c"),
+				"0002_b.up.sql":   file("b"),
+				"0002_b.down.sql": file("-b"),
+				"0001_a.up.sql":   file("a"),
+			},
+			versions: []int{1, 2, 10},
+		},
+		{
+			name:    "down without up",
+			files:   fstest.MapFS{"0001_a.down.sql": file("-a")},
+			wantErr: "has no up script",
+		},
+		{
+			name:    "conflicting names",
+			files:   fstest.MapFS{"0001_a.up.sql": file("a"), "0001_b.down.sql": file("-b")},
+			wantErr: "conflicting names",
+		},
+		{
+			name:    "no direction",
+			files:   fstest.MapFS{"0001_a.sql": file("a")},
+			wantErr: "expected NNNN_name.up.sql",
+		},
+		{
+			name:    "no version",
+			files:   fstest.MapFS{"first_a.up.sql": file("a")},
+			wantErr: "invalid version",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := load(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("load() = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load() = %v", err)
+			}
+
+			versions := make([]int, 0, len(migrations))
+			for _, m := range migrations {
+				versions = append(versions, m.Version)
+			}
+			if fmt.Sprint(versions) != fmt.Sprint(tt.versions) {
+				t.Errorf("versions = %v, want %v", versions, tt.versions)
+			}
+		})
+	}
+}
+
+func TestCheckModified(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "a", Checksum: "aaa"},
+		{Version: 2, Name: "b", Checksum: "bbb"},
+	}
+
+	tests := []struct {
+		name    string
+		done    map[int]applied
+		wantErr string
+	}{
+		{name: "nothing applied", done: map[int]applied{}},
+		{name: "applied unchanged", done: map[int]applied{1: {Version: 1, Checksum: "aaa"}, 2: {Version: 2, Checksum: "bbb"}}},
+		{name: "pending migrations are not checked", done: map[int]applied{1: {Version: 1, Checksum: "aaa"}}},
+		{name: "edited after applied", done: map[int]applied{1: {Version: 1, Checksum: "aaa"}, 2: {Version: 2, Checksum: "old"}}, wantErr: "0002_b"},
+		{name: "applied but no longer embedded", done: map[int]applied{3: {Version: 3, Checksum: "ccc"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkModified(migrations, tt.done)
+			if (err != nil) != (tt.wantErr != "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkModified() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+type (
+	// Limits is the client-side budget for a single upstream provider.
+	Limits struct {
+		RatePerSecond float64
+		Burst         int
+		DailyQuota    int64 // 0 means no daily quota
+	}
+
+	// UsageStore persists the number of calls made to a provider per day so quotas survive restarts and are
+	// shared by replicas. AddUsage adds calls to the count of the day and returns the new count.
+	UsageStore interface {
+		AddUsage(provider string, day time.Time, calls int64) (int64, error)
+		GetUsage(provider string, day time.Time) (int64, error)
+	}
+
+	// Usage reports the remaining budget of a provider for the current day.
+	Usage struct {
+		Provider      string  `json:"provider"`
+		Date          string  `json:"date"`
+		Used          int64   `json:"used"`
+		DailyQuota    int64   `json:"daily_quota"`
+		Remaining     int64   `json:"remaining"`
+		RatePerSecond float64 `json:"rate_per_second"`
+		Burst         int     `json:"burst"`
+		NearBudget    bool    `json:"near_budget"`
+	}
+
+	providerLimiter struct {
+		provider string
+		limits   Limits
+		bucket   *rate.Limiter
+
+		mu       sync.Mutex
+		day      time.Time
+		used     int64
+		reserved int64 // calls admitted by Wait that were neither recorded nor released yet
+		pending  int64 // calls of day not persisted yet
+		flushing bool
+	}
+)
+
+// ErrQuotaExceeded is returned by Wait once the daily quota of a provider is spent.
+var ErrQuotaExceeded = errors.New("daily quota exceeded")
+
+var (
+	mu       sync.RWMutex
+	limiters = make(map[string]*providerLimiter)
+	store    UsageStore
+
+	// nearBudgetRatio is the share of the daily quota after which callers should prefer stored data.
+	nearBudgetRatio = 0.9
+)
+
+// Configure registers the limits for a provider. It is shared by every goroutine calling that provider.
+func Configure(provider string, limits Limits) {
+	burst := limits.Burst
+	if burst < 1 {
+		burst = 1
+	}
+
+	every := rate.Inf
+	if limits.RatePerSecond > 0 {
+		every = rate.Limit(limits.RatePerSecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	limiters[provider] = &providerLimiter{
+		provider: provider,
+		limits:   limits,
+		bucket:   rate.NewLimiter(every, burst),
+	}
+}
+
+// SetUsageStore sets where daily usage is persisted. Without a store usage is only kept in memory.
+func SetUsageStore(s UsageStore) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	store = s
+}
+
+// SetNearBudgetRatio sets the share of the daily quota (0-1) after which NearBudget reports true.
+func SetNearBudgetRatio(ratio float64) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ratio > 0 && ratio <= 1 {
+		nearBudgetRatio = ratio
+	}
+}
+
+// Wait blocks until the provider's token bucket allows another call.
+// It fails fast with ErrQuotaExceeded when the daily quota is already spent, and stops waiting when ctx is done.
+// An admitted call is reserved against the quota, so concurrent callers cannot overshoot it; the caller
+// must then either Record the call or Release it when the provider was never reached.
+func Wait(ctx context.Context, provider string) error {
+	l := get(provider)
+	if l == nil {
+		return nil
+	}
+
+	l.rollover()
+
+	l.mu.Lock()
+	if l.limits.DailyQuota > 0 && l.used+l.reserved >= l.limits.DailyQuota {
+		l.mu.Unlock()
+		return ErrQuotaExceeded
+	}
+	l.reserved++
+	l.mu.Unlock()
+
+	if err := l.bucket.Wait(ctx); err != nil {
+		l.release()
+		return err
+	}
+
+	return nil
+}
+
+// Release gives back the call reserved by Wait when it was not made or never reached the provider.
+func Release(provider string) {
+	if l := get(provider); l != nil {
+		l.release()
+	}
+}
+
+// Record counts one billable call against the provider's daily usage, in place of the call reserved by Wait.
+// The count is persisted in the background, calls never wait on the store.
+func Record(provider string) {
+	l := get(provider)
+	if l == nil {
+		return
+	}
+
+	s := usageStore()
+
+	l.rollover()
+
+	l.mu.Lock()
+	if l.reserved > 0 {
+		l.reserved--
+	}
+	l.used++
+	l.pending++
+	start := s != nil && !l.flushing
+	if start {
+		l.flushing = true
+	}
+	l.mu.Unlock()
+
+	if start {
+		go l.flush(s)
+	}
+}
+
+// Flush persists the calls recorded since the last flush, for shutdown.
+func Flush() error {
+	s := usageStore()
+	if s == nil {
+		return nil
+	}
+
+	mu.RLock()
+	all := make([]*providerLimiter, 0, len(limiters))
+	for _, l := range limiters {
+		all = append(all, l)
+	}
+	mu.RUnlock()
+
+	problems := make([]error, 0)
+	for _, l := range all {
+		l.mu.Lock()
+		day, calls := l.day, l.pending
+		l.pending = 0
+		l.mu.Unlock()
+
+		if calls == 0 {
+			continue
+		}
+		if _, err := s.AddUsage(l.provider, day, calls); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+// NearBudget reports whether the provider has used most of its daily quota,
+// in which case callers should serve stored data instead of calling it.
+func NearBudget(provider string) bool {
+	l := get(provider)
+	if l == nil || l.limits.DailyQuota <= 0 {
+		return false
+	}
+
+	return float64(l.currentUsage()) >= float64(l.limits.DailyQuota)*ratio()
+}
+
+// Snapshot returns the usage of every configured provider for today, sorted by provider.
+func Snapshot() []Usage {
+	mu.RLock()
+	providers := make([]string, 0, len(limiters))
+	for provider := range limiters {
+		providers = append(providers, provider)
+	}
+	mu.RUnlock()
+
+	sort.Strings(providers)
+
+	usages := make([]Usage, 0, len(providers))
+	for _, provider := range providers {
+		l := get(provider)
+		used := l.currentUsage()
+
+		usage := Usage{
+			Provider:      provider,
+			Date:          today().Format(time.DateOnly),
+			Used:          used,
+			DailyQuota:    l.limits.DailyQuota,
+			Remaining:     -1,
+			RatePerSecond: l.limits.RatePerSecond,
+			Burst:         l.bucket.Burst(),
+			NearBudget:    NearBudget(provider),
+		}
+
+		if l.limits.DailyQuota > 0 {
+			usage.Remaining = l.limits.DailyQuota - used
+			if usage.Remaining < 0 {
+				usage.Remaining = 0
+			}
+		}
+
+		usages = append(usages, usage)
+	}
+
+	return usages
+}
+
+// flush persists the pending calls until none are left, without holding l.mu during the writes. The count
+// the store returns includes the calls of other replicas.
+func (l *providerLimiter) flush(s UsageStore) {
+	for {
+		l.mu.Lock()
+		day, calls := l.day, l.pending
+		l.pending = 0
+		if calls == 0 {
+			l.flushing = false
+			l.mu.Unlock()
+			return
+		}
+		l.mu.Unlock()
+
+		used, err := s.AddUsage(l.provider, day, calls)
+
+		l.mu.Lock()
+		if err != nil {
+			// kept for the flush started by the next call
+			if l.day.Equal(day) {
+				l.pending += calls
+			}
+			l.flushing = false
+			l.mu.Unlock()
+			return
+		}
+		if l.day.Equal(day) && used+l.pending > l.used {
+			l.used = used + l.pending
+		}
+		l.mu.Unlock()
+	}
+}
+
+func (l *providerLimiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.reserved > 0 {
+		l.reserved--
+	}
+}
+
+func (l *providerLimiter) currentUsage() int64 {
+	l.rollover()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.used
+}
+
+// rollover resets the counter on a new day, starting from the persisted value if there is one. The
+// persisted value is loaded without holding l.mu, so calls do not wait on the store; the caller must not
+// hold l.mu.
+func (l *providerLimiter) rollover() {
+	day := today()
+
+	l.mu.Lock()
+	current := l.day.Equal(day)
+	l.mu.Unlock()
+	if current {
+		return
+	}
+
+	var used int64
+	s := usageStore()
+	if s != nil {
+		if stored, err := s.GetUsage(l.provider, day); err == nil {
+			used = stored
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// another call rolled over while the usage was loaded
+	if l.day.Equal(day) {
+		return
+	}
+
+	// the calls of the previous day not persisted yet still count for it
+	if s != nil && l.pending > 0 {
+		previous, calls := l.day, l.pending
+		go func() { _, _ = s.AddUsage(l.provider, previous, calls) }()
+	}
+
+	l.day = day
+	l.used = used
+	l.pending = 0
+}
+
+func get(provider string) *providerLimiter {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return limiters[provider]
+}
+
+func usageStore() UsageStore {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return store
+}
+
+func ratio() float64 {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return nearBudgetRatio
+}
+
+// today returns the start of the current UTC day, which is when provider quotas reset.
+func today() time.Time {
+	return time.Now().UTC().Truncate(24 * time.Hour)
+}
@@ -0,0 +1,142 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// lockCheckingStore fails the test when the usage is loaded while the limiter lock is held.
+type lockCheckingStore struct {
+	t       *testing.T
+	limiter func() *providerLimiter
+	used    int64
+}
+
+func (s *lockCheckingStore) AddUsage(provider string, day time.Time, calls int64) (int64, error) {
+	return s.used + calls, nil
+}
+
+func (s *lockCheckingStore) GetUsage(provider string, day time.Time) (int64, error) {
+	l := s.limiter()
+	if !l.mu.TryLock() {
+		s.t.Error("GetUsage() called under the limiter lock")
+		return s.used, nil
+	}
+	l.mu.Unlock()
+
+	return s.used, nil
+}
+
+func setStore(t *testing.T, s UsageStore) {
+	t.Helper()
+
+	previous := usageStore()
+	SetUsageStore(s)
+	t.Cleanup(func() { SetUsageStore(previous) })
+}
+
+func TestWaitReservesTheQuota(t *testing.T) {
+	setStore(t, nil)
+	Configure("test-reserve", Limits{DailyQuota: 5})
+
+	var admitted atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 60; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := Wait(context.Background(), "test-reserve"); err == nil {
+				admitted.Add(1)
+			} else if !errors.Is(err, ErrQuotaExceeded) {
+				t.Errorf("Wait() = %v, want ErrQuotaExceeded", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := admitted.Load(); got != 5 {
+		t.Errorf("admitted %d concurrent calls, want the daily quota of 5", got)
+	}
+}
+
+func TestReleaseAndRecord(t *testing.T) {
+	setStore(t, nil)
+	Configure("test-release", Limits{DailyQuota: 2})
+
+	steps := []struct {
+		name string
+		do   func()
+		want error
+	}{
+		{"first call", nil, nil},
+		{"second call", nil, nil},
+		{"quota reserved", nil, ErrQuotaExceeded},
+		{"released call is given back", func() { Release("test-release") }, nil},
+		{"recorded calls keep counting", func() { Record("test-release"); Record("test-release") }, ErrQuotaExceeded},
+	}
+
+	for _, step := range steps {
+		if step.do != nil {
+			step.do()
+		}
+		if err := Wait(context.Background(), "test-release"); !errors.Is(err, step.want) {
+			t.Errorf("%s: Wait() = %v, want %v", step.name, err, step.want)
+		}
+	}
+
+	if got := get("test-release").currentUsage(); got != 2 {
+		t.Errorf("used = %d, want the 2 recorded calls", got)
+	}
+}
+
+func TestWaitReleasesCanceledCalls(t *testing.T) {
+	setStore(t, nil)
+	Configure("test-canceled", Limits{RatePerSecond: 0.001, Burst: 1, DailyQuota: 2})
+
+	if err := Wait(context.Background(), "test-canceled"); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+
+	// the bucket is empty: the call waits until the context ends and gives its reservation back
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Wait(ctx, "test-canceled"); err == nil {
+		t.Fatal("Wait() = nil, want the context error")
+	}
+
+	l := get("test-canceled")
+	l.mu.Lock()
+	reserved := l.reserved
+	l.mu.Unlock()
+	if reserved != 1 {
+		t.Errorf("reserved = %d, want only the admitted call", reserved)
+	}
+}
+
+func TestRolloverLoadsUsageOutsideTheLock(t *testing.T) {
+	Configure("test-rollover", Limits{DailyQuota: 10})
+	store := &lockCheckingStore{t: t, limiter: func() *providerLimiter { return get("test-rollover") }, used: 9}
+	setStore(t, store)
+
+	if err := Wait(context.Background(), "test-rollover"); err != nil {
+		t.Fatalf("Wait() = %v", err)
+	}
+	if err := Wait(context.Background(), "test-rollover"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Wait() = %v, want ErrQuotaExceeded after the 9 persisted calls", err)
+	}
+
+	// an old day rolls over to the persisted usage of today
+	l := get("test-rollover")
+	l.mu.Lock()
+	l.day = today().Add(-24 * time.Hour)
+	l.mu.Unlock()
+
+	store.used = 3
+	if got := l.currentUsage(); got != 3 {
+		t.Errorf("used = %d after the day changed, want 3", got)
+	}
+}
@@ -0,0 +1,95 @@
+package tracing
+
+import (
+	er "errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// spanKey stores the span of a running query on the gorm statement.
+const spanKey = "tracing:span"
+
+type (
+	// GormPlugin records a span per query, child of the span in the context given with db.WithContext.
+	GormPlugin struct{}
+)
+
+func (GormPlugin) Name() string {
+	return "tracing"
+}
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	register := []func() error{
+		func() error {
+			return cb.Create().Before("gorm:create").Register("tracing:before_create", startQuery("create"))
+		},
+		func() error { return cb.Create().After("gorm:create").Register("tracing:after_create", endQuery) },
+		func() error {
+			return cb.Query().Before("gorm:query").Register("tracing:before_query", startQuery("query"))
+		},
+		func() error { return cb.Query().After("gorm:query").Register("tracing:after_query", endQuery) },
+		func() error {
+			return cb.Update().Before("gorm:update").Register("tracing:before_update", startQuery("update"))
+		},
+		func() error { return cb.Update().After("gorm:update").Register("tracing:after_update", endQuery) },
+		func() error {
+			return cb.Delete().Before("gorm:delete").Register("tracing:before_delete", startQuery("delete"))
+		},
+		func() error { return cb.Delete().After("gorm:delete").Register("tracing:after_delete", endQuery) },
+		func() error { return cb.Row().Before("gorm:row").Register("tracing:before_row", startQuery("row")) },
+		func() error { return cb.Row().After("gorm:row").Register("tracing:after_row", endQuery) },
+		func() error { return cb.Raw().Before("gorm:raw").Register("tracing:before_raw", startQuery("raw")) },
+		func() error { return cb.Raw().After("gorm:raw").Register("tracing:after_raw", endQuery) },
+	}
+
+	for _, fn := range register {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startQuery(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		ctx := db.Statement.Context
+		if ctx == nil || !trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			// queries outside a traced request would each start a trace of their own
+			return
+		}
+
+		_, span := Start(ctx, "db "+operation, trace.WithSpanKind(trace.SpanKindClient))
+		span.SetAttributes(attribute.String("db.system", "postgresql"), attribute.String("db.operation", operation))
+		db.InstanceSet(spanKey, span)
+	}
+}
+
+func endQuery(db *gorm.DB) {
+	value, ok := db.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+
+	span, ok := value.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("db.sql.table", db.Statement.Table),
+		attribute.String("db.statement", db.Statement.SQL.String()),
+		attribute.Int64("db.rows_affected", db.Statement.RowsAffected),
+	)
+
+	err := db.Error
+	if er.Is(err, gorm.ErrRecordNotFound) {
+		// an empty result is an answer, not a failure
+		err = nil
+	}
+
+	End(span, err)
+}
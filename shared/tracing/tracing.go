@@ -0,0 +1,82 @@
+// Package tracing sets up OpenTelemetry tracing: W3C trace context propagation, the OTLP exporter and the
+// helpers the handlers, provider calls and database use to record spans.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+)
+
+const instrumentation = "github.com/0xbase-Corp/portfolio_svc"
+
+// Setup installs the W3C trace context propagator and, when tracing is enabled, a tracer provider exporting
+// to the OTLP/HTTP endpoint of the configuration. The returned function flushes pending spans on shutdown.
+// With tracing disabled spans are not recorded, but incoming trace context is still passed on.
+func Setup(env *configs.EnvConfigs) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if !env.TracingEnabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpoint, err := url.Parse(env.OtlpEndpoint)
+	if err != nil {
+		return nil, fmt.Errorf("OTLP_ENDPOINT: %w", err)
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint.Host)}
+	if endpoint.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if endpoint.Path != "" && endpoint.Path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(endpoint.Path))
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(env.OtelServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(env.TracingSampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Start starts a span named name as a child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End ends span, marking it failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
@@ -1,9 +1,5 @@
 package utils
 
-import (
-	"strconv"
-)
-
 type (
 	T interface{}
 )
@@ -15,7 +11,3 @@ func Ternary[T any](cond bool, trueVal, falseVal T) T {
 
 	return falseVal
 }
-
-func StrToFloat64(str string) (float64, error) {
-	return strconv.ParseFloat(str, 64)
-}
@@ -5,3 +5,32 @@ const (
 	Solana  = "solana"
 	Bitcoin = "bitcoin"
 )
+
+// upstream data providers
+const (
+	ProviderBtcCom    = "btc.com"
+	ProviderMoralis   = "moralis"
+	ProviderDebank    = "debank"
+	ProviderCoingecko = "coingecko"
+	ProviderENS       = "ens" // ethereum json-rpc used for ENS names
+	ProviderSNS       = "sns" // solana name service proxy
+)
+
+// MaxBodySize bounds the size of the JSON request bodies read by the service, 1 MiB.
+const MaxBodySize = 1 << 20
+
+// api key scopes
+const (
+	ScopePortfolioRead = "portfolio:read" // fetch portfolios from the providers
+	ScopeWalletsRead   = "wallets:read"   // read stored wallets
+	ScopeWebhooks      = "webhooks"       // manage the webhooks of the key
+)
+
+// Scopes lists every scope an api key can be granted.
+var Scopes = []string{ScopePortfolioRead, ScopeWalletsRead, ScopeWebhooks}
+
+// keys set on the gin context by the auth middleware
+const (
+	ContextUserID = "user_id"
+	ContextAPIKey = "api_key"
+)
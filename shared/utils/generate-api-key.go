@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// apiKeyPrefix marks our keys so they are easy to recognise in logs and secret scanners.
+const apiKeyPrefix = "0xb_"
+
+// GenerateAPIKey returns a new random api key, its displayable prefix and the hash to store.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", "", err
+	}
+
+	key = apiKeyPrefix + hex.EncodeToString(b)
+
+	return key, key[:len(apiKeyPrefix)+8], HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the hex sha256 of an api key, which is what gets stored and looked up.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
@@ -21,11 +23,11 @@ type (
 )
 
 func GenerateAccessToken(userID int, email, publicKey string) (string, error) {
-	return generateToken(userID, email, publicKey, 438000*time.Hour)
+	return generateToken(userID, email, publicKey, configs.EnvConfigVars.AccessTokenTTL)
 }
 
 func GenerateRefreshToken(userID int, email, publicKey string) (string, error) {
-	return generateToken(userID, email, publicKey, 24*time.Hour)
+	return generateToken(userID, email, publicKey, configs.EnvConfigVars.RefreshTokenTTL)
 }
 
 func (c *Claims) Valid() error {
@@ -57,3 +59,25 @@ func generateToken(userID int, email, publicKey string, expiresAt time.Duration)
 
 	return signedToken, nil
 }
+
+// ParseToken verifies a signed token and returns its claims.
+func ParseToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return []byte(configs.EnvConfigVars.GetSecret()), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
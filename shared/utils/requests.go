@@ -2,36 +2,229 @@ package utils
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	er "errors"
 	"io"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/breaker"
+	"github.com/0xbase-Corp/portfolio_svc/shared/callstats"
+	"github.com/0xbase-Corp/portfolio_svc/shared/configs"
+	"github.com/0xbase-Corp/portfolio_svc/shared/errors"
+	"github.com/0xbase-Corp/portfolio_svc/shared/metrics"
+	"github.com/0xbase-Corp/portfolio_svc/shared/ratelimit"
+	"github.com/0xbase-Corp/portfolio_svc/shared/tracing"
+)
+
+const (
+	// maxAttempts is the number of times an idempotent request is tried before giving up.
+	maxAttempts = 3
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 5 * time.Second
 )
 
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
 // CallAPI sends a GET request to the specified URL with provided headers and returns the response body.
-func CallAPI(url string, headers map[string]string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+//
+// Any non-2xx response is returned as *errors.UpstreamError tagged with provider. Transport failures,
+// 429 and 5xx responses are retried with exponential backoff, honouring the upstream Retry-After.
+// Every attempt goes through the provider's client-side rate limiter and counts against its daily quota,
+// and the call is short-circuited while the provider's circuit breaker is open. The call is recorded as a
+// span, child of the span in ctx, whose trace context is passed on only to the TRACE_PROPAGATE_HOSTS.
+func CallAPI(ctx context.Context, provider, url string, headers map[string]string) ([]byte, error) {
+	return call(ctx, provider, http.MethodGet, url, headers, nil)
+}
+
+// PostJSON sends payload as a JSON POST request and returns the response body. It must only be used for
+// idempotent calls, such as JSON-RPC reads, since it is retried and guarded exactly like CallAPI.
+func PostJSON(ctx context.Context, provider, url string, headers map[string]string, payload interface{}) ([]byte, error) {
+	body, err := json.Marshal(payload)
 	if err != nil {
 		return nil, err
 	}
 
+	jsonHeaders := map[string]string{"Content-Type": "application/json"}
+	for key, value := range headers {
+		jsonHeaders[key] = value
+	}
+
+	return call(ctx, provider, http.MethodPost, url, jsonHeaders, body)
+}
+
+func call(ctx context.Context, provider, method, url string, headers map[string]string, payload []byte) (body []byte, err error) {
+	ctx, span := tracing.Start(ctx, "upstream "+provider, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("upstream.provider", provider),
+		attribute.String("http.method", method),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if err := breaker.Allow(provider); err != nil {
+		return nil, errors.NewUpstreamTransportError(provider, err)
+	}
+
+	body, upstreamErr := callWithRetry(ctx, provider, method, url, headers, payload)
+	if upstreamErr != nil {
+		span.SetAttributes(attribute.Int("http.status_code", upstreamErr.StatusCode))
+	}
+
+	switch {
+	case upstreamErr == nil:
+		breaker.Success(provider)
+	case er.Is(upstreamErr, ratelimit.ErrQuotaExceeded):
+		// the provider was never reached
+		breaker.Release(provider)
+	case upstreamErr.Temporary():
+		breaker.Failure(provider, upstreamErr)
+	default:
+		// the provider answered, the request itself was rejected
+		breaker.Success(provider)
+	}
+
+	if upstreamErr != nil {
+		return nil, upstreamErr
+	}
+
+	return body, nil
+}
+
+// callWithRetry performs the request, retrying temporary failures.
+func callWithRetry(ctx context.Context, provider, method, url string, headers map[string]string, payload []byte) ([]byte, *errors.UpstreamError) {
+	var upstreamErr *errors.UpstreamError
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err := ratelimit.Wait(ctx, provider); err != nil {
+			return nil, errors.NewUpstreamTransportError(provider, err)
+		}
+
+		start := time.Now()
+		body, status, err := doRequest(ctx, provider, method, url, headers, payload)
+		metrics.ObserveUpstream(provider, status, time.Since(start))
+		if err == nil {
+			callstats.Record(provider, time.Since(start), nil)
+			slog.DebugContext(ctx, "provider call", slog.String("provider", provider), slog.String("method", method),
+				slog.Int("status", status), slog.Duration("duration", time.Since(start)), slog.Int("attempt", attempt))
+			return body, nil
+		}
+		callstats.Record(provider, time.Since(start), err)
+		upstreamErr = err
+
+		// urls are left out, some providers take credentials in the query
+		slog.WarnContext(ctx, "provider call failed", slog.String("provider", provider), slog.String("method", method),
+			slog.Int("status", status), slog.Duration("duration", time.Since(start)), slog.Int("attempt", attempt), slog.Any("error", err))
+
+		if !err.Temporary() || attempt == maxAttempts || err.RetryAfter > maxBackoff {
+			break
+		}
+
+		timer := time.NewTimer(backoff(attempt, err.RetryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.NewUpstreamTransportError(provider, ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	return nil, upstreamErr
+}
+
+// doRequest performs a single request and returns the response status, 0 when there was no response.
+func doRequest(ctx context.Context, provider, method, url string, headers map[string]string, payload []byte) ([]byte, int, *errors.UpstreamError) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		ratelimit.Release(provider)
+		return nil, 0, errors.NewUpstreamTransportError(provider, err)
+	}
+
 	// Add headers to the request
 	for key, value := range headers {
 		req.Header.Set(key, value)
 	}
+	// trace headers would leak internal ids to third parties
+	if propagatesTrace(req.URL.Hostname()) {
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		// the provider was never reached, the call does not count against its quota
+		ratelimit.Release(provider)
+		return nil, 0, errors.NewUpstreamTransportError(provider, err)
 	}
 	defer resp.Body.Close()
 
+	ratelimit.Record(provider)
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, resp.StatusCode, errors.NewUpstreamTransportError(provider, err)
 	}
 
-	return body, nil
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, resp.StatusCode, errors.NewUpstreamError(provider, resp.StatusCode, parseRetryAfter(resp.Header.Get("Retry-After")), body)
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// propagatesTrace reports whether host is one of the internal TRACE_PROPAGATE_HOSTS.
+func propagatesTrace(host string) bool {
+	for _, internal := range strings.Split(configs.EnvConfigVars.TracePropagateHosts, ",") {
+		if internal = strings.TrimSpace(internal); internal != "" && strings.EqualFold(internal, host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header given either in seconds or as an HTTP date.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+
+	return 0
+}
+
+// backoff returns the wait before the next attempt: the upstream Retry-After when given,
+// otherwise exponential backoff with jitter.
+func backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+
+	wait := baseBackoff << (attempt - 1)
+	if wait > maxBackoff {
+		wait = maxBackoff
+	}
+
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)))
 }
 
 // DecodeJSONResponse decodes a JSON response body into the provided interface.
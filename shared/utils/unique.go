@@ -2,16 +2,20 @@ package utils
 
 // UniqueAddress takes a slice of strings and returns a new slice with duplicates removed.
 func UniqueAddress(input []string) []string {
-	seen := make(map[string]struct{})
-	j := 0
+	return Unique(input)
+}
+
+// Unique returns the values of input in order, without their duplicates. input is left unchanged.
+func Unique[T comparable](input []T) []T {
+	seen := make(map[T]struct{}, len(input))
+	unique := make([]T, 0, len(input))
 	for _, val := range input {
 		if _, ok := seen[val]; ok {
 			continue
 		}
 		seen[val] = struct{}{}
-		input[j] = val
-		j++
+		unique = append(unique, val)
 	}
 
-	return input[:j]
+	return unique
 }
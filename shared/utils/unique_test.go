@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUnique(t *testing.T) {
+	tests := []struct {
+		input []string
+		want  []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"a", "b", "a", "c", "b"}, []string{"a", "b", "c"}},
+		{[]string{"b", "b", "b"}, []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		input := append([]string{}, tt.input...)
+
+		if got := Unique(input); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Unique(%v) = %v, want %v", tt.input, got, tt.want)
+		}
+		if !reflect.DeepEqual(input, tt.input) {
+			t.Errorf("Unique(%v) changed its input to %v", tt.input, input)
+		}
+	}
+
+	if got := Unique([]int{3, 1, 3, 2, 1}); !reflect.DeepEqual(got, []int{3, 1, 2}) {
+		t.Errorf("Unique() = %v, want [3 1 2]", got)
+	}
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+)
+
+// webhookSecretPrefix marks webhook signing secrets, like apiKeyPrefix does keys.
+const webhookSecretPrefix = "whsec_"
+
+// GenerateWebhookSecret returns a new random secret to sign the deliveries of a webhook with.
+func GenerateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return webhookSecretPrefix + hex.EncodeToString(b), nil
+}
+
+// SignWebhook returns the signature of a webhook body sent at timestamp, in unix seconds:
+// "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret of the webhook.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestSignWebhook(t *testing.T) {
+	const (
+		secret    = "whsec_test"
+		timestamp = int64(1700000000)
+	)
+	body := []byte(`{"event":"webhook.test"}`)
+	signature := SignWebhook(secret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		same      bool
+	}{
+		{"same input", secret, timestamp, body, true},
+		{"other secret", "whsec_other", timestamp, body, false},
+		{"other timestamp", secret, timestamp + 1, body, false},
+		{"other body", secret, timestamp, []byte(`{"event":"webhook.test "}`), false},
+		// the timestamp and the body are separated, so that digits cannot move from one to the other
+		{"timestamp digit moved to the body", secret, timestamp / 10, append([]byte("0."), body...), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SignWebhook(tt.secret, tt.timestamp, tt.body)
+			if (got == signature) != tt.same {
+				t.Errorf("SignWebhook() = %s, signature %s, want same %v", got, signature, tt.same)
+			}
+		})
+	}
+
+	// as documented for receivers: the hex HMAC-SHA256 of "<timestamp>.<body>", computed with openssl
+	if want := "sha256=1d83e338c8b0156315e4b02e94f96d83e4260098aa05dead2a856d36245f342f"; signature != want {
+		t.Errorf("SignWebhook() = %s, want %s", signature, want)
+	}
+}
@@ -0,0 +1,230 @@
+// Package validation checks and normalizes wallet addresses before they reach the providers or the database.
+package validation
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+type (
+	// AddressError reports why the address at Index of a request was rejected.
+	AddressError struct {
+		Index   int
+		Address string
+		Reason  string
+	}
+)
+
+var (
+	ErrUnsupportedChain = errors.New("unsupported chain")
+	ErrUnknownFormat    = errors.New("address does not match any supported chain")
+	ErrAmbiguous        = errors.New("address matches more than one chain")
+	ErrName             = errors.New("names cannot be resolved, use the address")
+
+	errBitcoinVersion  = errors.New("unknown bitcoin address version")
+	errBitcoinNetwork  = errors.New("not a bitcoin mainnet address")
+	errWitnessVersion  = errors.New("invalid segwit witness version")
+	errWitnessProgram  = errors.New("invalid segwit witness program")
+	errWitnessEncoding = errors.New("segwit version does not match bech32 variant")
+	errSolanaLength    = errors.New("solana address must decode to 32 bytes")
+	errEVMFormat       = errors.New("evm address must be 0x followed by 40 hex characters")
+	errEVMChecksum     = errors.New("invalid EIP-55 checksum")
+)
+
+// Normalize trims whitespace and brings an address to the form it is stored in: EVM and bech32 bitcoin
+// addresses are case-insensitive and stored lowercase, base58 addresses are case-sensitive and kept as is.
+func Normalize(chain, address string) string {
+	address = strings.TrimSpace(address)
+
+	switch chain {
+	case utils.Debank:
+		return strings.ToLower(address)
+	case utils.Bitcoin:
+		if strings.HasPrefix(strings.ToLower(address), "bc1") {
+			return strings.ToLower(address)
+		}
+	}
+
+	return address
+}
+
+// Validate checks address, as given by the caller, against the address format of chain.
+func Validate(chain, address string) error {
+	switch chain {
+	case utils.Bitcoin:
+		return Bitcoin(address)
+	case utils.Solana:
+		return Solana(address)
+	case utils.Debank:
+		return EVM(address)
+	}
+
+	return ErrUnsupportedChain
+}
+
+// Addresses validates and normalizes the addresses of a request for chain. Blank entries are skipped and
+// duplicates are removed after normalization; invalid entries are reported with the reason.
+func Addresses(chain string, addresses []string) ([]string, []AddressError) {
+	normalized := make([]string, 0, len(addresses))
+	invalid := make([]AddressError, 0)
+
+	for i, address := range addresses {
+		address = strings.TrimSpace(address)
+		if address == "" {
+			continue
+		}
+
+		if err := Validate(chain, address); err != nil {
+			invalid = append(invalid, AddressError{Index: i, Address: address, Reason: err.Error()})
+			continue
+		}
+
+		normalized = append(normalized, Normalize(chain, address))
+	}
+
+	return utils.UniqueAddress(normalized), invalid
+}
+
+// Detect returns the chain whose address format address matches. Addresses with a chain specific prefix
+// report why they are invalid for that chain instead of ErrUnknownFormat.
+func Detect(address string) (string, error) {
+	address = strings.TrimSpace(address)
+
+	switch lower := strings.ToLower(address); {
+	case strings.HasPrefix(lower, "0x"):
+		return utils.Debank, EVM(address)
+	case strings.HasPrefix(lower, "bc1"):
+		return utils.Bitcoin, Bitcoin(address)
+	case strings.Contains(address, "."):
+		return "", ErrName
+	}
+
+	matches := make([]string, 0, 1)
+	for _, chain := range []string{utils.Bitcoin, utils.Solana, utils.Debank} {
+		if Validate(chain, address) == nil {
+			matches = append(matches, chain)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", ErrUnknownFormat
+	case 1:
+		return matches[0], nil
+	}
+
+	return "", ErrAmbiguous
+}
+
+// Bitcoin accepts mainnet P2PKH and P2SH base58check addresses and bech32 (segwit v0) or bech32m
+// (taproot and later) addresses.
+func Bitcoin(address string) error {
+	if strings.HasPrefix(strings.ToLower(address), "bc1") {
+		return segwit(address)
+	}
+
+	version, payload, err := decodeBase58Check(address)
+	if err != nil {
+		return err
+	}
+
+	if version != 0x00 && version != 0x05 {
+		return errBitcoinVersion
+	}
+
+	if len(payload) != 20 {
+		return errBase58Length
+	}
+
+	return nil
+}
+
+func segwit(address string) error {
+	hrp, data, constant, err := decodeBech32(address)
+	if err != nil {
+		return err
+	}
+
+	if hrp != "bc" {
+		return errBitcoinNetwork
+	}
+
+	if len(data) < 1 || data[0] > 16 {
+		return errWitnessVersion
+	}
+
+	program, ok := convertBits(data[1:], 5, 8)
+	if !ok || len(program) < 2 || len(program) > 40 {
+		return errWitnessProgram
+	}
+
+	version := data[0]
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return errWitnessProgram
+	}
+
+	if (version == 0) != (constant == bech32Const) {
+		return errWitnessEncoding
+	}
+
+	return nil
+}
+
+// Solana accepts base58 encoded 32-byte public keys.
+func Solana(address string) error {
+	decoded, err := decodeBase58(address)
+	if err != nil {
+		return err
+	}
+
+	if len(decoded) != 32 {
+		return errSolanaLength
+	}
+
+	return nil
+}
+
+// EVM accepts 0x prefixed hex addresses. Mixed-case addresses must carry a valid EIP-55 checksum.
+func EVM(address string) error {
+	hexPart, ok := strings.CutPrefix(address, "0x")
+	if !ok || len(hexPart) != 40 {
+		return errEVMFormat
+	}
+
+	if _, err := hex.DecodeString(hexPart); err != nil {
+		return errEVMFormat
+	}
+
+	if strings.ToLower(hexPart) == hexPart || strings.ToUpper(hexPart) == hexPart {
+		return nil
+	}
+
+	if ChecksumEVM(address) != address {
+		return errEVMChecksum
+	}
+
+	return nil
+}
+
+// ChecksumEVM returns the EIP-55 mixed-case form of a 0x prefixed hex address.
+func ChecksumEVM(address string) string {
+	lower := strings.ToLower(strings.TrimPrefix(address, "0x"))
+
+	hash := sha3.NewLegacyKeccak256()
+	hash.Write([]byte(lower))
+	digest := hex.EncodeToString(hash.Sum(nil))
+
+	checksummed := []byte(lower)
+	for i, c := range checksummed {
+		if c >= 'a' && c <= 'f' && digest[i] >= '8' {
+			checksummed[i] = c - 'a' + 'A'
+		}
+	}
+
+	return "0x" + string(checksummed)
+}
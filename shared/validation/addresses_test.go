@@ -0,0 +1,101 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/0xbase-Corp/portfolio_svc/shared/utils"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		chain   string
+		address string
+		wantErr bool
+	}{
+		{"p2pkh", utils.Bitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", false},
+		{"p2sh", utils.Bitcoin, "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy", false},
+		{"bech32", utils.Bitcoin, "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", false},
+		{"bech32 uppercase", utils.Bitcoin, "BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ", false},
+		{"bech32m", utils.Bitcoin, "bc1p5d7rjq7g6rdk2yhzks9smlaqtedr4dekq08ge8ztwac72sfr9rusxg3297", false},
+		{"bad base58 checksum", utils.Bitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb", true},
+		{"bad bech32 checksum", utils.Bitcoin, "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdr", true},
+		{"testnet", utils.Bitcoin, "tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx", true},
+		{"solana", utils.Solana, "So11111111111111111111111111111111111111112", false},
+		{"solana too short", utils.Solana, "So1111111111111111111111111111", true},
+		{"solana not base58", utils.Solana, "So1111111111111111111111111111111111111111O", true},
+		{"evm lowercase", utils.Debank, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", false},
+		{"evm checksummed", utils.Debank, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", false},
+		{"evm bad checksum", utils.Debank, "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD", true},
+		{"evm short", utils.Debank, "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", true},
+		{"evm no prefix", utils.Debank, "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", true},
+		{"unsupported chain", "dogecoin", "DH5yaieqoZN36fDVciNyRueRGvGLR3mr7L", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.chain, tt.address)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q, %q) = %v, want error %v", tt.chain, tt.address, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		chain   string
+		address string
+		want    string
+	}{
+		{utils.Debank, " 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed ", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+		{utils.Bitcoin, "BC1QAR0SRRR7XFKVY5L643LYDNW9RE59GTZZWF5MDQ", "bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq"},
+		{utils.Bitcoin, "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa\n", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"},
+		{utils.Solana, "\tSo11111111111111111111111111111111111111112", "So11111111111111111111111111111111111111112"},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.chain, tt.address); got != tt.want {
+			t.Errorf("Normalize(%q, %q) = %q, want %q", tt.chain, tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	addresses, invalid := Addresses(utils.Debank, []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"",
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed",
+		"0x123",
+	})
+
+	if len(addresses) != 1 || addresses[0] != "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed" {
+		t.Errorf("addresses = %v, want the one normalized address", addresses)
+	}
+	if len(invalid) != 1 || invalid[0].Index != 3 {
+		t.Errorf("invalid = %+v, want the entry at index 3", invalid)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		wantErr error
+	}{
+		{"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", utils.Debank, nil},
+		{"bc1qar0srrr7xfkvy5l643lydnw9re59gtzzwf5mdq", utils.Bitcoin, nil},
+		{"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", utils.Bitcoin, nil},
+		{"So11111111111111111111111111111111111111112", utils.Solana, nil},
+		{"vitalik.eth", "", ErrName},
+		{"hello", "", ErrUnknownFormat},
+	}
+
+	for _, tt := range tests {
+		got, err := Detect(tt.address)
+		if got != tt.want || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) || (tt.wantErr == nil && err != nil) {
+			t.Errorf("Detect(%q) = %q, %v, want %q, %v", tt.address, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
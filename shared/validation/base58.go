@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var (
+	errBase58Character = errors.New("invalid base58 character")
+	errBase58Checksum  = errors.New("invalid base58check checksum")
+	errBase58Length    = errors.New("invalid base58check length")
+
+	base58Indexes = func() [256]int {
+		var indexes [256]int
+		for i := range indexes {
+			indexes[i] = -1
+		}
+		for i, r := range base58Alphabet {
+			indexes[r] = i
+		}
+		return indexes
+	}()
+)
+
+// decodeBase58 decodes a base58 string with the bitcoin alphabet, keeping leading zero bytes.
+func decodeBase58(s string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+
+	for i := 0; i < len(s); i++ {
+		index := base58Indexes[s[i]]
+		if index < 0 {
+			return nil, errBase58Character
+		}
+		n.Mul(n, radix)
+		n.Add(n, big.NewInt(int64(index)))
+	}
+
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+
+	return append(make([]byte, zeros), n.Bytes()...), nil
+}
+
+// decodeBase58Check decodes a base58check string and returns its version byte and payload.
+func decodeBase58Check(s string) (byte, []byte, error) {
+	decoded, err := decodeBase58(s)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if len(decoded) < 5 {
+		return 0, nil, errBase58Length
+	}
+
+	data, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+
+	first := sha256.Sum256(data)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return 0, nil, errBase58Checksum
+	}
+
+	return data[0], data[1:], nil
+}
@@ -0,0 +1,107 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// checksum constants from BIP-173 (bech32) and BIP-350 (bech32m)
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+var (
+	errBech32MixedCase = errors.New("bech32 address mixes upper and lower case")
+	errBech32Format    = errors.New("invalid bech32 format")
+	errBech32Character = errors.New("invalid bech32 character")
+	errBech32Checksum  = errors.New("invalid bech32 checksum")
+)
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+func bech32HRPExpand(hrp string) []byte {
+	expanded := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]>>5)
+	}
+	expanded = append(expanded, 0)
+	for i := 0; i < len(hrp); i++ {
+		expanded = append(expanded, hrp[i]&31)
+	}
+
+	return expanded
+}
+
+// decodeBech32 decodes a bech32 or bech32m string and returns the human readable part, the 5-bit data
+// without the checksum and the checksum constant it was encoded with.
+func decodeBech32(s string) (string, []byte, uint32, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return "", nil, 0, errBech32MixedCase
+	}
+	s = strings.ToLower(s)
+
+	separator := strings.LastIndexByte(s, '1')
+	if separator < 1 || separator+7 > len(s) || len(s) > 90 {
+		return "", nil, 0, errBech32Format
+	}
+
+	hrp := s[:separator]
+	data := make([]byte, 0, len(s)-separator-1)
+	for i := separator + 1; i < len(s); i++ {
+		index := strings.IndexByte(bech32Charset, s[i])
+		if index < 0 {
+			return "", nil, 0, errBech32Character
+		}
+		data = append(data, byte(index))
+	}
+
+	constant := bech32Polymod(append(bech32HRPExpand(hrp), data...))
+	if constant != bech32Const && constant != bech32mConst {
+		return "", nil, 0, errBech32Checksum
+	}
+
+	return hrp, data[:len(data)-6], constant, nil
+}
+
+// convertBits regroups 5-bit groups into bytes, rejecting non-zero padding.
+func convertBits(data []byte, from, to uint) ([]byte, bool) {
+	var (
+		acc  uint32
+		bits uint
+		out  []byte
+		max  = uint32(1)<<to - 1
+	)
+
+	for _, v := range data {
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&max))
+		}
+	}
+
+	if bits >= from || (acc<<(to-bits))&max != 0 {
+		return nil, false
+	}
+
+	return out, true
+}